@@ -83,6 +83,9 @@ func (am *AuthMiddleware) JWTAuth() gin.HandlerFunc {
 		c.Set("user_id", claims["user_id"])
 		c.Set("username", claims["username"])
 		c.Set("roles", claims["roles"])
+		if tier, ok := claims["tier"]; ok {
+			c.Set("user_tier", tier)
+		}
 
 		c.Next()
 	}
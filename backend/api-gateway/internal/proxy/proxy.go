@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -168,6 +169,21 @@ func (sp *ServiceProxy) proxyRequest(c *gin.Context, client *ServiceClient) {
 	req.Header.Set("X-Forwarded-Host", c.Request.Host)
 	req.Header.Set("X-Real-IP", c.ClientIP())
 
+	// 转发用户等级，供下游按等级排队
+	if tier, exists := c.Get("user_tier"); exists {
+		req.Header.Set("X-User-Tier", fmt.Sprint(tier))
+	}
+
+	// 转发已认证的用户ID，供下游校验请求中的用户是否为本人
+	if userID, exists := c.Get("user_id"); exists && userID != nil {
+		req.Header.Set("X-User-ID", formatClaim(userID))
+	}
+
+	// 转发用户角色，下游的管理接口据此校验权限
+	if roles, exists := c.Get("roles"); exists && roles != nil {
+		req.Header.Set("X-User-Roles", formatRoles(roles))
+	}
+
 	// 添加追踪头
 	if traceID := c.GetHeader("X-Trace-ID"); traceID == "" {
 		req.Header.Set("X-Trace-ID", sp.generateTraceID())
@@ -263,6 +279,9 @@ func (sp *ServiceProxy) copyHeaders(src, dst http.Header) {
 		"Trailers":            true,
 		"Transfer-Encoding":   true,
 		"Upgrade":             true,
+		"X-User-Tier":         true, // 用户等级只能由网关根据 token 设置
+		"X-User-Id":           true, // 用户ID只能由网关根据 token 设置
+		"X-User-Roles":        true, // 用户角色只能由网关根据 token 设置
 	}
 
 	for key, values := range src {
@@ -275,6 +294,29 @@ func (sp *ServiceProxy) copyHeaders(src, dst http.Header) {
 	}
 }
 
+// formatClaim 格式化 JWT 声明值，数值声明解析为 float64，需避免科学计数法
+func formatClaim(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// formatRoles 将 token 中的角色列表格式化为逗号分隔的字符串
+func formatRoles(value interface{}) string {
+	switch roles := value.(type) {
+	case []string:
+		return strings.Join(roles, ",")
+	case []interface{}:
+		names := make([]string, 0, len(roles))
+		for _, role := range roles {
+			names = append(names, fmt.Sprint(role))
+		}
+		return strings.Join(names, ",")
+	}
+	return fmt.Sprint(value)
+}
+
 // getScheme 获取请求协议
 func (sp *ServiceProxy) getScheme(c *gin.Context) string {
 	if c.Request.TLS != nil {
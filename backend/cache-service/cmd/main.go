@@ -39,6 +39,8 @@ func main() {
 	redisConfig := &redis.Config{
 		Host:         cfg.Redis.Host,
 		Port:         cfg.Redis.Port,
+		Cluster:      cfg.Redis.Cluster,
+		Addrs:        cfg.Redis.Addrs,
 		Password:     cfg.Redis.Password,
 		DB:           cfg.Redis.DB,
 		PoolSize:     cfg.Redis.PoolSize,
@@ -21,6 +21,8 @@ type ServerConfig struct {
 type RedisConfig struct {
 	Host         string        `mapstructure:"host"`
 	Port         int           `mapstructure:"port"`
+	Cluster      bool          `mapstructure:"cluster"`
+	Addrs        []string      `mapstructure:"addrs"`
 	Password     string        `mapstructure:"password"`
 	DB           int           `mapstructure:"db"`
 	PoolSize     int           `mapstructure:"pool_size"`
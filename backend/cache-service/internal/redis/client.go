@@ -9,14 +9,17 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// 单机与集群模式通用的 Redis 客户端封装
 type Client struct {
-	rdb    *redis.Client
+	rdb    redis.UniversalClient
 	logger *logrus.Logger
 }
 
 type Config struct {
 	Host         string
 	Port         int
+	Cluster      bool     // 是否使用 Redis Cluster
+	Addrs        []string // 集群种子节点，为空时使用 Host:Port
 	Password     string
 	DB           int
 	PoolSize     int
@@ -33,8 +36,13 @@ func NewClient(config *Config, logger *logrus.Logger) (*Client, error) {
 		logger.Warn("logger is nil, creating default logger")
 	}
 	
-	rdb := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", config.Host, config.Port),
+	addrs := config.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf("%s:%d", config.Host, config.Port)}
+	}
+
+	options := &redis.UniversalOptions{
+		Addrs:        addrs,
 		Password:     config.Password,
 		DB:           config.DB,
 		PoolSize:     config.PoolSize,
@@ -43,7 +51,14 @@ func NewClient(config *Config, logger *logrus.Logger) (*Client, error) {
 		DialTimeout:  config.DialTimeout,
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
-	})
+	}
+
+	var rdb redis.UniversalClient
+	if config.Cluster {
+		rdb = redis.NewClusterClient(options.Cluster())
+	} else {
+		rdb = redis.NewClient(options.Simple())
+	}
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -242,7 +257,7 @@ func (c *Client) EvalSha(ctx context.Context, sha1 string, keys []string, args .
 	return c.rdb.EvalSha(ctx, sha1, keys, args...)
 }
 
-// 获取原始客户端（用于复杂操作）
-func (c *Client) GetRawClient() *redis.Client {
+// 获取原始客户端（用于复杂操作），集群模式下为 *redis.ClusterClient
+func (c *Client) GetRawClient() redis.UniversalClient {
 	return c.rdb
 }
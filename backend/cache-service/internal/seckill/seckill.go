@@ -302,23 +302,23 @@ func (sc *SeckillCache) CleanupSeckillData(ctx context.Context, productID int64)
 	return sc.client.Del(ctx, keys...)
 }
 
-// 生成各种 key
+// 生成各种 key，商品ID 作为 hash tag，Redis Cluster 下同一活动的 key 落在同一个槽
 func (sc *SeckillCache) getStockKey(productID int64) string {
-	return fmt.Sprintf("%s%d", sc.config.StockKeyPrefix, productID)
+	return fmt.Sprintf("%s{%d}", sc.config.StockKeyPrefix, productID)
 }
 
 func (sc *SeckillCache) getUserKey(productID int64) string {
-	return fmt.Sprintf("%s%d", sc.config.UserKeyPrefix, productID)
+	return fmt.Sprintf("%s{%d}", sc.config.UserKeyPrefix, productID)
 }
 
 func (sc *SeckillCache) getLockKey(productID int64) string {
-	return fmt.Sprintf("%s%d", sc.config.LockKeyPrefix, productID)
+	return fmt.Sprintf("%s{%d}", sc.config.LockKeyPrefix, productID)
 }
 
 func (sc *SeckillCache) getActivityKey(productID int64) string {
-	return fmt.Sprintf("seckill:activity:%d", productID)
+	return fmt.Sprintf("seckill:activity:{%d}", productID)
 }
 
 func (sc *SeckillCache) getUserPurchaseInfoKey(productID int64, userID int64) string {
-	return fmt.Sprintf("seckill:purchase:%d:%d", productID, userID)
+	return fmt.Sprintf("seckill:purchase:{%d}:%d", productID, userID)
 }
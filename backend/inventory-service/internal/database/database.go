@@ -76,6 +76,7 @@ func (d *Database) AutoMigrate() error {
 	err := d.DB.AutoMigrate(
 		&model.Inventory{},
 		&model.InventoryLog{},
+		&model.InventoryReservation{},
 		&model.InventoryDiff{},
 		&model.InventoryAlert{},
 	)
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -52,6 +53,66 @@ func (h *InventoryHandler) SyncStock(c *gin.Context) {
 	}
 }
 
+// ReserveStock 预留库存
+func (h *InventoryHandler) ReserveStock(c *gin.Context) {
+	var req model.ReserveStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: " + err.Error()})
+		return
+	}
+
+	response, err := h.inventoryService.ReserveStock(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"data": response,
+			"msg":  "库存预留成功",
+		})
+	} else {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 1,
+			"data": response,
+			"msg":  response.Message,
+		})
+	}
+}
+
+// ReleaseReservation 释放预留
+func (h *InventoryHandler) ReleaseReservation(c *gin.Context) {
+	reservationID := c.Param("reservationId")
+	if reservationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "预留ID不能为空"})
+		return
+	}
+
+	var req model.ReleaseReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: " + err.Error()})
+		return
+	}
+
+	response, err := h.inventoryService.ReleaseReservation(c.Request.Context(), reservationID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrReservationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": response,
+		"msg":  "预留释放成功",
+	})
+}
+
 // GetInventory 获取库存信息
 func (h *InventoryHandler) GetInventory(c *gin.Context) {
 	productIDStr := c.Param("productId")
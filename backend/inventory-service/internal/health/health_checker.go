@@ -132,7 +132,10 @@ func (hc *HealthChecker) autoFixDifference(ctx context.Context, response *model.
 
 	// 简单策略：如果Redis库存更高，认为是正确的（因为Redis是实时扣减的）
 	fixType := "use_redis"
-	if response.Diff < 0 {
+	if response.Reserved > 0 {
+		// 有进行中的秒杀预留时只能把 Redis 库存降到预留以内
+		fixType = "use_db"
+	} else if response.Diff < 0 {
 		// 如果Redis库存更低，可能是缓存丢失，使用数据库库存
 		fixType = "use_db"
 	}
@@ -15,10 +15,18 @@ const (
 
 // 库存操作类型
 const (
-	InventoryOpTypeDeduct = "deduct" // 扣减
-	InventoryOpTypeAdd    = "add"    // 增加
-	InventoryOpTypeSync   = "sync"   // 同步
-	InventoryOpTypeInit   = "init"   // 初始化
+	InventoryOpTypeDeduct  = "deduct"  // 扣减
+	InventoryOpTypeAdd     = "add"     // 增加
+	InventoryOpTypeSync    = "sync"    // 同步
+	InventoryOpTypeInit    = "init"    // 初始化
+	InventoryOpTypeReserve = "reserve" // 预留
+	InventoryOpTypeRelease = "release" // 释放预留
+)
+
+// 预留状态
+const (
+	ReservationStatusReserved = "reserved" // 已预留
+	ReservationStatusReleased = "released" // 已释放（售出部分扣减库存，未售出部分归还）
 )
 
 // 库存表
@@ -57,13 +65,31 @@ type InventoryLog struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
+// 库存预留表（秒杀活动预热时预留，活动结束后释放）
+type InventoryReservation struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ReservationID string     `gorm:"size:64;uniqueIndex;not null" json:"reservation_id"` // 调用方生成的预留ID，重复请求按同一预留处理
+	ProductID     int64      `gorm:"index;not null" json:"product_id"`
+	Quantity      int64      `gorm:"not null" json:"quantity"`             // 预留数量
+	Returned      int64      `gorm:"not null;default:0" json:"returned"`   // 释放时归还的数量
+	Status        string     `gorm:"size:20;not null;index" json:"status"` // reserved, released
+	Reason        string     `gorm:"size:255" json:"reason"`
+	Operator      string     `gorm:"size:100" json:"operator"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
 // 库存差异记录表（健康检查发现的差异）
 type InventoryDiff struct {
 	ID         uint       `gorm:"primaryKey" json:"id"`
 	ProductID  int64      `gorm:"index;not null" json:"product_id"`
 	DBStock    int64      `gorm:"not null" json:"db_stock"`             // 数据库库存
 	RedisStock int64      `gorm:"not null" json:"redis_stock"`          // Redis库存
-	Diff       int64      `gorm:"not null" json:"diff"`                 // 差异量 = redis_stock - db_stock
+	Reserved   int64      `gorm:"not null;default:0" json:"reserved"`   // 秒杀活动的预留库存，大于 0 时 Redis 库存以其为上限
+	Diff       int64      `gorm:"not null" json:"diff"`                 // 差异量 = redis_stock - db_stock，有预留时为超出预留的数量
 	Status     string     `gorm:"size:20;not null;index" json:"status"` // pending, fixed, ignored
 	FixedAt    *time.Time `json:"fixed_at,omitempty"`
 	FixedBy    string     `gorm:"size:100" json:"fixed_by"`
@@ -98,6 +124,10 @@ func (InventoryLog) TableName() string {
 	return "inventory_logs"
 }
 
+func (InventoryReservation) TableName() string {
+	return "inventory_reservations"
+}
+
 func (InventoryDiff) TableName() string {
 	return "inventory_diffs"
 }
@@ -155,6 +185,33 @@ type SyncStockResponse struct {
 	Message     string `json:"message,omitempty"`
 }
 
+// 库存预留请求DTO，同一预留ID重复请求时调整为新的预留数量
+type ReserveStockRequest struct {
+	ReservationID string `json:"reservation_id" binding:"required"`
+	ProductID     int64  `json:"product_id" binding:"required"`
+	Quantity      int64  `json:"quantity" binding:"required"`
+	Reason        string `json:"reason"`
+	Operator      string `json:"operator"`
+}
+
+// 释放预留请求DTO，未归还的部分视为已售出并从库存中扣减
+type ReleaseReservationRequest struct {
+	Returned int64  `json:"returned"` // 归还数量
+	Reason   string `json:"reason"`
+}
+
+// 库存预留响应DTO
+type ReservationResponse struct {
+	ReservationID string `json:"reservation_id"`
+	ProductID     int64  `json:"product_id"`
+	Quantity      int64  `json:"quantity"`
+	Returned      int64  `json:"returned"`
+	Status        string `json:"status"`
+	Available     int64  `json:"available"` // 操作后的可用库存
+	Success       bool   `json:"success"`
+	Message       string `json:"message,omitempty"`
+}
+
 // 库存查询请求DTO
 type GetInventoryRequest struct {
 	ProductID int64 `form:"product_id" binding:"required"`
@@ -185,6 +242,7 @@ type InventoryHealthResponse struct {
 	ProductID  int64  `json:"product_id"`
 	DBStock    int64  `json:"db_stock"`
 	RedisStock int64  `json:"redis_stock"`
+	Reserved   int64  `json:"reserved"`
 	Diff       int64  `json:"diff"`
 	Status     string `json:"status"`
 }
@@ -30,6 +30,10 @@ func SetupRouter(inventoryHandler *handler.InventoryHandler) *gin.Engine {
 			inventory.GET("", inventoryHandler.ListInventories)            // 获取库存列表
 			inventory.POST("", inventoryHandler.CreateInventory)           // 创建库存记录
 			inventory.PUT("/:productId", inventoryHandler.UpdateInventory) // 更新库存信息
+
+			// 库存预留接口 - 秒杀活动预热时预留，活动结束后释放
+			inventory.POST("/reservations", inventoryHandler.ReserveStock)                              // 预留库存
+			inventory.POST("/reservations/:reservationId/release", inventoryHandler.ReleaseReservation) // 释放预留
 		}
 
 		// 健康检查相关接口
@@ -251,18 +251,22 @@ func (s *InventoryService) CheckInventoryHealth(ctx context.Context) ([]*model.I
 
 	for _, inventory := range inventories {
 		// 获取Redis中的库存
-		redisKey := fmt.Sprintf("seckill:stock:%d", inventory.ProductID)
-		redisStock, err := s.redisClient.Get(ctx, redisKey).Int64()
+		redisStock, err := s.getRedisStock(ctx, inventory.ProductID)
 		if err != nil {
-			if err == redis.Nil {
-				redisStock = 0
-			} else {
-				s.logger.WithError(err).Errorf("获取Redis库存失败: product_id=%d", inventory.ProductID)
-				continue
-			}
+			s.logger.WithError(err).Errorf("获取Redis库存失败: product_id=%d", inventory.ProductID)
+			continue
 		}
 
+		// 有进行中的秒杀预留时 Redis 库存是预留中尚未售出的部分，不超过预留即为正常
 		diff := redisStock - inventory.Stock
+		if inventory.Reserved > 0 {
+			diff = 0
+			if redisStock > inventory.Reserved {
+				diff = redisStock - inventory.Reserved
+			} else if redisStock < 0 {
+				diff = redisStock
+			}
+		}
 		status := "normal"
 
 		// 检查差异是否超过阈值
@@ -271,12 +275,12 @@ func (s *InventoryService) CheckInventoryHealth(ctx context.Context) ([]*model.I
 			foundDiffs++
 
 			// 记录差异
-			s.recordInventoryDiff(ctx, inventory.ProductID, inventory.Stock, redisStock, diff)
+			s.recordInventoryDiff(ctx, inventory.ProductID, inventory.Stock, redisStock, inventory.Reserved, diff)
 
 			// 检查是否需要报警
 			if abs(diff) > s.config.Inventory.HealthCheck.AlertThreshold {
 				s.createAlert(ctx, inventory.ProductID, "diff_alert",
-					fmt.Sprintf("库存差异过大: DB=%d, Redis=%d, 差异=%d", inventory.Stock, redisStock, diff), "error")
+					fmt.Sprintf("库存差异过大: DB=%d, Redis=%d, 预留=%d, 差异=%d", inventory.Stock, redisStock, inventory.Reserved, diff), "error")
 			}
 		}
 
@@ -284,6 +288,7 @@ func (s *InventoryService) CheckInventoryHealth(ctx context.Context) ([]*model.I
 			ProductID:  inventory.ProductID,
 			DBStock:    inventory.Stock,
 			RedisStock: redisStock,
+			Reserved:   inventory.Reserved,
 			Diff:       diff,
 			Status:     status,
 		})
@@ -307,14 +312,34 @@ func (s *InventoryService) FixInventoryDiff(ctx context.Context, diffID uint, fi
 		return fmt.Errorf("获取差异记录失败: %w", err)
 	}
 
+	// 有进行中的秒杀预留时 Redis 库存只能降到预留以内，不能用仓库库存覆盖，也不能反写数据库
+	var inventory model.Inventory
+	if err := s.db.GetDB().WithContext(ctx).Where("product_id = ?", diff.ProductID).First(&inventory).Error; err != nil {
+		return fmt.Errorf("获取库存信息失败: %w", err)
+	}
+	if inventory.Reserved > 0 {
+		if fixType != "use_db" {
+			return fmt.Errorf("商品 %d 有进行中的库存预留，Redis 库存不代表仓库库存，只能使用 use_db 修复", diff.ProductID)
+		}
+		if err := s.clampRedisStock(ctx, diff.ProductID, inventory.Reserved); err != nil {
+			return fmt.Errorf("更新Redis库存失败: %w", err)
+		}
+		return s.markDiffFixed(&diff, fixType)
+	}
+
 	switch fixType {
 	case "use_db":
 		// 使用数据库库存作为准确值，更新Redis
-		redisKey := fmt.Sprintf("seckill:stock:%d", diff.ProductID)
-		err = s.redisClient.Set(ctx, redisKey, diff.DBStock, 0).Err()
+		err = s.setRedisStock(ctx, diff.ProductID, diff.DBStock)
 		if err != nil {
 			return fmt.Errorf("更新Redis库存失败: %w", err)
 		}
+		// 通知秒杀服务清除本地售罄标记
+		if diff.DBStock > 0 {
+			if err := s.redisClient.Publish(ctx, "seckill:soldout", fmt.Sprintf("restock:%d", diff.ProductID)).Err(); err != nil {
+				s.logger.WithError(err).Warnf("广播补货消息失败: product_id=%d", diff.ProductID)
+			}
+		}
 	case "use_redis":
 		// 使用Redis库存作为准确值，更新数据库
 		_, err := s.SyncStock(ctx, &model.SyncStockRequest{
@@ -330,9 +355,13 @@ func (s *InventoryService) FixInventoryDiff(ctx context.Context, diffID uint, fi
 		return fmt.Errorf("不支持的修复类型: %s", fixType)
 	}
 
-	// 更新差异记录状态
+	return s.markDiffFixed(&diff, fixType)
+}
+
+// markDiffFixed 更新差异记录状态
+func (s *InventoryService) markDiffFixed(diff *model.InventoryDiff, fixType string) error {
 	now := time.Now()
-	err = s.db.GetDB().Model(&diff).Updates(map[string]interface{}{
+	err := s.db.GetDB().Model(diff).Updates(map[string]interface{}{
 		"status":   "fixed",
 		"fixed_at": &now,
 		"fixed_by": "system",
@@ -346,7 +375,7 @@ func (s *InventoryService) FixInventoryDiff(ctx context.Context, diffID uint, fi
 	s.stats.FixedDifferences++
 
 	s.logger.WithFields(logrus.Fields{
-		"diff_id":    diffID,
+		"diff_id":    diff.ID,
 		"product_id": diff.ProductID,
 		"fix_type":   fixType,
 	}).Info("库存差异修复成功")
@@ -469,11 +498,12 @@ func (s *InventoryService) createAlert(ctx context.Context, productID int64, ale
 }
 
 // recordInventoryDiff 记录库存差异
-func (s *InventoryService) recordInventoryDiff(ctx context.Context, productID, dbStock, redisStock, diff int64) {
+func (s *InventoryService) recordInventoryDiff(ctx context.Context, productID, dbStock, redisStock, reserved, diff int64) {
 	diffRecord := &model.InventoryDiff{
 		ProductID:  productID,
 		DBStock:    dbStock,
 		RedisStock: redisStock,
+		Reserved:   reserved,
 		Diff:       diff,
 		Status:     "pending",
 	}
@@ -510,6 +540,42 @@ func abs(x int64) int64 {
 	return x
 }
 
+// 秒杀服务在Redis中的库存key（{} 为 Redis Cluster hash tag）
+func seckillStockKey(productID int64) string {
+	return fmt.Sprintf("seckill:stock:{%d}", productID)
+}
+
+// 获取秒杀服务在Redis中的库存，不存在时为 0
+func (s *InventoryService) getRedisStock(ctx context.Context, productID int64) (int64, error) {
+	stock, err := s.redisClient.Get(ctx, seckillStockKey(productID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return stock, err
+}
+
+// 覆盖秒杀服务在Redis中的库存
+func (s *InventoryService) setRedisStock(ctx context.Context, productID, stock int64) error {
+	return s.redisClient.Set(ctx, seckillStockKey(productID), stock, 0).Err()
+}
+
+// 库存超过上限时扣减到上限，只减不增，返回扣减的数量
+// KEYS[1]: 库存key
+// ARGV[1]: 上限
+var clampStockScript = redis.NewScript(`
+local excess = tonumber(redis.call('GET', KEYS[1]) or '0') - tonumber(ARGV[1])
+if excess <= 0 then
+    return 0
+end
+redis.call('DECRBY', KEYS[1], excess)
+return excess
+`)
+
+// 秒杀服务在Redis中的库存超过上限时扣减超出部分，读取与扣减在同一脚本中，不会覆盖并发售出
+func (s *InventoryService) clampRedisStock(ctx context.Context, productID, limit int64) error {
+	return clampStockScript.Run(ctx, s.redisClient, []string{seckillStockKey(productID)}, limit).Err()
+}
+
 // GetServiceStats 获取服务统计信息
 func (s *InventoryService) GetServiceStats() ServiceStats {
 	return s.stats
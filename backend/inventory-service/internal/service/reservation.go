@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"inventory-service/internal/model"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var ErrReservationNotFound = errors.New("预留记录不存在")
+
+// ReserveStock 预留库存 - 秒杀活动预热时调用
+// 同一预留ID重复请求时按差额调整预留数量，可用库存不足时返回失败
+func (s *InventoryService) ReserveStock(ctx context.Context, req *model.ReserveStockRequest) (*model.ReservationResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"reservation_id": req.ReservationID,
+		"product_id":     req.ProductID,
+		"quantity":       req.Quantity,
+	}).Info("开始预留库存")
+
+	// 参数验证
+	if req.ProductID <= 0 {
+		return nil, fmt.Errorf("商品ID必须大于0")
+	}
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("预留数量必须大于0")
+	}
+
+	tx := s.db.BeginTx().WithContext(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// 查询已有预留
+	var reservation model.InventoryReservation
+	exists := true
+	err := tx.Where("reservation_id = ?", req.ReservationID).First(&reservation).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			tx.Rollback()
+			return nil, fmt.Errorf("获取预留记录失败: %w", err)
+		}
+		exists = false
+	}
+	if exists && reservation.ProductID != req.ProductID {
+		tx.Rollback()
+		return nil, fmt.Errorf("预留记录 %s 属于商品 %d", req.ReservationID, reservation.ProductID)
+	}
+	if exists && reservation.Status != model.ReservationStatusReserved {
+		tx.Rollback()
+		return s.reservationFailed(&reservation, 0, "预留已释放"), nil
+	}
+
+	var inventory model.Inventory
+	err = tx.Where("product_id = ?", req.ProductID).First(&inventory).Error
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &model.ReservationResponse{
+				ReservationID: req.ReservationID,
+				ProductID:     req.ProductID,
+				Success:       false,
+				Message:       "商品库存不存在",
+			}, nil
+		}
+		return nil, fmt.Errorf("获取库存信息失败: %w", err)
+	}
+
+	delta := req.Quantity - reservation.Quantity
+	if delta > inventory.Available {
+		tx.Rollback()
+		reservation.ReservationID = req.ReservationID
+		reservation.ProductID = req.ProductID
+		return s.reservationFailed(&reservation, inventory.Available, "可用库存不足"), nil
+	}
+
+	// 更新预留库存（使用乐观锁）
+	available := inventory.Available - delta
+	result := tx.Model(&inventory).
+		Where("version = ?", inventory.Version).
+		Updates(map[string]interface{}{
+			"reserved":  inventory.Reserved + delta,
+			"available": available,
+			"version":   inventory.Version + 1,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("更新库存失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("库存更新冲突，请重试")
+	}
+
+	// 保存预留记录
+	if exists {
+		result = tx.Model(&reservation).
+			Where("status = ? AND quantity = ?", model.ReservationStatusReserved, reservation.Quantity).
+			Update("quantity", req.Quantity)
+		if result.Error == nil && result.RowsAffected == 0 {
+			tx.Rollback()
+			return nil, fmt.Errorf("预留更新冲突，请重试")
+		}
+	} else {
+		reservation = model.InventoryReservation{
+			ReservationID: req.ReservationID,
+			ProductID:     req.ProductID,
+			Quantity:      req.Quantity,
+			Status:        model.ReservationStatusReserved,
+			Reason:        req.Reason,
+			Operator:      req.Operator,
+		}
+		result = tx.Create(&reservation)
+	}
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("保存预留记录失败: %w", result.Error)
+	}
+	reservation.Quantity = req.Quantity
+
+	// 记录操作日志，预留不改变库存，变化量为 0
+	if err := tx.Create(&model.InventoryLog{
+		ProductID:   req.ProductID,
+		OpType:      model.InventoryOpTypeReserve,
+		BeforeStock: inventory.Stock,
+		AfterStock:  inventory.Stock,
+		Reason:      fmt.Sprintf("预留 %d（调整 %+d）: %s", req.Quantity, delta, req.Reason),
+		Operator:    operatorOrSystem(req.Operator),
+		TraceID:     req.ReservationID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("记录操作日志失败: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	// 异步更新缓存
+	go s.updateInventoryCache(context.Background(), req.ProductID, inventory.Stock)
+
+	s.logger.WithFields(logrus.Fields{
+		"reservation_id": req.ReservationID,
+		"product_id":     req.ProductID,
+		"quantity":       req.Quantity,
+		"delta":          delta,
+		"available":      available,
+	}).Info("库存预留成功")
+
+	return &model.ReservationResponse{
+		ReservationID: reservation.ReservationID,
+		ProductID:     reservation.ProductID,
+		Quantity:      reservation.Quantity,
+		Status:        reservation.Status,
+		Available:     available,
+		Success:       true,
+	}, nil
+}
+
+// ReleaseReservation 释放预留 - 秒杀活动结束后调用
+// 归还的数量回到可用库存，其余视为已售出从库存中扣减；重复释放直接返回已有结果
+func (s *InventoryService) ReleaseReservation(ctx context.Context, reservationID string, req *model.ReleaseReservationRequest) (*model.ReservationResponse, error) {
+	tx := s.db.BeginTx().WithContext(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	var reservation model.InventoryReservation
+	err := tx.Where("reservation_id = ?", reservationID).First(&reservation).Error
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReservationNotFound
+		}
+		return nil, fmt.Errorf("获取预留记录失败: %w", err)
+	}
+	if reservation.Status == model.ReservationStatusReleased {
+		tx.Rollback()
+		return &model.ReservationResponse{
+			ReservationID: reservation.ReservationID,
+			ProductID:     reservation.ProductID,
+			Quantity:      reservation.Quantity,
+			Returned:      reservation.Returned,
+			Status:        reservation.Status,
+			Success:       true,
+			Message:       "预留已释放",
+		}, nil
+	}
+	if req.Returned < 0 || req.Returned > reservation.Quantity {
+		tx.Rollback()
+		return nil, fmt.Errorf("归还数量必须在 0 到 %d 之间", reservation.Quantity)
+	}
+
+	var inventory model.Inventory
+	err = tx.Where("product_id = ?", reservation.ProductID).First(&inventory).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("获取库存信息失败: %w", err)
+	}
+
+	sold := reservation.Quantity - req.Returned
+	beforeStock := inventory.Stock
+	afterStock := beforeStock - sold
+	reserved := inventory.Reserved - reservation.Quantity
+	available := afterStock - reserved
+
+	// 更新库存（使用乐观锁）
+	result := tx.Model(&inventory).
+		Where("version = ?", inventory.Version).
+		Updates(map[string]interface{}{
+			"stock":     afterStock,
+			"reserved":  reserved,
+			"available": available,
+			"version":   inventory.Version + 1,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("更新库存失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("库存更新冲突，请重试")
+	}
+
+	now := time.Now()
+	result = tx.Model(&reservation).
+		Where("status = ?", model.ReservationStatusReserved).
+		Updates(map[string]interface{}{
+			"status":      model.ReservationStatusReleased,
+			"returned":    req.Returned,
+			"released_at": &now,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("更新预留记录失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("预留更新冲突，请重试")
+	}
+
+	// 记录操作日志，售出部分计入库存变化
+	if err := tx.Create(&model.InventoryLog{
+		ProductID:   reservation.ProductID,
+		OpType:      model.InventoryOpTypeRelease,
+		Delta:       -sold,
+		BeforeStock: beforeStock,
+		AfterStock:  afterStock,
+		Reason:      fmt.Sprintf("释放预留 %d，售出 %d，归还 %d: %s", reservation.Quantity, sold, req.Returned, req.Reason),
+		Operator:    operatorOrSystem(reservation.Operator),
+		TraceID:     reservationID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("记录操作日志失败: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	// 异步更新缓存并检查库存预警
+	go s.updateInventoryCache(context.Background(), reservation.ProductID, afterStock)
+	go s.checkStockAlert(context.Background(), reservation.ProductID, afterStock)
+
+	s.logger.WithFields(logrus.Fields{
+		"reservation_id": reservationID,
+		"product_id":     reservation.ProductID,
+		"sold":           sold,
+		"returned":       req.Returned,
+		"after_stock":    afterStock,
+	}).Info("库存预留释放成功")
+
+	return &model.ReservationResponse{
+		ReservationID: reservation.ReservationID,
+		ProductID:     reservation.ProductID,
+		Quantity:      reservation.Quantity,
+		Returned:      req.Returned,
+		Status:        model.ReservationStatusReleased,
+		Available:     available,
+		Success:       true,
+	}, nil
+}
+
+// reservationFailed 预留失败响应
+func (s *InventoryService) reservationFailed(reservation *model.InventoryReservation, available int64, message string) *model.ReservationResponse {
+	return &model.ReservationResponse{
+		ReservationID: reservation.ReservationID,
+		ProductID:     reservation.ProductID,
+		Quantity:      reservation.Quantity,
+		Returned:      reservation.Returned,
+		Status:        reservation.Status,
+		Available:     available,
+		Success:       false,
+		Message:       message,
+	}
+}
+
+func operatorOrSystem(operator string) string {
+	if operator == "" {
+		return "system"
+	}
+	return operator
+}
@@ -14,7 +14,10 @@ import (
 	"order-service/internal/config"
 	"order-service/internal/database"
 	"order-service/internal/handler"
+	"order-service/internal/idgen"
 	"order-service/internal/mq"
+	"order-service/internal/report"
+	"order-service/internal/reservation"
 	"order-service/internal/router"
 	"order-service/internal/service"
 
@@ -42,41 +45,105 @@ func main() {
 	// 初始化 Redis 客户端
 	redisClient := database.NewRedisClient(&cfg.Redis)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 初始化订单ID生成器
+	// 工作节点ID限定在本服务的范围内，与 seckill-service 生成的订单ID不重复
+	workerID := cfg.IDGenerator.WorkerID
+	if !cfg.IDGenerator.Lease && !idgen.OrderWorkerIDs.Contains(workerID) {
+		logger.Fatalf("工作节点ID %d 超出 order-service 的范围 %d-%d", workerID, idgen.OrderWorkerIDs.Min, idgen.OrderWorkerIDs.Max)
+	}
+	var workerLease *idgen.WorkerLease
+	if cfg.IDGenerator.Lease {
+		hostname, _ := os.Hostname()
+		owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+		workerLease, err = idgen.AcquireWorkerLease(ctx, redisClient, "order:idgen:worker", idgen.OrderWorkerIDs, owner, cfg.IDGenerator.LeaseTTL, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("租用工作节点ID失败")
+		}
+		workerID = workerLease.WorkerID()
+	}
+	idGenerator, err := idgen.NewGenerator(workerID)
+	if err != nil {
+		logger.WithError(err).Fatal("初始化订单ID生成器失败")
+	}
+	if cfg.IDGenerator.MaxClockBackward > 0 {
+		idGenerator.SetMaxBackward(cfg.IDGenerator.MaxClockBackward)
+	}
+	if workerLease != nil {
+		go workerLease.KeepAlive(ctx, idGenerator)
+		defer workerLease.Release(context.Background())
+	}
+
+	// 初始化秒杀库存预留确认器，使用 seckill-service 的 Redis 库
+	var confirmer *reservation.Confirmer
+	if cfg.Reservation.Enable {
+		reservationRedisCfg := cfg.Redis
+		reservationRedisCfg.DB = cfg.Reservation.DB
+		confirmer = reservation.NewConfirmer(database.NewRedisClient(&reservationRedisCfg), logger)
+	}
+
+	// 初始化秒杀活动报告订单计数，使用 seckill-service 的 Redis 库
+	var reportCounter *report.Counter
+	if cfg.Report.Enable {
+		reportRedisCfg := cfg.Redis
+		reportRedisCfg.DB = cfg.Report.DB
+		reportCounter = report.NewCounter(database.NewRedisClient(&reportRedisCfg), logger)
+	}
+
 	// 初始化订单服务
-	orderService := service.NewOrderService(cfg, db, redisClient, logger)
+	orderService := service.NewOrderService(cfg, db, redisClient, idGenerator, confirmer, reportCounter, logger)
 
 	// 初始化失败补偿管理器
 	compensationManager := compensation.NewCompensationManager(cfg, db, orderService, logger)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	go compensationManager.Start(ctx) // 启动定时任务
 
 	// 初始化消息队列消费者
-	var consumers []interface{}
-
-	// RabbitMQ 消费者 (简化处理，假设总是启用)
-	rabbitConsumer, err := mq.NewRabbitMQConsumer(&cfg.RabbitMQ, orderService, logger)
-	if err != nil {
-		logger.WithError(err).Fatal("初始化RabbitMQ消费者失败")
+	var consumers []mq.Consumer
+
+	switch cfg.MQ.Type {
+	case config.MQTypeRedisStream:
+		// Redis Stream 消费者，使用 seckill-service 写入的 Redis 库
+		streamRedisCfg := cfg.Redis
+		streamRedisCfg.DB = cfg.RedisStream.DB
+		hostname, _ := os.Hostname()
+		streamConsumer := mq.NewRedisStreamConsumer(
+			database.NewRedisClient(&streamRedisCfg),
+			&cfg.RedisStream,
+			fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+			orderService,
+			logger,
+		)
+		if err := streamConsumer.StartConsume(ctx); err != nil {
+			logger.WithError(err).Fatal("启动Redis Stream消费者失败")
+		}
+		consumers = append(consumers, streamConsumer)
+	default:
+		// RabbitMQ 消费者 (简化处理，假设总是启用)
+		rabbitConsumer, err := mq.NewRabbitMQConsumer(&cfg.RabbitMQ, orderService, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("初始化RabbitMQ消费者失败")
+		}
+		consumers = append(consumers, rabbitConsumer)
+
+		// Kafka 消费者 (简化处理，假设总是启用)
+		kafkaConsumer := mq.NewKafkaConsumer(&cfg.Kafka, orderService, logger)
+		consumers = append(consumers, kafkaConsumer)
+
+		// 启动消费者 (暂时注释掉，因为接口不明确)
+		// for _, consumer := range consumers {
+		// 	go func(c interface{}) {
+		// 		// 这里需要根据实际的消费者接口来实现
+		// 		// if starter, ok := c.(interface{ Start(context.Context) error }); ok {
+		// 		// 	if err := starter.Start(context.Background()); err != nil {
+		// 		// 		logger.WithError(err).Error("消费者启动失败")
+		// 		// 	}
+		// 		// }
+		// 	}(consumer)
+		// }
 	}
-	consumers = append(consumers, rabbitConsumer)
-
-	// Kafka 消费者 (简化处理，假设总是启用)
-	kafkaConsumer := mq.NewKafkaConsumer(&cfg.Kafka, orderService, logger)
-	consumers = append(consumers, kafkaConsumer)
-
-	// 启动消费者 (暂时注释掉，因为接口不明确)
-	// for _, consumer := range consumers {
-	// 	go func(c interface{}) {
-	// 		// 这里需要根据实际的消费者接口来实现
-	// 		// if starter, ok := c.(interface{ Start(context.Context) error }); ok {
-	// 		// 	if err := starter.Start(context.Background()); err != nil {
-	// 		// 		logger.WithError(err).Error("消费者启动失败")
-	// 		// 	}
-	// 		// }
-	// 	}(consumer)
-	// }
 
 	// 初始化HTTP处理器和路由
 	orderHandler := handler.NewOrderHandler(orderService)
@@ -103,13 +170,10 @@ func main() {
 
 	logger.Info("正在关闭服务器...")
 
-	// 停止消费者 (暂时注释掉)
-	// for _, consumer := range consumers {
-	// 	// 这里需要根据实际的消费者接口来实现
-	// 	// if stopper, ok := consumer.(interface{ Stop() }); ok {
-	// 	// 	stopper.Stop()
-	// 	// }
-	// }
+	// 停止消费者
+	for _, consumer := range consumers {
+		consumer.Close()
+	}
 
 	// 关闭HTTP服务器
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
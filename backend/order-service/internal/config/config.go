@@ -7,14 +7,19 @@ import (
 )
 
 type Config struct {
-	Server     ServerConfig     `mapstructure:"server"`
-	Database   DatabaseConfig   `mapstructure:"database"`
-	Redis      RedisConfig      `mapstructure:"redis"`
-	RabbitMQ   RabbitMQConfig   `mapstructure:"rabbitmq"`
-	Kafka      KafkaConfig      `mapstructure:"kafka"`
-	Order      OrderConfig      `mapstructure:"order"`
-	Log        LogConfig        `mapstructure:"log"`
-	Monitoring MonitoringConfig `mapstructure:"monitoring"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	MQ          MQConfig          `mapstructure:"mq"`
+	RabbitMQ    RabbitMQConfig    `mapstructure:"rabbitmq"`
+	Kafka       KafkaConfig       `mapstructure:"kafka"`
+	RedisStream RedisStreamConfig `mapstructure:"redis_stream"`
+	Reservation ReservationConfig `mapstructure:"seckill_reservation"`
+	Report      ReportConfig      `mapstructure:"seckill_report"`
+	Order       OrderConfig       `mapstructure:"order"`
+	IDGenerator IDGeneratorConfig `mapstructure:"id_generator"`
+	Log         LogConfig         `mapstructure:"log"`
+	Monitoring  MonitoringConfig  `mapstructure:"monitoring"`
 }
 
 type ServerConfig struct {
@@ -65,6 +70,17 @@ type RedisConfig struct {
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 }
 
+// 消息队列类型
+const (
+	MQTypeRabbitMQ    = "rabbitmq"
+	MQTypeKafka       = "kafka"
+	MQTypeRedisStream = "redis_stream"
+)
+
+type MQConfig struct {
+	Type string `mapstructure:"type"`
+}
+
 type RabbitMQConfig struct {
 	URL           string `mapstructure:"url"`
 	Exchange      string `mapstructure:"exchange"`
@@ -83,6 +99,25 @@ type KafkaConfig struct {
 	Timeout   time.Duration `mapstructure:"timeout"`
 }
 
+type RedisStreamConfig struct {
+	Stream       string        `mapstructure:"stream"`
+	Group        string        `mapstructure:"group"`
+	DB           int           `mapstructure:"db"` // 与 seckill-service 使用的 Redis 库一致
+	BatchSize    int64         `mapstructure:"batch_size"`
+	BlockTimeout time.Duration `mapstructure:"block_timeout"`
+	ClaimIdle    time.Duration `mapstructure:"claim_idle"`
+}
+
+type ReservationConfig struct {
+	Enable bool `mapstructure:"enable"`
+	DB     int  `mapstructure:"db"` // 与 seckill-service 使用的 Redis 库一致
+}
+
+type ReportConfig struct {
+	Enable bool `mapstructure:"enable"`
+	DB     int  `mapstructure:"db"` // 与 seckill-service 使用的 Redis 库一致
+}
+
 type OrderConfig struct {
 	OrderTimeout   time.Duration      `mapstructure:"order_timeout"`
 	PaymentTimeout time.Duration      `mapstructure:"payment_timeout"`
@@ -104,6 +139,13 @@ type CompensationConfig struct {
 	BatchSize     int           `mapstructure:"batch_size"`
 }
 
+type IDGeneratorConfig struct {
+	WorkerID         int64         `mapstructure:"worker_id"`
+	Lease            bool          `mapstructure:"lease"`
+	LeaseTTL         time.Duration `mapstructure:"lease_ttl"`
+	MaxClockBackward time.Duration `mapstructure:"max_clock_backward"`
+}
+
 type LogConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
@@ -79,6 +79,7 @@ func (d *Database) AutoMigrate() error {
 		&model.OrderFailure{},
 		&model.OrderIdempotency{},
 		&model.OrderStats{},
+		&model.CouponGrant{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
@@ -0,0 +1,179 @@
+package idgen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// 续约脚本：仅当租约仍归属本节点时延长过期时间
+const renewLeaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`
+
+// 释放脚本：仅删除本节点持有的租约
+const releaseLeaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// 租约所需的 Redis 客户端接口
+type LeaseClient interface {
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// 基于 Redis 的工作节点 ID 租约
+type WorkerLease struct {
+	client    LeaseClient
+	keyPrefix string
+	ids       WorkerIDRange
+	owner     string
+	ttl       time.Duration
+	logger    *logrus.Logger
+
+	mutex     sync.Mutex
+	workerID  int64
+	held      bool      // 租约是否有效，丢失后重新租用前为 false
+	renewedAt time.Time // 最近一次成功租用或续约的时间
+}
+
+// 从 Redis 租用 ids 范围内一个空闲的工作节点 ID
+func AcquireWorkerLease(ctx context.Context, client LeaseClient, keyPrefix string, ids WorkerIDRange, owner string, ttl time.Duration, logger *logrus.Logger) (*WorkerLease, error) {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	if err := ids.validate(); err != nil {
+		return nil, err
+	}
+
+	l := &WorkerLease{
+		client:    client,
+		keyPrefix: keyPrefix,
+		ids:       ids,
+		owner:     owner,
+		ttl:       ttl,
+		logger:    logger,
+	}
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// 依次尝试范围内的工作节点 ID，租用第一个空闲的
+func (l *WorkerLease) acquire(ctx context.Context) error {
+	for id := l.ids.Min; id <= l.ids.Max; id++ {
+		start := time.Now()
+		ok, err := l.client.SetNX(ctx, l.keyFor(id), l.owner, l.ttl).Result()
+		if err != nil {
+			return fmt.Errorf("failed to acquire worker lease: %w", err)
+		}
+		if ok {
+			l.mutex.Lock()
+			l.workerID, l.held, l.renewedAt = id, true, start
+			l.mutex.Unlock()
+
+			l.logger.Infof("Acquired worker id %d (owner: %s, ttl: %s)", id, l.owner, l.ttl)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no free worker id in %d-%d under %s", l.ids.Min, l.ids.Max, l.keyPrefix)
+}
+
+// 获取租用的工作节点 ID
+func (l *WorkerLease) WorkerID() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.workerID
+}
+
+// 定期续约，直到上下文取消。
+// 续约失败持续到下一次续约前租约可能过期时视为丢失：generator 立即失效，避免其他节点租用同一 ID 后生成重复 ID；
+// 租约丢失后每个续约周期尝试重新租用，成功后 generator 切换到新的工作节点 ID 继续生成
+func (l *WorkerLease) KeepAlive(ctx context.Context, generator *Generator) {
+	interval := l.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		l.mutex.Lock()
+		held, workerID, renewedAt := l.held, l.workerID, l.renewedAt
+		l.mutex.Unlock()
+
+		if !held {
+			if err := l.acquire(ctx); err != nil {
+				l.logger.Warnf("Failed to re-acquire worker lease: %v", err)
+				continue
+			}
+			if err := generator.Reassign(l.WorkerID()); err != nil {
+				l.logger.Errorf("Failed to switch id generator to worker id %d: %v", l.WorkerID(), err)
+			}
+			continue
+		}
+
+		start := time.Now()
+		renewed, err := l.client.Eval(ctx, renewLeaseScript, []string{l.keyFor(workerID)}, l.owner, l.ttl.Milliseconds()).Int64()
+		switch {
+		case err == nil && renewed == 1:
+			l.mutex.Lock()
+			l.renewedAt = start
+			l.mutex.Unlock()
+		case err == nil:
+			l.logger.Errorf("Worker lease %d lost", workerID)
+			l.lose(generator)
+		case time.Since(renewedAt)+interval >= l.ttl:
+			// 下一次续约前租约可能已过期，不再使用该 ID
+			l.logger.Errorf("Failed to renew worker lease %d since %s, treating it as lost: %v", workerID, renewedAt.Format(time.RFC3339), err)
+			l.lose(generator)
+		default:
+			// 网络抖动时继续重试，租约在 TTL 内仍然有效
+			l.logger.Warnf("Failed to renew worker lease %d: %v", workerID, err)
+		}
+	}
+}
+
+func (l *WorkerLease) lose(generator *Generator) {
+	generator.Invalidate(ErrWorkerLeaseLost)
+
+	l.mutex.Lock()
+	l.held = false
+	l.mutex.Unlock()
+}
+
+// 释放租约
+func (l *WorkerLease) Release(ctx context.Context) error {
+	l.mutex.Lock()
+	held, workerID := l.held, l.workerID
+	l.held = false
+	l.mutex.Unlock()
+
+	if !held {
+		return nil
+	}
+	if err := l.client.Eval(ctx, releaseLeaseScript, []string{l.keyFor(workerID)}, l.owner).Err(); err != nil {
+		return fmt.Errorf("failed to release worker lease: %w", err)
+	}
+	l.logger.Infof("Released worker id %d", workerID)
+	return nil
+}
+
+func (l *WorkerLease) keyFor(workerID int64) string {
+	return fmt.Sprintf("%s:%d", l.keyPrefix, workerID)
+}
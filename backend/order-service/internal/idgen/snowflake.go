@@ -0,0 +1,195 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ID 位布局：1 位符号 | 41 位毫秒时间戳 | 10 位工作节点 | 12 位序列号
+const (
+	workerBits   = 10
+	sequenceBits = 12
+
+	MaxWorkerID  = -1 ^ (-1 << workerBits)
+	maxSequence  = -1 ^ (-1 << sequenceBits)
+	workerShift  = sequenceBits
+	timeShift    = sequenceBits + workerBits
+	encodedWidth = 13 // 63 位整数的 36 进制最大长度
+)
+
+// 工作节点 ID 范围（含两端）
+type WorkerIDRange struct {
+	Min int64
+	Max int64
+}
+
+// 各服务的工作节点 ID 范围互不重叠：两个服务使用相同的纪元与位布局，
+// 秒杀订单都以 SK 为前缀，范围不重叠才能保证两边生成的订单ID不重复
+var (
+	SeckillWorkerIDs = WorkerIDRange{Min: 0, Max: 511}
+	OrderWorkerIDs   = WorkerIDRange{Min: 512, Max: MaxWorkerID}
+)
+
+// 是否包含工作节点 ID
+func (r WorkerIDRange) Contains(workerID int64) bool {
+	return workerID >= r.Min && workerID <= r.Max
+}
+
+func (r WorkerIDRange) validate() error {
+	if r.Min < 0 || r.Max > MaxWorkerID || r.Min > r.Max {
+		return fmt.Errorf("%w: range %d-%d (0-%d)", ErrInvalidWorkerID, r.Min, r.Max, MaxWorkerID)
+	}
+	return nil
+}
+
+// 起始纪元 2024-01-01T00:00:00Z（毫秒）
+const defaultEpoch int64 = 1704067200000
+
+// 默认允许的时钟回拨容忍时间
+const defaultMaxBackward = 5 * time.Second
+
+var (
+	ErrInvalidWorkerID  = errors.New("worker id out of range")
+	ErrClockMovedBack   = errors.New("clock moved backwards beyond tolerance")
+	ErrWorkerLeaseLost  = errors.New("worker id lease lost")
+	ErrInvalidEncodedID = errors.New("invalid encoded id")
+)
+
+// 雪花 ID 生成器
+type Generator struct {
+	workerID    int64
+	epoch       int64
+	maxBackward int64 // 毫秒
+
+	mutex    sync.Mutex
+	lastTime int64
+	sequence int64
+	invalid  error
+}
+
+// 创建 ID 生成器
+func NewGenerator(workerID int64) (*Generator, error) {
+	if workerID < 0 || workerID > MaxWorkerID {
+		return nil, fmt.Errorf("%w: %d (0-%d)", ErrInvalidWorkerID, workerID, MaxWorkerID)
+	}
+
+	return &Generator{
+		workerID:    workerID,
+		epoch:       defaultEpoch,
+		maxBackward: defaultMaxBackward.Milliseconds(),
+	}, nil
+}
+
+// 设置时钟回拨容忍时间
+func (g *Generator) SetMaxBackward(d time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.maxBackward = d.Milliseconds()
+}
+
+// 生成下一个 ID
+func (g *Generator) NextID() (int64, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.invalid != nil {
+		return 0, g.invalid
+	}
+
+	now := time.Now().UnixMilli() - g.epoch
+
+	// 时钟回拨：容忍范围内沿用上次时间戳继续递增，保证节点内单调
+	if now < g.lastTime {
+		if g.lastTime-now > g.maxBackward {
+			return 0, fmt.Errorf("%w: %dms", ErrClockMovedBack, g.lastTime-now)
+		}
+		now = g.lastTime
+	}
+
+	if now == g.lastTime {
+		g.sequence = (g.sequence + 1) & maxSequence
+		if g.sequence == 0 {
+			// 当前毫秒序列号用尽，借用下一毫秒
+			now = g.lastTime + 1
+		}
+	} else {
+		g.sequence = 0
+	}
+
+	g.lastTime = now
+	return now<<timeShift | g.workerID<<workerShift | g.sequence, nil
+}
+
+// 生成带前缀的紧凑字符串 ID（定长 36 进制，可按字典序排序）
+func (g *Generator) NextString(prefix string) (string, error) {
+	id, err := g.NextID()
+	if err != nil {
+		return "", err
+	}
+	return prefix + Encode(id), nil
+}
+
+// 使生成器失效（如工作节点租约丢失）
+func (g *Generator) Invalidate(err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.invalid = err
+}
+
+// 切换到新租用的工作节点 ID 并恢复生成，时间戳与序列号保持单调
+func (g *Generator) Reassign(workerID int64) error {
+	if workerID < 0 || workerID > MaxWorkerID {
+		return fmt.Errorf("%w: %d (0-%d)", ErrInvalidWorkerID, workerID, MaxWorkerID)
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.workerID = workerID
+	g.invalid = nil
+	return nil
+}
+
+// 获取工作节点 ID
+func (g *Generator) WorkerID() int64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.workerID
+}
+
+// 编码为定长 36 进制字符串
+func Encode(id int64) string {
+	s := strings.ToUpper(strconv.FormatInt(id, 36))
+	if len(s) < encodedWidth {
+		s = strings.Repeat("0", encodedWidth-len(s)) + s
+	}
+	return s
+}
+
+// 解码 36 进制字符串
+func Decode(s string) (int64, error) {
+	id, err := strconv.ParseInt(strings.ToLower(s), 36, 64)
+	if err != nil || id < 0 {
+		return 0, ErrInvalidEncodedID
+	}
+	return id, nil
+}
+
+// ID 组成部分
+type IDParts struct {
+	Time     time.Time `json:"time"`
+	WorkerID int64     `json:"worker_id"`
+	Sequence int64     `json:"sequence"`
+}
+
+// 解析 ID
+func Parse(id int64) IDParts {
+	return IDParts{
+		Time:     time.UnixMilli((id >> timeShift) + defaultEpoch),
+		WorkerID: (id >> workerShift) & MaxWorkerID,
+		Sequence: id & maxSequence,
+	}
+}
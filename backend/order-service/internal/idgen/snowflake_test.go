@@ -0,0 +1,127 @@
+package idgen
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextIDMonotonic(t *testing.T) {
+	tests := []struct {
+		name     string
+		workerID int64
+		count    int
+	}{
+		{name: "single", workerID: 0, count: 1},
+		{name: "sequence overflow", workerID: 7, count: 3 * (maxSequence + 1)},
+		{name: "max worker", workerID: MaxWorkerID, count: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGenerator(tt.workerID)
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+
+			var last int64 = -1
+			var lastEncoded string
+			for i := 0; i < tt.count; i++ {
+				id, err := g.NextID()
+				if err != nil {
+					t.Fatalf("NextID #%d: %v", i, err)
+				}
+				if id <= last {
+					t.Fatalf("NextID #%d = %d, not greater than %d", i, id, last)
+				}
+				if encoded := Encode(id); encoded <= lastEncoded {
+					t.Fatalf("Encode(%d) = %s, not greater than %s", id, encoded, lastEncoded)
+				} else {
+					lastEncoded = encoded
+				}
+				if parts := Parse(id); parts.WorkerID != tt.workerID {
+					t.Fatalf("Parse(%d).WorkerID = %d, want %d", id, parts.WorkerID, tt.workerID)
+				}
+				last = id
+			}
+		})
+	}
+}
+
+func TestNextIDClockRollback(t *testing.T) {
+	tests := []struct {
+		name     string
+		backward time.Duration
+		sequence int64
+		wantErr  error
+		wantTime int64 // 相对上次时间戳的偏移
+		wantSeq  int64
+	}{
+		{name: "within tolerance", backward: time.Second, sequence: 5, wantTime: 0, wantSeq: 6},
+		{name: "within tolerance sequence exhausted", backward: time.Second, sequence: maxSequence, wantTime: 1, wantSeq: 0},
+		{name: "near tolerance", backward: defaultMaxBackward - 100*time.Millisecond, sequence: 0, wantTime: 0, wantSeq: 1},
+		{name: "beyond tolerance", backward: 2 * defaultMaxBackward, sequence: 0, wantErr: ErrClockMovedBack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGenerator(1)
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+
+			// 上次时间戳在当前时间之后，等价于时钟回拨了 backward
+			lastTime := time.Now().UnixMilli() - g.epoch + tt.backward.Milliseconds()
+			g.lastTime = lastTime
+			g.sequence = tt.sequence
+
+			id, err := g.NextID()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("NextID error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NextID: %v", err)
+			}
+
+			if got := id >> timeShift; got != lastTime+tt.wantTime {
+				t.Errorf("timestamp = %d, want %d", got, lastTime+tt.wantTime)
+			}
+			if got := id & maxSequence; got != tt.wantSeq {
+				t.Errorf("sequence = %d, want %d", got, tt.wantSeq)
+			}
+		})
+	}
+}
+
+func TestNextIDInvalidated(t *testing.T) {
+	g, err := NewGenerator(3)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	before, err := g.NextID()
+	if err != nil {
+		t.Fatalf("NextID: %v", err)
+	}
+
+	g.Invalidate(ErrWorkerLeaseLost)
+	if _, err := g.NextID(); !errors.Is(err, ErrWorkerLeaseLost) {
+		t.Fatalf("NextID after Invalidate error = %v, want %v", err, ErrWorkerLeaseLost)
+	}
+
+	if err := g.Reassign(4); err != nil {
+		t.Fatalf("Reassign: %v", err)
+	}
+	after, err := g.NextID()
+	if err != nil {
+		t.Fatalf("NextID after Reassign: %v", err)
+	}
+	if after <= before {
+		t.Errorf("NextID after Reassign = %d, not greater than %d", after, before)
+	}
+	if parts := Parse(after); parts.WorkerID != 4 {
+		t.Errorf("Parse(%d).WorkerID = %d, want 4", after, parts.WorkerID)
+	}
+}
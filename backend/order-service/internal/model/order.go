@@ -117,6 +117,19 @@ type OrderStats struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// 发券记录表（发券活动不创建订单，按发放流水号幂等记录）
+type CouponGrant struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	GrantID    string    `gorm:"size:64;not null;uniqueIndex" json:"grant_id"`
+	ProductID  int64     `gorm:"not null;index" json:"product_id"`
+	UserID     int64     `gorm:"not null;index" json:"user_id"`
+	CouponCode string    `gorm:"size:64;not null" json:"coupon_code"`
+	GrantTime  time.Time `json:"grant_time"`
+	TraceID    string    `gorm:"size:64" json:"trace_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // 表初始化
 func (Order) TableName() string {
 	return "orders"
@@ -138,6 +151,10 @@ func (OrderStats) TableName() string {
 	return "order_stats"
 }
 
+func (CouponGrant) TableName() string {
+	return "coupon_grants"
+}
+
 // 创建唯一索引（防重复下单）
 func CreateUniqueIndexes(db *gorm.DB) error {
 	// 用户-商品唯一索引（防止重复下单）
@@ -163,7 +180,7 @@ func CreateUniqueIndexes(db *gorm.DB) error {
 
 // 订单请求DTO
 type CreateOrderRequest struct {
-	OrderID     string  `json:"order_id" binding:"required"`
+	OrderID     string  `json:"order_id"` // 为空时由服务端生成
 	UserID      int64   `json:"user_id" binding:"required"`
 	ProductID   int64   `json:"product_id" binding:"required"`
 	ProductName string  `json:"product_name" binding:"required"`
@@ -16,6 +16,7 @@ import (
 // 消息处理器接口
 type MessageHandler interface {
 	HandleSeckillOrder(ctx context.Context, message *SeckillOrderMessage) error
+	HandleCouponGranted(ctx context.Context, message *CouponGrantedMessage) error
 	HandleStockUpdate(ctx context.Context, message *StockUpdateMessage) error
 	HandleUserNotify(ctx context.Context, message *UserNotifyMessage) error
 }
@@ -163,6 +164,11 @@ func (c *RabbitMQConsumer) handleMessage(ctx context.Context, msg amqp.Delivery)
 		if err = json.Unmarshal(msg.Body, &orderMsg); err == nil {
 			err = c.handler.HandleSeckillOrder(ctx, &orderMsg)
 		}
+	case MessageTypeCouponGranted:
+		var couponMsg CouponGrantedMessage
+		if err = json.Unmarshal(msg.Body, &couponMsg); err == nil {
+			err = c.handler.HandleCouponGranted(ctx, &couponMsg)
+		}
 	case MessageTypeStockUpdate:
 		var stockMsg StockUpdateMessage
 		if err = json.Unmarshal(msg.Body, &stockMsg); err == nil {
@@ -271,6 +277,11 @@ func (c *KafkaConsumer) handleMessage(ctx context.Context, msg kafka.Message) {
 		if err = json.Unmarshal(msg.Value, &orderMsg); err == nil {
 			err = c.handler.HandleSeckillOrder(ctx, &orderMsg)
 		}
+	case MessageTypeCouponGranted:
+		var couponMsg CouponGrantedMessage
+		if err = json.Unmarshal(msg.Value, &couponMsg); err == nil {
+			err = c.handler.HandleCouponGranted(ctx, &couponMsg)
+		}
 	case MessageTypeStockUpdate:
 		var stockMsg StockUpdateMessage
 		if err = json.Unmarshal(msg.Value, &stockMsg); err == nil {
@@ -317,3 +328,4 @@ type Consumer interface {
 // 确保实现了接口
 var _ Consumer = (*RabbitMQConsumer)(nil)
 var _ Consumer = (*KafkaConsumer)(nil)
+var _ Consumer = (*RedisStreamConsumer)(nil)
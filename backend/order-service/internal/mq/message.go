@@ -17,6 +17,17 @@ type SeckillOrderMessage struct {
 	TraceID     string    `json:"trace_id"`
 }
 
+// 发券消息（从 seckill-service 接收），发券活动不创建订单
+type CouponGrantedMessage struct {
+	GrantID     string    `json:"grant_id"`
+	ProductID   int64     `json:"product_id"`
+	UserID      int64     `json:"user_id"`
+	CouponCode  string    `json:"coupon_code"`
+	GrantTime   time.Time `json:"grant_time"`
+	MessageType string    `json:"message_type"`
+	TraceID     string    `json:"trace_id"`
+}
+
 // 库存更新消息
 type StockUpdateMessage struct {
 	ProductID      int64     `json:"product_id"`
@@ -52,10 +63,11 @@ type OrderStatusMessage struct {
 
 // 消息类型常量
 const (
-	MessageTypeSeckillOrder = "seckill_order"
-	MessageTypeStockUpdate  = "stock_update"
-	MessageTypeUserNotify   = "user_notify"
-	MessageTypeOrderStatus  = "order_status"
+	MessageTypeSeckillOrder  = "seckill_order"
+	MessageTypeStockUpdate   = "stock_update"
+	MessageTypeUserNotify    = "user_notify"
+	MessageTypeOrderStatus   = "order_status"
+	MessageTypeCouponGranted = "coupon_granted"
 )
 
 // 通知类型常量
@@ -0,0 +1,211 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"order-service/internal/config"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Redis Stream 条目字段（与 seckill-service 保持一致）
+const (
+	StreamFieldType    = "type"
+	StreamFieldPayload = "payload"
+)
+
+// Redis Stream 消费者
+type RedisStreamConsumer struct {
+	client    *redis.Client
+	stream    string
+	group     string
+	consumer  string
+	batchSize int64
+	block     time.Duration
+	claimIdle time.Duration
+	handler   MessageHandler
+	logger    *logrus.Logger
+}
+
+// 创建 Redis Stream 消费者
+func NewRedisStreamConsumer(client *redis.Client, cfg *config.RedisStreamConfig, consumerName string, handler MessageHandler, logger *logrus.Logger) *RedisStreamConsumer {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 10
+	}
+	block := cfg.BlockTimeout
+	if block <= 0 {
+		block = time.Second
+	}
+	claimIdle := cfg.ClaimIdle
+	if claimIdle <= 0 {
+		claimIdle = time.Minute
+	}
+
+	return &RedisStreamConsumer{
+		client:    client,
+		stream:    cfg.Stream,
+		group:     cfg.Group,
+		consumer:  consumerName,
+		batchSize: batchSize,
+		block:     block,
+		claimIdle: claimIdle,
+		handler:   handler,
+		logger:    logger,
+	}
+}
+
+// 开始消费消息
+func (c *RedisStreamConsumer) StartConsume(ctx context.Context) error {
+	// 创建消费者组（已存在时忽略）
+	err := c.client.XGroupCreateMkStream(ctx, c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			// 认领其他消费者长时间未确认的消息
+			c.reclaimPending(ctx)
+
+			streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    c.group,
+				Consumer: c.consumer,
+				Streams:  []string{c.stream, ">"},
+				Count:    c.batchSize,
+				Block:    c.block,
+			}).Result()
+			if err != nil {
+				if err != redis.Nil && ctx.Err() == nil {
+					c.logger.Errorf("Failed to read from stream %s: %v", c.stream, err)
+					time.Sleep(time.Second)
+				}
+				continue
+			}
+
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					c.handleMessage(ctx, msg)
+				}
+			}
+		}
+	}()
+
+	c.logger.Infof("Started consuming Redis stream: stream=%s, group=%s, consumer=%s", c.stream, c.group, c.consumer)
+	return nil
+}
+
+// 通过 XAUTOCLAIM 认领空闲超时的待确认消息
+func (c *RedisStreamConsumer) reclaimPending(ctx context.Context) {
+	start := "0-0"
+	for {
+		messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.stream,
+			Group:    c.group,
+			Consumer: c.consumer,
+			MinIdle:  c.claimIdle,
+			Start:    start,
+			Count:    c.batchSize,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Errorf("Failed to reclaim pending messages: %v", err)
+			}
+			return
+		}
+
+		for _, msg := range messages {
+			c.logger.Warnf("Reclaimed pending message %s from stream %s", msg.ID, c.stream)
+			c.handleMessage(ctx, msg)
+		}
+
+		if next == "0-0" || len(messages) == 0 {
+			return
+		}
+		start = next
+	}
+}
+
+// 处理消息
+func (c *RedisStreamConsumer) handleMessage(ctx context.Context, msg redis.XMessage) {
+	payload, _ := msg.Values[StreamFieldPayload].(string)
+
+	// 解析消息类型
+	var baseMsg struct {
+		MessageType string `json:"message_type"`
+	}
+
+	if err := json.Unmarshal([]byte(payload), &baseMsg); err != nil {
+		c.logger.Errorf("Failed to parse message type: %v", err)
+		c.ack(ctx, msg.ID)
+		return
+	}
+
+	// 根据消息类型处理
+	var err error
+	switch baseMsg.MessageType {
+	case MessageTypeSeckillOrder:
+		var orderMsg SeckillOrderMessage
+		if err = json.Unmarshal([]byte(payload), &orderMsg); err == nil {
+			err = c.handler.HandleSeckillOrder(ctx, &orderMsg)
+		}
+	case MessageTypeCouponGranted:
+		var couponMsg CouponGrantedMessage
+		if err = json.Unmarshal([]byte(payload), &couponMsg); err == nil {
+			err = c.handler.HandleCouponGranted(ctx, &couponMsg)
+		}
+	case MessageTypeStockUpdate:
+		var stockMsg StockUpdateMessage
+		if err = json.Unmarshal([]byte(payload), &stockMsg); err == nil {
+			err = c.handler.HandleStockUpdate(ctx, &stockMsg)
+		}
+	case MessageTypeUserNotify:
+		var notifyMsg UserNotifyMessage
+		if err = json.Unmarshal([]byte(payload), &notifyMsg); err == nil {
+			err = c.handler.HandleUserNotify(ctx, &notifyMsg)
+		}
+	default:
+		c.logger.Warnf("Unknown message type: %s", baseMsg.MessageType)
+		c.ack(ctx, msg.ID)
+		return
+	}
+
+	if err != nil {
+		// 不确认，留在待确认列表中等待重新认领
+		c.logger.Errorf("Failed to handle message %s: %v", msg.ID, err)
+		return
+	}
+
+	c.ack(ctx, msg.ID)
+	c.logger.Debugf("Successfully processed stream message: stream=%s, id=%s", c.stream, msg.ID)
+}
+
+// 确认消息
+func (c *RedisStreamConsumer) ack(ctx context.Context, id string) {
+	if err := c.client.XAck(ctx, c.stream, c.group, id).Err(); err != nil {
+		c.logger.Errorf("Failed to ack message %s: %v", id, err)
+	}
+}
+
+// 关闭消费者
+func (c *RedisStreamConsumer) Close() error {
+	if c.client != nil {
+		if err := c.client.Close(); err != nil {
+			c.logger.Errorf("Failed to close Redis stream client: %v", err)
+			return err
+		}
+	}
+	c.logger.Info("Redis stream consumer closed")
+	return nil
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// 订单计数脚本，只在 seckill-service 已创建活动报告时累加，避免产生没有过期时间的 key
+// KEYS[1]: 活动报告key (seckill:report:{productId})
+// ARGV[1]: 计数字段 (orders_created / orders_paid)
+const incrScript = `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 0
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+`
+
+const (
+	fieldOrdersCreated = "orders_created"
+	fieldOrdersPaid    = "orders_paid"
+)
+
+// 秒杀活动报告的订单计数，用于统计购买到下单、支付的转化
+type Counter struct {
+	client redis.Scripter
+	script *redis.Script
+	logger *logrus.Logger
+}
+
+// 创建订单计数器，client 需连接 seckill-service 使用的 Redis 库
+func NewCounter(client redis.Scripter, logger *logrus.Logger) *Counter {
+	return &Counter{
+		client: client,
+		script: redis.NewScript(incrScript),
+		logger: logger,
+	}
+}
+
+// 记录创建的秒杀订单
+func (c *Counter) OrderCreated(ctx context.Context, productID int64) {
+	c.incr(ctx, productID, fieldOrdersCreated)
+}
+
+// 记录已支付的秒杀订单
+func (c *Counter) OrderPaid(ctx context.Context, productID int64) {
+	c.incr(ctx, productID, fieldOrdersPaid)
+}
+
+// 计数失败只影响报告，不影响订单流程
+func (c *Counter) incr(ctx context.Context, productID int64, field string) {
+	key := fmt.Sprintf("seckill:report:{%d}", productID)
+	if err := c.script.Run(ctx, c.client, []string{key}, field).Err(); err != nil {
+		c.logger.WithError(err).WithFields(logrus.Fields{
+			"product_id": productID,
+			"field":      field,
+		}).Warn("Failed to update seckill report counter")
+	}
+}
@@ -0,0 +1,52 @@
+package reservation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// 库存预留确认脚本，与 seckill-service 的 ConfirmReservationScript 保持一致
+// KEYS[1]: 库存预留记录key (seckill:reservations:{productId})
+// ARGV[1]: 预留成员 (userId:quantity)
+const confirmScript = `
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`
+
+// 秒杀库存预留确认器
+type Confirmer struct {
+	client redis.Scripter
+	script *redis.Script
+	logger *logrus.Logger
+}
+
+// 创建预留确认器，client 需连接 seckill-service 使用的 Redis 库
+func NewConfirmer(client redis.Scripter, logger *logrus.Logger) *Confirmer {
+	return &Confirmer{
+		client: client,
+		script: redis.NewScript(confirmScript),
+		logger: logger,
+	}
+}
+
+// 确认预留，返回 false 表示预留已过期且库存已归还
+func (c *Confirmer) Confirm(ctx context.Context, productID, userID, quantity int64) (bool, error) {
+	key := fmt.Sprintf("seckill:reservations:{%d}", productID)
+	member := fmt.Sprintf("%d:%d", userID, quantity)
+
+	removed, err := c.script.Run(ctx, c.client, []string{key}, member).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm reservation: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"product_id": productID,
+		"user_id":    userID,
+		"quantity":   quantity,
+		"confirmed":  removed == 1,
+	}).Info("Seckill reservation confirmed")
+
+	return removed == 1, nil
+}
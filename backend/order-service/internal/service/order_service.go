@@ -9,12 +9,16 @@ import (
 
 	"order-service/internal/config"
 	"order-service/internal/database"
+	"order-service/internal/idgen"
 	"order-service/internal/model"
 	"order-service/internal/mq"
+	"order-service/internal/report"
+	"order-service/internal/reservation"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // 订单服务
@@ -22,12 +26,21 @@ type OrderService struct {
 	config      *config.Config
 	db          *database.Database
 	redisClient *redis.Client
+	idGenerator *idgen.Generator
+	confirmer   *reservation.Confirmer
+	report      *report.Counter // 秒杀活动报告订单计数，未启用时为空
 	logger      *logrus.Logger
 
 	// 统计信息
 	stats ServiceStats
 }
 
+// 订单ID前缀（按订单类型区分）
+var orderIDPrefixes = map[string]string{
+	model.OrderTypeSeckill: "SK",
+	model.OrderTypeNormal:  "NO",
+}
+
 // 服务统计信息
 type ServiceStats struct {
 	TotalOrders       int64
@@ -35,14 +48,18 @@ type ServiceStats struct {
 	FailedOrders      int64
 	DuplicateOrders   int64
 	CompensationTasks int64
+	CouponsGranted    int64 // 记录的发券数（不含重复消息），发券活动不创建订单
 }
 
 // 创建订单服务
-func NewOrderService(cfg *config.Config, db *database.Database, redisClient *redis.Client, logger *logrus.Logger) *OrderService {
+func NewOrderService(cfg *config.Config, db *database.Database, redisClient *redis.Client, idGenerator *idgen.Generator, confirmer *reservation.Confirmer, reportCounter *report.Counter, logger *logrus.Logger) *OrderService {
 	return &OrderService{
 		config:      cfg,
 		db:          db,
 		redisClient: redisClient,
+		idGenerator: idGenerator,
+		confirmer:   confirmer,
+		report:      reportCounter,
 		logger:      logger,
 	}
 }
@@ -95,6 +112,45 @@ func (s *OrderService) HandleSeckillOrder(ctx context.Context, message *mq.Secki
 	}
 
 	s.stats.SuccessOrders++
+	if s.report != nil {
+		s.report.OrderCreated(ctx, message.ProductID)
+	}
+	return nil
+}
+
+// 处理发券消息：发券活动的券码已在秒杀时绑定到用户，不创建待支付订单，只按发放流水号幂等记录发券。
+// 券码可直接兑换，不写入日志
+func (s *OrderService) HandleCouponGranted(ctx context.Context, message *mq.CouponGrantedMessage) error {
+	if message.GrantID == "" {
+		return fmt.Errorf("%w: grant_id is required", ErrInvalidOrder)
+	}
+
+	grant := &model.CouponGrant{
+		GrantID:    message.GrantID,
+		ProductID:  message.ProductID,
+		UserID:     message.UserID,
+		CouponCode: message.CouponCode,
+		GrantTime:  message.GrantTime,
+		TraceID:    message.TraceID,
+	}
+	result := s.db.GetDB().WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "grant_id"}}, DoNothing: true}).
+		Create(grant)
+	if result.Error != nil {
+		return fmt.Errorf("failed to record coupon grant: %w", result.Error)
+	}
+
+	logger := s.logger.WithFields(logrus.Fields{
+		"grant_id": message.GrantID,
+		"trace_id": message.TraceID,
+	})
+	if result.RowsAffected == 0 {
+		logger.Debug("Duplicate coupon grant message ignored")
+		return nil
+	}
+
+	s.stats.CouponsGranted++
+	logger.Info("Coupon grant recorded")
 	return nil
 }
 
@@ -131,10 +187,25 @@ func (s *OrderService) HandleUserNotify(ctx context.Context, message *mq.UserNot
 var (
 	ErrDuplicateOrder = errors.New("duplicate order")
 	ErrInvalidOrder   = errors.New("invalid order")
+
+	ErrReservationExpired = errors.New("seckill reservation expired")
 )
 
 // 创建订单
 func (s *OrderService) CreateOrder(ctx context.Context, request *model.CreateOrderRequest) (*model.OrderResponse, error) {
+	// 普通订单未指定订单ID时由本服务生成
+	if request.OrderID == "" && s.idGenerator != nil {
+		prefix, ok := orderIDPrefixes[request.OrderType]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown order_type %q", ErrInvalidOrder, request.OrderType)
+		}
+		orderID, err := s.idGenerator.NextString(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate order id: %w", err)
+		}
+		request.OrderID = orderID
+	}
+
 	// 参数验证
 	if err := s.validateOrderRequest(request); err != nil {
 		return nil, fmt.Errorf("invalid order request: %w", err)
@@ -414,6 +485,21 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID, newStatus
 	oldStatus := order.Status
 	now := time.Now()
 
+	// 秒杀订单支付时确认库存预留，预留已过期（库存已归还）则拒绝支付
+	if newStatus == model.OrderStatusPaid && oldStatus != model.OrderStatusPaid &&
+		order.OrderType == model.OrderTypeSeckill && s.confirmer != nil {
+		confirmed, err := s.confirmer.Confirm(ctx, order.ProductID, order.UserID, order.Quantity)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			if err := s.CancelOrder(orderID, "库存预留已过期"); err != nil {
+				s.logger.WithError(err).WithField("order_id", orderID).Error("取消预留过期订单失败")
+			}
+			return ErrReservationExpired
+		}
+	}
+
 	updates := map[string]interface{}{
 		"status":     newStatus,
 		"updated_at": now,
@@ -436,6 +522,11 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID, newStatus
 	key := fmt.Sprintf("order:%s", orderID)
 	s.redisClient.Del(ctx, key)
 
+	if newStatus == model.OrderStatusPaid && oldStatus != model.OrderStatusPaid &&
+		order.OrderType == model.OrderTypeSeckill && s.report != nil {
+		s.report.OrderPaid(ctx, order.ProductID)
+	}
+
 	s.logger.WithFields(logrus.Fields{
 		"order_id":   orderID,
 		"old_status": oldStatus,
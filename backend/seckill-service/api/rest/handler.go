@@ -1,13 +1,25 @@
 package rest
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"seckill-service/internal/activity"
+	"seckill-service/internal/antibot"
+	"seckill-service/internal/dashboard"
+	"seckill-service/internal/lottery"
+	"seckill-service/internal/model"
+	"seckill-service/internal/report"
+	"seckill-service/internal/risk"
 	"seckill-service/internal/seckill"
 	"seckill-service/internal/service"
+	"seckill-service/internal/waitingroom"
 
 	"github.com/gin-gonic/gin"
 )
@@ -43,74 +55,1121 @@ func (h *Handler) SeckillPurchase(c *gin.Context) {
 		return
 	}
 
+	// 校验等候室准入令牌
+	if !h.checkAdmission(c, &req) {
+		return
+	}
+
+	// 核销一次性购买路径
+	if !h.checkPurchasePath(c, &req) {
+		return
+	}
+
 	// 处理秒杀请求
-	result, err := h.seckillService.ProcessSeckill(c.Request.Context(), &req)
+	result, err := h.seckillService.ProcessSeckill(c.Request.Context(), &req, h.riskClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 根据结果返回不同的 HTTP 状态码
+	var statusCode int
+	switch result.Code {
+	case seckill.ResultSuccess:
+		statusCode = http.StatusOK
+	case seckill.ResultSystemBusy:
+		statusCode = http.StatusTooManyRequests
+	case seckill.ResultInsufficientStock:
+		statusCode = http.StatusConflict
+	case seckill.ResultUserAlreadyBought:
+		statusCode = http.StatusConflict
+	case seckill.ResultNoPurchaseRight, seckill.ResultRiskRejected:
+		statusCode = http.StatusForbidden
+	case seckill.ResultRiskChallenge:
+		h.riskChallenge(c, &req, result.Message)
+		return
+	default:
+		statusCode = http.StatusBadRequest
+	}
+
+	c.JSON(statusCode, result)
+}
+
+// 异步秒杀请求
+func (h *Handler) SeckillPurchaseAsync(c *gin.Context) {
+	var req seckill.SeckillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 参数验证
+	if req.ProductID <= 0 || req.UserID <= 0 || req.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid parameters",
+		})
+		return
+	}
+
+	// 校验等候室准入令牌
+	if !h.checkAdmission(c, &req) {
+		return
+	}
+
+	// 核销一次性购买路径
+	if !h.checkPurchasePath(c, &req) {
+		return
+	}
+
+	// 异步处理秒杀请求
+	tier := c.GetHeader(h.seckillService.UserTierHeader())
+	ticket, err := h.seckillService.ProcessSeckillAsync(c.Request.Context(), &req, tier, h.riskClient(c))
+	if err != nil {
+		if errors.Is(err, seckill.ErrSoldOut) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Product sold out",
+			})
+			return
+		}
+		if errors.Is(err, risk.ErrRejected) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Request rejected by risk control",
+			})
+			return
+		}
+		if errors.Is(err, risk.ErrChallengeRequired) {
+			h.riskChallenge(c, &req, "Risk challenge required")
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Service unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Request accepted and will be processed asynchronously",
+		"ticket":     ticket.ID,
+		"status":     ticket.Status,
+		"position":   ticket.Position,
+		"product_id": req.ProductID,
+		"user_id":    req.UserID,
+	})
+}
+
+// 风控使用的请求来源信息
+func (h *Handler) riskClient(c *gin.Context) *risk.Client {
+	deviceHeader, challengeHeader, nonceHeader := h.seckillService.RiskHeaders()
+	return &risk.Client{
+		DeviceID:  c.GetHeader(deviceHeader),
+		IP:        c.ClientIP(),
+		Challenge: c.GetHeader(challengeHeader),
+		Nonce:     c.GetHeader(nonceHeader),
+	}
+}
+
+// 返回 428 与新的风控挑战，客户端完成后在请求头携带挑战与解重试
+func (h *Handler) riskChallenge(c *gin.Context, req *seckill.SeckillRequest, message string) {
+	challenge, err := h.seckillService.GetRiskChallenge(req.ProductID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to issue risk challenge",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	_, challengeHeader, nonceHeader := h.seckillService.RiskHeaders()
+	c.JSON(http.StatusPreconditionRequired, gin.H{
+		"error":            message,
+		"code":             seckill.ResultRiskChallenge,
+		"challenge":        challenge,
+		"challenge_header": challengeHeader,
+		"nonce_header":     nonceHeader,
+	})
+}
+
+// 校验准入令牌，未通过时直接返回 403
+func (h *Handler) checkAdmission(c *gin.Context, req *seckill.SeckillRequest) bool {
+	token := c.GetHeader(h.seckillService.AdmissionTokenHeader())
+	if err := h.seckillService.CheckAdmission(req.ProductID, req.UserID, token); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Admission required, please join the waiting room first",
+			"details": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// 核销购买路径，未通过时直接返回 403
+func (h *Handler) checkPurchasePath(c *gin.Context, req *seckill.SeckillRequest) bool {
+	err := h.seckillService.CheckPurchasePath(c.Request.Context(), req.ProductID, req.UserID, c.Param("path"))
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, antibot.ErrPathRequired) || errors.Is(err, antibot.ErrInvalidPath) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Valid purchase path required, please request a new one",
+			"details": err.Error(),
+		})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Failed to verify purchase path",
+		"details": err.Error(),
+	})
+	return false
+}
+
+// 请求中的用户（购买路径、等候室、购买详情）必须是网关认证的用户
+func (h *Handler) checkPathUser(c *gin.Context, userID int64) bool {
+	if c.GetHeader(h.seckillService.UserIDHeader()) != strconv.FormatInt(userID, 10) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "User does not match the authenticated user",
+		})
+		return false
+	}
+	return true
+}
+
+// 获取工作量证明挑战
+func (h *Handler) GetPathChallenge(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	if !h.checkPathUser(c, userID) {
+		return
+	}
+
+	challenge, err := h.seckillService.GetPathChallenge(c.Request.Context(), productID, userID)
+	if err != nil {
+		h.purchasePathError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
+
+// 领取一次性购买路径
+func (h *Handler) IssuePurchasePath(c *gin.Context) {
+	var req antibot.PathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 参数验证
+	if req.ProductID <= 0 || req.UserID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid parameters",
+		})
+		return
+	}
+
+	if !h.checkPathUser(c, req.UserID) {
+		return
+	}
+
+	path, err := h.seckillService.IssuePurchasePath(c.Request.Context(), &req)
+	if err != nil {
+		h.purchasePathError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id":         path.ProductID,
+		"user_id":            path.UserID,
+		"path":               path.Path,
+		"expires_at":         path.ExpiresAt,
+		"purchase_url":       fmt.Sprintf("/api/v1/seckill/p/%s/purchase", path.Path),
+		"async_purchase_url": fmt.Sprintf("/api/v1/seckill/p/%s/purchase/async", path.Path),
+	})
+}
+
+// 获取当前工作量证明难度
+func (h *Handler) GetPathDifficulty(c *gin.Context) {
+	difficulty, err := h.seckillService.GetPathDifficulty(c.Request.Context())
+	if err != nil {
+		h.purchasePathError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"difficulty": difficulty,
+	})
+}
+
+// 调整工作量证明难度
+func (h *Handler) SetPathDifficulty(c *gin.Context) {
+	var req struct {
+		Difficulty int `json:"difficulty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.seckillService.SetPathDifficulty(c.Request.Context(), req.Difficulty); err != nil {
+		h.purchasePathError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Difficulty updated",
+		"difficulty": req.Difficulty,
+	})
+}
+
+func (h *Handler) purchasePathError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, antibot.ErrNotEnabled):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Purchase path is not enabled",
+		})
+	case errors.Is(err, seckill.ErrActivityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Activity not found",
+		})
+	case errors.Is(err, antibot.ErrRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many requests",
+		})
+	case errors.Is(err, antibot.ErrInvalidDifficulty):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid difficulty",
+			"details": err.Error(),
+		})
+	case errors.Is(err, antibot.ErrTooEarly), errors.Is(err, antibot.ErrActivityEnded),
+		errors.Is(err, antibot.ErrChallengeRequired), errors.Is(err, antibot.ErrInvalidChallenge),
+		errors.Is(err, antibot.ErrChallengeExpired), errors.Is(err, antibot.ErrInsufficientWork):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Purchase path request rejected",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to issue purchase path",
+			"details": err.Error(),
+		})
+	}
+}
+
+// 加入黑名单
+func (h *Handler) AddToBlacklist(c *gin.Context) {
+	var req struct {
+		UserID     int64  `json:"user_id"`
+		Reason     string `json:"reason"`
+		TTLSeconds int64  `json:"ttl_seconds"` // 为 0 表示永久封禁
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 参数验证
+	if req.UserID <= 0 || req.TTLSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid parameters",
+		})
+		return
+	}
+
+	entry, err := h.seckillService.AddToBlacklist(c.Request.Context(), req.UserID, req.Reason, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		h.riskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// 移出黑名单
+func (h *Handler) RemoveFromBlacklist(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	removed, err := h.seckillService.RemoveFromBlacklist(c.Request.Context(), userID)
+	if err != nil {
+		h.riskError(c, err)
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User is not blacklisted",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User removed from blacklist",
+		"user_id": userID,
+	})
+}
+
+// 列出黑名单
+func (h *Handler) ListBlacklist(c *gin.Context) {
+	entries, err := h.seckillService.ListBlacklist(c.Request.Context())
+	if err != nil {
+		h.riskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// 查询用户的风险评分依据，可通过 device_id、ip 查询参数一并评估设备与 IP
+func (h *Handler) GetUserRisk(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	report, err := h.seckillService.ExplainRisk(c.Request.Context(), userID, &risk.Client{
+		DeviceID: c.Query("device_id"),
+		IP:       c.Query("ip"),
+	})
+	if err != nil {
+		h.riskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) riskError(c *gin.Context, err error) {
+	if errors.Is(err, risk.ErrNotEnabled) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Risk control is not enabled",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Risk control operation failed",
+		"details": err.Error(),
+	})
+}
+
+// 加入等候室
+func (h *Handler) JoinWaitingRoom(c *gin.Context) {
+	var req waitingroom.JoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 参数验证
+	if req.ProductID <= 0 || req.UserID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid parameters",
+		})
+		return
+	}
+
+	if !h.checkPathUser(c, req.UserID) {
+		return
+	}
+
+	tier := c.GetHeader(h.seckillService.UserTierHeader())
+	status, err := h.seckillService.JoinWaitingRoom(c.Request.Context(), req.ProductID, req.UserID, tier)
+	if err != nil {
+		h.waitingRoomError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// 查询等候室排队状态
+func (h *Handler) GetWaitingRoomStatus(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	// 排队状态含已放行用户的准入令牌，只允许本人查询
+	if !h.checkPathUser(c, userID) {
+		return
+	}
+
+	status, err := h.seckillService.GetWaitingRoomStatus(c.Request.Context(), productID, userID)
+	if err != nil {
+		h.waitingRoomError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) waitingRoomError(c *gin.Context, err error) {
+	if errors.Is(err, waitingroom.ErrRoomNotEnabled) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Waiting room is not enabled for this activity",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Failed to access waiting room",
+		"details": err.Error(),
+	})
+}
+
+// 登记参与抽签
+func (h *Handler) RegisterLottery(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.UserID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	entry, err := h.seckillService.RegisterLottery(c.Request.Context(), productID, req.UserID)
+	if err != nil {
+		h.lotteryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// 手动开奖
+func (h *Handler) DrawLottery(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	record, err := h.seckillService.DrawLottery(c.Request.Context(), productID)
+	if err != nil {
+		h.lotteryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// 获取开奖记录
+func (h *Handler) GetLotteryDraw(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	record, err := h.seckillService.GetLotteryDraw(c.Request.Context(), productID)
+	if err != nil {
+		h.lotteryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// 获取开奖记录与开奖名单（纠纷核查）
+func (h *Handler) GetLotteryDrawDetail(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	detail, err := h.seckillService.GetLotteryDrawDetail(c.Request.Context(), productID)
+	if err != nil {
+		h.lotteryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// 查询用户的抽签结果
+func (h *Handler) GetLotteryEntry(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	entry, err := h.seckillService.GetLotteryEntry(c.Request.Context(), productID, userID)
+	if err != nil {
+		h.lotteryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) lotteryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, seckill.ErrActivityNotFound), errors.Is(err, lottery.ErrNotLottery), errors.Is(err, lottery.ErrNotDrawn):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, lottery.ErrEntryClosed), errors.Is(err, lottery.ErrEntryOpen), errors.Is(err, lottery.ErrAlreadyDrawn),
+		errors.Is(err, lottery.ErrDrawing):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to process lottery request",
+			"details": err.Error(),
+		})
+	}
+}
+
+// 查询异步秒杀结果
+func (h *Handler) GetSeckillResult(c *gin.Context) {
+	ticketID := c.Param("ticket")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ticket",
+		})
+		return
+	}
+
+	ticket, err := h.seckillService.GetSeckillResult(c.Request.Context(), ticketID)
+	if err != nil {
+		if errors.Is(err, seckill.ErrTicketNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Ticket not found or expired",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get seckill result",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// 预热活动
+func (h *Handler) PrewarmActivity(c *gin.Context) {
+	var activity seckill.SeckillActivity
+	if err := c.ShouldBindJSON(&activity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 启用活动管理时只按商品ID从活动表读取当前或下一场活动
+	if h.seckillService.ActivityManagementEnabled() {
+		if activity.ProductID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid parameters",
+			})
+			return
+		}
+		stored, err := h.seckillService.PrewarmProduct(c.Request.Context(), activity.ProductID)
+		if err != nil {
+			h.activityError(c, err)
+			return
+		}
+		h.activityPrewarmed(c, stored)
+		return
+	}
+
+	// 参数验证
+	if activity.ProductID <= 0 || activity.Stock <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid parameters",
+		})
+		return
+	}
+
+	reservation, err := h.seckillService.PrewarmActivity(c.Request.Context(), &activity)
+	if err != nil {
+		if isInventoryError(err) || errors.Is(err, seckill.ErrInsufficientCoupons) {
+			h.activityError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to prewarm activity",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"message":    "Activity prewarmed successfully",
+		"product_id": activity.ProductID,
+		"stock":      activity.Stock,
+	}
+	if reservation != nil {
+		response["reservation_id"] = reservation.ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 创建活动
+func (h *Handler) CreateActivity(c *gin.Context) {
+	var req model.ActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	created, err := h.seckillService.CreateActivity(c.Request.Context(), &req, h.operator(c))
+	if err != nil {
+		h.activityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// 查询活动列表，支持 product_id、state（upcoming / ongoing / ended）、page、page_size 参数
+func (h *Handler) ListActivities(c *gin.Context) {
+	query := model.ActivityQuery{
+		State: c.Query("state"),
+	}
+	query.ProductID, _ = strconv.ParseInt(c.Query("product_id"), 10, 64)
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+
+	activities, total, err := h.seckillService.ListActivities(c.Request.Context(), &query)
+	if err != nil {
+		h.activityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activities": activities,
+		"total":      total,
+	})
+}
+
+// 获取活动
+func (h *Handler) GetActivity(c *gin.Context) {
+	id, ok := h.activityID(c)
+	if !ok {
+		return
+	}
+
+	stored, err := h.seckillService.GetActivity(c.Request.Context(), id)
+	if err != nil {
+		h.activityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stored)
+}
+
+// 修改活动
+func (h *Handler) UpdateActivity(c *gin.Context) {
+	id, ok := h.activityID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.seckillService.UpdateActivity(c.Request.Context(), id, &req, h.operator(c))
+	if err != nil {
+		h.activityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// 删除活动，可通过 reason 参数记录删除原因
+func (h *Handler) DeleteActivity(c *gin.Context) {
+	id, ok := h.activityID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.seckillService.DeleteActivity(c.Request.Context(), id, h.operator(c), c.Query("reason")); err != nil {
+		h.activityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Activity deleted successfully",
+		"id":      id,
+	})
+}
+
+// 获取活动版本历史
+func (h *Handler) GetActivityVersions(c *gin.Context) {
+	id, ok := h.activityID(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.seckillService.GetActivityVersions(c.Request.Context(), id)
+	if err != nil {
+		h.activityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       id,
+		"versions": versions,
+	})
+}
+
+// 按活动表中的定义预热活动
+func (h *Handler) PrewarmStoredActivity(c *gin.Context) {
+	id, ok := h.activityID(c)
+	if !ok {
+		return
+	}
+
+	stored, err := h.seckillService.PrewarmStoredActivity(c.Request.Context(), id)
+	if err != nil {
+		h.activityError(c, err)
+		return
+	}
+
+	h.activityPrewarmed(c, stored)
+}
+
+func (h *Handler) activityPrewarmed(c *gin.Context, stored *model.Activity) {
+	response := gin.H{
+		"message":     "Activity prewarmed successfully",
+		"activity_id": stored.ID,
+		"version":     stored.Version,
+		"product_id":  stored.ProductID,
+		"stock":       stored.Stock,
+	}
+	if stored.ReservationID != "" {
+		response["reservation_id"] = stored.ReservationID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) activityID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid activity ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// 变更活动的操作者，取网关转发的用户ID
+func (h *Handler) operator(c *gin.Context) string {
+	if userID := c.GetHeader(h.seckillService.UserIDHeader()); userID != "" {
+		return userID
+	}
+	return "system"
+}
+
+// 预热时库存预留失败
+func isInventoryError(err error) bool {
+	return errors.Is(err, activity.ErrExceedsInventory) || errors.Is(err, activity.ErrInventoryUnavailable)
+}
+
+func (h *Handler) activityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, activity.ErrNotEnabled):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Activity management is not enabled",
+		})
+	case errors.Is(err, activity.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Activity not found",
+		})
+	case errors.Is(err, activity.ErrInvalidActivity):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid activity",
+			"details": err.Error(),
+		})
+	case errors.Is(err, activity.ErrTimeOverlap), errors.Is(err, activity.ErrVersionConflict),
+		errors.Is(err, activity.ErrAlreadyStarted), errors.Is(err, activity.ErrAlreadyEnded),
+		errors.Is(err, activity.ErrExceedsInventory), errors.Is(err, seckill.ErrInsufficientCoupons):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Activity conflict",
+			"details": err.Error(),
+		})
+	case errors.Is(err, activity.ErrInventoryUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Inventory service unavailable",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Activity operation failed",
+			"details": err.Error(),
+		})
+	}
+}
+
+// 获取秒杀统计信息
+func (h *Handler) GetSeckillStats(c *gin.Context) {
+	productIDStr := c.Param("productId")
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	stats, err := h.seckillService.GetSeckillStats(c.Request.Context(), productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get seckill stats",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// 实时看板（SSE），按推送间隔持续推送活动的集群汇总统计
+func (h *Handler) StreamDashboard(c *gin.Context) {
+	productIDStr := c.Param("productId")
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	frame, err := h.seckillService.GetDashboardFrame(ctx, productID)
 	if err != nil {
+		if errors.Is(err, dashboard.ErrNotEnabled) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Dashboard is not enabled",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Internal server error",
+			"error":   "Failed to get dashboard stats",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// 根据结果返回不同的 HTTP 状态码
-	var statusCode int
-	switch result.Code {
-	case seckill.ResultSuccess:
-		statusCode = http.StatusOK
-	case seckill.ResultSystemBusy:
-		statusCode = http.StatusTooManyRequests
-	case seckill.ResultInsufficientStock:
-		statusCode = http.StatusConflict
-	case seckill.ResultUserAlreadyBought:
-		statusCode = http.StatusConflict
-	default:
-		statusCode = http.StatusBadRequest
-	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭反向代理缓冲
+	c.SSEvent("stats", frame)
+	c.Writer.Flush()
 
-	c.JSON(statusCode, result)
+	ticker := time.NewTicker(h.seckillService.DashboardInterval())
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		// 服务下线时结束推送，避免长连接阻塞 HTTP 服务关闭
+		if h.seckillService.Draining() {
+			return false
+		}
+
+		// 单次获取失败时推送错误事件，连接保持
+		frame, err := h.seckillService.GetDashboardFrame(ctx, productID)
+		if err != nil {
+			c.SSEvent("error", gin.H{
+				"error": err.Error(),
+			})
+			return true
+		}
+		c.SSEvent("stats", frame)
+		return true
+	})
 }
 
-// 异步秒杀请求
-func (h *Handler) SeckillPurchaseAsync(c *gin.Context) {
-	var req seckill.SeckillRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+// 检查用户购买状态
+func (h *Handler) IsUserPurchased(c *gin.Context) {
+	productIDStr := c.Param("productId")
+	userIDStr := c.Param("userId")
+
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request format",
-			"details": err.Error(),
+			"error": "Invalid product ID",
 		})
 		return
 	}
 
-	// 参数验证
-	if req.ProductID <= 0 || req.UserID <= 0 || req.Quantity <= 0 {
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid parameters",
+			"error": "Invalid user ID",
 		})
 		return
 	}
 
-	// 异步处理秒杀请求
-	err := h.seckillService.ProcessSeckillAsync(c.Request.Context(), &req)
+	purchased, err := h.seckillService.IsUserPurchased(c.Request.Context(), productID, userID)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "Service unavailable",
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to check user purchase status",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message":    "Request accepted and will be processed asynchronously",
-		"product_id": req.ProductID,
-		"user_id":    req.UserID,
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": productID,
+		"user_id":    userID,
+		"purchased":  purchased,
 	})
 }
 
-// 预热活动
-func (h *Handler) PrewarmActivity(c *gin.Context) {
-	var activity seckill.SeckillActivity
-	if err := c.ShouldBindJSON(&activity); err != nil {
+// 获取用户购买信息
+func (h *Handler) GetUserPurchaseInfo(c *gin.Context) {
+	productIDStr := c.Param("productId")
+	userIDStr := c.Param("userId")
+
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	// 购买详情含发券活动的券码，券码可直接核销，只允许本人或管理员查询
+	if !h.isAdmin(c) && !h.checkPathUser(c, userID) {
+		return
+	}
+
+	info, err := h.seckillService.GetUserPurchaseInfo(c.Request.Context(), productID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get user purchase info",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// 批量查询用户购买状态
+func (h *Handler) BatchCheckUserStatus(c *gin.Context) {
+	var req struct {
+		ProductID int64   `json:"product_id"`
+		UserIDs   []int64 `json:"user_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request format",
 			"details": err.Error(),
@@ -118,32 +1177,49 @@ func (h *Handler) PrewarmActivity(c *gin.Context) {
 		return
 	}
 
-	// 参数验证
-	if activity.ProductID <= 0 || activity.Stock <= 0 {
+	if req.ProductID <= 0 || len(req.UserIDs) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid parameters",
 		})
 		return
 	}
+	if limit := h.seckillService.BatchCheckMaxUsers(); len(req.UserIDs) > limit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Too many users",
+			"details": fmt.Sprintf("at most %d users per request", limit),
+		})
+		return
+	}
+	for _, userID := range req.UserIDs {
+		if userID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid user ID",
+			})
+			return
+		}
+	}
 
-	err := h.seckillService.PrewarmActivity(c.Request.Context(), &activity)
+	users, err := h.seckillService.BatchCheckUserStatus(c.Request.Context(), req.ProductID, req.UserIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to prewarm activity",
+			"error":   "Failed to check user purchase status",
 			"details": err.Error(),
 		})
 		return
 	}
 
+	if !h.isAdmin(c) {
+		hideCouponCodes(users)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Activity prewarmed successfully",
-		"product_id": activity.ProductID,
-		"stock":      activity.Stock,
+		"product_id": req.ProductID,
+		"users":      users,
 	})
 }
 
-// 获取秒杀统计信息
-func (h *Handler) GetSeckillStats(c *gin.Context) {
+// 导出活动全部购买者（CSV，边扫描边输出）
+func (h *Handler) ExportBuyers(c *gin.Context) {
 	productIDStr := c.Param("productId")
 	productID, err := strconv.ParseInt(productIDStr, 10, 64)
 	if err != nil {
@@ -153,43 +1229,130 @@ func (h *Handler) GetSeckillStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.seckillService.GetSeckillStats(c.Request.Context(), productID)
+	// 第一批数据就绪后再写响应头，开始输出前出错仍可返回错误响应
+	writer := csv.NewWriter(c.Writer)
+	started := false
+	start := func() {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=buyers-%d.csv", productID))
+		c.Status(http.StatusOK)
+		writer.Write([]string{"user_id", "quantity", "order_id", "purchase_time", "status", "reserved_until", "coupon_code"})
+		started = true
+	}
+
+	showCoupons := h.isAdmin(c)
+	err = h.seckillService.ExportBuyers(c.Request.Context(), productID, func(buyers []*seckill.UserPurchaseInfo) error {
+		if !started {
+			start()
+		}
+		if !showCoupons {
+			hideCouponCodes(buyers)
+		}
+		for _, buyer := range buyers {
+			writer.Write([]string{
+				strconv.FormatInt(buyer.UserID, 10),
+				strconv.FormatInt(buyer.Quantity, 10),
+				buyer.OrderID,
+				formatCSVTime(buyer.PurchaseTime),
+				buyer.Status,
+				formatCSVTime(buyer.ReservedUntil),
+				buyer.CouponCode,
+			})
+		}
+		writer.Flush()
+		c.Writer.Flush()
+		return writer.Error()
+	})
 	if err != nil {
+		if started {
+			// 已输出部分数据，中断连接让客户端感知导出不完整
+			panic(http.ErrAbortHandler)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get seckill stats",
+			"error":   "Failed to export buyers",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	if !started {
+		start()
+	}
+	writer.Flush()
 }
 
-// 检查用户购买状态
-func (h *Handler) IsUserPurchased(c *gin.Context) {
-	productIDStr := c.Param("productId")
-	userIDStr := c.Param("userId")
+// 隐藏券码，券码可直接核销，只返回给本人或管理员
+func hideCouponCodes(users []*seckill.UserPurchaseInfo) {
+	for _, user := range users {
+		user.CouponCode = ""
+	}
+}
+
+func formatCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
 
+// 向发券活动的券码池导入券码
+func (h *Handler) AddCouponCodes(c *gin.Context) {
+	productIDStr := c.Param("productId")
 	productID, err := strconv.ParseInt(productIDStr, 10, 64)
-	if err != nil {
+	if err != nil || productID <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid product ID",
 		})
 		return
 	}
 
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
+	var req struct {
+		Codes []string `json:"codes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID",
+			"error":   "Invalid request format",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	purchased, err := h.seckillService.IsUserPurchased(c.Request.Context(), productID, userID)
+	if len(req.Codes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid parameters",
+		})
+		return
+	}
+	if limit := h.seckillService.CouponMaxCodesPerRequest(); len(req.Codes) > limit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Too many codes",
+			"details": fmt.Sprintf("at most %d codes per request", limit),
+		})
+		return
+	}
+
+	// 同一请求中的重复券码只导入一次
+	seen := make(map[string]struct{}, len(req.Codes))
+	codes := make([]string, 0, len(req.Codes))
+	for _, code := range req.Codes {
+		if err := seckill.ValidateCouponCode(code); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid coupon code",
+				"details": err.Error(),
+			})
+			return
+		}
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, code)
+	}
+
+	poolSize, err := h.seckillService.AddCouponCodes(c.Request.Context(), productID, codes)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to check user purchase status",
+			"error":   "Failed to add coupon codes",
 			"details": err.Error(),
 		})
 		return
@@ -197,16 +1360,41 @@ func (h *Handler) IsUserPurchased(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"product_id": productID,
-		"user_id":    userID,
-		"purchased":  purchased,
+		"added":      len(codes),
+		"duplicates": len(req.Codes) - len(codes),
+		"pool_size":  poolSize,
 	})
 }
 
-// 获取用户购买信息
-func (h *Handler) GetUserPurchaseInfo(c *gin.Context) {
+// 查询券码池中尚未发出的券码数量
+func (h *Handler) GetCouponPool(c *gin.Context) {
 	productIDStr := c.Param("productId")
-	userIDStr := c.Param("userId")
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	poolSize, err := h.seckillService.GetCouponPoolSize(c.Request.Context(), productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get coupon pool",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": productID,
+		"pool_size":  poolSize,
+	})
+}
 
+// 获取活动报告
+func (h *Handler) GetActivityReport(c *gin.Context) {
+	productIDStr := c.Param("productId")
 	productID, err := strconv.ParseInt(productIDStr, 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -215,24 +1403,57 @@ func (h *Handler) GetUserPurchaseInfo(c *gin.Context) {
 		return
 	}
 
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	activityReport, err := h.seckillService.GetActivityReport(c.Request.Context(), productID)
+	if err != nil {
+		h.reportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, activityReport)
+}
+
+// 导出活动报告（CSV）
+func (h *Handler) ExportActivityReport(c *gin.Context) {
+	productIDStr := c.Param("productId")
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID",
+			"error": "Invalid product ID",
 		})
 		return
 	}
 
-	info, err := h.seckillService.GetUserPurchaseInfo(c.Request.Context(), productID, userID)
+	activityReport, err := h.seckillService.GetActivityReport(c.Request.Context(), productID)
 	if err != nil {
+		h.reportError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=report-%d.csv", productID))
+	c.Status(http.StatusOK)
+	if err := activityReport.WriteCSV(c.Writer); err != nil {
+		// 报告已开始输出，中断连接让客户端感知导出不完整
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h *Handler) reportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, report.ErrNotEnabled):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Activity report is not enabled",
+		})
+	case errors.Is(err, report.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Report not found",
+		})
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get user purchase info",
+			"error":   "Failed to get activity report",
 			"details": err.Error(),
 		})
-		return
 	}
-
-	c.JSON(http.StatusOK, info)
 }
 
 // 清理活动数据
@@ -267,8 +1488,50 @@ func (h *Handler) GetServiceStats(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"service_stats":         stats,
 		"queue_stats":           h.seckillService.GetQueueStats(),
+		"queue_priority_stats":  h.seckillService.GetPriorityQueueStats(),
+		"outbox_stats":          h.seckillService.GetOutboxStats(c.Request.Context()),
+		"load":                  h.seckillService.GetLoadSnapshot(),
 		"circuit_breaker_state": h.seckillService.GetCircuitBreakerState().String(),
 		"limiter_tokens":        h.seckillService.GetLimiterTokens(),
+		"hot_keys":              h.seckillService.GetHotKeys(),
+		"sold_out_products":     h.seckillService.GetSoldOutProducts(),
+	})
+}
+
+// 获取本节点所有熔断器的状态
+func (h *Handler) GetCircuitBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"circuit_breakers": h.seckillService.GetCircuitBreakers(),
+	})
+}
+
+// 获取本节点指定熔断器的状态
+func (h *Handler) GetCircuitBreaker(c *gin.Context) {
+	snapshot, ok := h.seckillService.GetCircuitBreaker(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Circuit breaker not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// 重置本节点的熔断器为关闭状态，其他节点不受影响
+func (h *Handler) ResetCircuitBreaker(c *gin.Context) {
+	name := c.Param("name")
+	if !h.seckillService.ResetCircuitBreaker(name) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Circuit breaker not found",
+		})
+		return
+	}
+
+	snapshot, _ := h.seckillService.GetCircuitBreaker(name)
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Circuit breaker reset",
+		"circuit_breaker": snapshot,
 	})
 }
 
@@ -310,7 +1573,7 @@ func (h *Handler) CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+h.seckillService.AdmissionTokenHeader())
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -321,6 +1584,32 @@ func (h *Handler) CORS() gin.HandlerFunc {
 	}
 }
 
+// 中间件：管理接口权限，要求网关转发的角色中包含管理员角色
+func (h *Handler) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.isAdmin(c) {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Admin role required",
+		})
+		c.Abort()
+	}
+}
+
+// 网关转发的角色中是否包含管理员角色
+func (h *Handler) isAdmin(c *gin.Context) bool {
+	header, role := h.seckillService.AdminRole()
+	for _, r := range strings.Split(c.GetHeader(header), ",") {
+		if strings.TrimSpace(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
 // 中间件：限流
 func (h *Handler) RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -335,6 +1624,10 @@ func (h *Handler) ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				// 中断已开始输出的响应（如流式导出），交给 net/http 关闭连接
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error":   "Internal server error",
 					"details": fmt.Sprintf("%v", err),
@@ -23,28 +23,99 @@ func SetupRouter(handler *Handler) *gin.Engine {
 		// 秒杀相关路由
 		seckill := v1.Group("/seckill")
 		{
+			// 加入等候室
+			seckill.POST("/waiting-room/join", handler.JoinWaitingRoom)
+
+			// 查询等候室排队状态
+			seckill.GET("/waiting-room/:productId/:userId", handler.GetWaitingRoomStatus)
+
+			// 抽签活动：登记、查询开奖记录与个人结果
+			seckill.POST("/lottery/:productId/register", handler.RegisterLottery)
+			seckill.GET("/lottery/:productId/draw", handler.GetLotteryDraw)
+			seckill.GET("/lottery/:productId/entry/:userId", handler.GetLotteryEntry)
+
+			// 防刷购买路径：领取工作量证明挑战、领取一次性购买路径、查询难度
+			seckill.GET("/purchase-path/challenge/:productId/:userId", handler.GetPathChallenge)
+			seckill.POST("/purchase-path", handler.IssuePurchasePath)
+			seckill.GET("/purchase-path/difficulty", handler.GetPathDifficulty)
+
 			// 同步秒杀
 			seckill.POST("/purchase", handler.SeckillPurchase)
 
 			// 异步秒杀
 			seckill.POST("/purchase/async", handler.SeckillPurchaseAsync)
 
-			// 预热活动
-			seckill.POST("/activity/prewarm", handler.PrewarmActivity)
+			// 携带购买路径的同步、异步秒杀（启用防刷购买路径时必须使用）
+			seckill.POST("/p/:path/purchase", handler.SeckillPurchase)
+			seckill.POST("/p/:path/purchase/async", handler.SeckillPurchaseAsync)
+
+			// 查询异步秒杀结果
+			seckill.GET("/result/:ticket", handler.GetSeckillResult)
 
 			// 获取秒杀统计信息
 			seckill.GET("/stats/:productId", handler.GetSeckillStats)
 
+			// 实时看板（SSE）
+			seckill.GET("/dashboard/:productId/stream", handler.StreamDashboard)
+
 			// 检查用户购买状态
 			seckill.GET("/purchased/:productId/:userId", handler.IsUserPurchased)
 
 			// 获取用户购买信息
 			seckill.GET("/purchase/:productId/:userId", handler.GetUserPurchaseInfo)
 
+			// 活动报告（JSON / CSV）
+			seckill.GET("/report/:productId", handler.GetActivityReport)
+			seckill.GET("/report/:productId/csv", handler.ExportActivityReport)
+
 			// 清理活动数据
 			seckill.DELETE("/activity/:productId", handler.CleanupActivity)
 		}
 
+		// 管理接口，要求网关转发的角色中包含管理员角色
+		admin := v1.Group("/admin")
+		admin.Use(handler.RequireAdmin())
+		{
+			// 抽签活动手动开奖（种子随机生成）
+			admin.POST("/lottery/:productId/draw", handler.DrawLottery)
+			// 开奖记录与开奖时的参与者、中签名单，供纠纷核查
+			admin.GET("/lottery/:productId/draw", handler.GetLotteryDrawDetail)
+
+			// 调整防刷购买路径的工作量证明难度
+			admin.PUT("/purchase-path/difficulty", handler.SetPathDifficulty)
+
+			// 风控：黑名单管理与评分依据查询
+			admin.GET("/risk/blacklist", handler.ListBlacklist)
+			admin.POST("/risk/blacklist", handler.AddToBlacklist)
+			admin.DELETE("/risk/blacklist/:userId", handler.RemoveFromBlacklist)
+			admin.GET("/risk/users/:userId", handler.GetUserRisk)
+
+			// 预热活动（启用活动管理时按商品ID从活动表读取）
+			admin.POST("/activity/prewarm", handler.PrewarmActivity)
+
+			// 活动管理：增删改查、版本历史与按活动表预热
+			admin.GET("/activities", handler.ListActivities)
+			admin.POST("/activities", handler.CreateActivity)
+			admin.GET("/activities/:id", handler.GetActivity)
+			admin.PUT("/activities/:id", handler.UpdateActivity)
+			admin.DELETE("/activities/:id", handler.DeleteActivity)
+			admin.GET("/activities/:id/versions", handler.GetActivityVersions)
+			admin.POST("/activities/:id/prewarm", handler.PrewarmStoredActivity)
+
+			// 发券活动的券码池：导入券码、查询剩余数量
+			admin.POST("/coupons/:productId/codes", handler.AddCouponCodes)
+			admin.GET("/coupons/:productId", handler.GetCouponPool)
+
+			// 客服查询：批量查询用户购买状态、导出活动全部购买者（CSV）
+			admin.POST("/purchased/batch", handler.BatchCheckUserStatus)
+			admin.GET("/buyers/:productId/export", handler.ExportBuyers)
+
+			// 熔断器状态查询与重置（仅作用于当前节点）
+			admin.GET("/circuit-breakers", handler.GetCircuitBreakers)
+			admin.GET("/circuit-breakers/:name", handler.GetCircuitBreaker)
+			admin.POST("/circuit-breakers/:name/reset", handler.ResetCircuitBreaker)
+		}
+
 		// 系统监控相关路由
 		system := v1.Group("/system")
 		{
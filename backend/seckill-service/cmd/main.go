@@ -97,11 +97,21 @@ func main() {
 	<-quit
 	logger.Info("Shutting down server...")
 
-	// 取消上下文
-	cancel()
-
-	// 创建一个超时上下文用于关闭服务器
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// 排空请求队列：拒绝新请求并让健康检查返回不健康，处理完已排队的请求，超时后拒绝剩余请求
+	drainTimeout := cfg.Server.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = 20 * time.Second
+	}
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+	seckillService.Drain(drainCtx)
+	drainCancel()
+
+	// 创建一个超时上下文用于关闭服务器，等待进行中的请求返回
+	shutdownTimeout := cfg.Server.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer shutdownCancel()
 
 	// 关闭 HTTP 服务器
@@ -109,5 +119,8 @@ func main() {
 		logger.Errorf("Server forced to shutdown: %v", err)
 	}
 
+	// 取消上下文，停止后台任务与队列工作协程
+	cancel()
+
 	logger.Info("Server exited")
 }
@@ -0,0 +1,143 @@
+package activity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 库存服务客户端，创建活动时查询商品可用库存，预热时预留活动库存
+type InventoryClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// 创建库存服务客户端
+func NewInventoryClient(host string, port int, timeout time.Duration) *InventoryClient {
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	return &InventoryClient{
+		baseURL:    fmt.Sprintf("http://%s:%d", host, port),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// 库存服务查询接口的响应
+type inventoryResponse struct {
+	Code int `json:"code"`
+	Data struct {
+		ProductID int64 `json:"product_id"`
+		Available int64 `json:"available"`
+	} `json:"data"`
+}
+
+// 查询商品可用库存
+func (c *InventoryClient) Available(ctx context.Context, productID int64) (int64, error) {
+	url := fmt.Sprintf("%s/api/v1/inventory/%d", c.baseURL, productID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("inventory service returned status %d", resp.StatusCode)
+	}
+
+	var body inventoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode inventory response: %w", err)
+	}
+	if body.Code != 0 {
+		return 0, fmt.Errorf("inventory service returned code %d", body.Code)
+	}
+	return body.Data.Available, nil
+}
+
+// 库存服务预留接口的响应
+type reservationResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		ReservationID string `json:"reservation_id"`
+		Quantity      int64  `json:"quantity"`
+		Returned      int64  `json:"returned"`
+		Available     int64  `json:"available"`
+	} `json:"data"`
+}
+
+// 预留库存，同一预留ID重复调用时调整为新的数量；可用库存不足时返回 ErrExceedsInventory
+func (c *InventoryClient) Reserve(ctx context.Context, reservationID string, productID, quantity int64, reason string) error {
+	body := map[string]interface{}{
+		"reservation_id": reservationID,
+		"product_id":     productID,
+		"quantity":       quantity,
+		"reason":         reason,
+		"operator":       "seckill-service",
+	}
+
+	var resp reservationResponse
+	if err := c.post(ctx, "/api/v1/inventory/reservations", body, &resp); err != nil {
+		return fmt.Errorf("%w: %v", ErrInventoryUnavailable, err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("%w: %s, available %d", ErrExceedsInventory, resp.Msg, resp.Data.Available)
+	}
+	return nil
+}
+
+// 释放预留，returned 为归还的未售出数量，返回库存服务记录的归还数量（重复释放时为首次释放的结果）；
+// 库存服务中没有该预留时返回 ErrReservationNotFound
+func (c *InventoryClient) Release(ctx context.Context, reservationID string, returned int64, reason string) (int64, error) {
+	body := map[string]interface{}{
+		"returned": returned,
+		"reason":   reason,
+	}
+
+	var resp reservationResponse
+	if err := c.post(ctx, "/api/v1/inventory/reservations/"+reservationID+"/release", body, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Code != 0 {
+		return 0, fmt.Errorf("inventory service returned code %d: %s", resp.Code, resp.Msg)
+	}
+	return resp.Data.Returned, nil
+}
+
+func (c *InventoryClient) post(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrReservationNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("inventory service returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode inventory response: %w", err)
+	}
+	return nil
+}
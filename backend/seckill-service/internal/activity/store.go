@@ -0,0 +1,392 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"seckill-service/internal/model"
+	"seckill-service/internal/seckill"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotEnabled           = errors.New("activity management is not enabled")
+	ErrNotFound             = errors.New("activity not found")
+	ErrInvalidActivity      = errors.New("invalid activity")
+	ErrTimeOverlap          = errors.New("activity time window overlaps another activity of the same product")
+	ErrVersionConflict      = errors.New("activity version conflict")
+	ErrAlreadyStarted       = errors.New("activity has already started")
+	ErrAlreadyEnded         = errors.New("activity has already ended")
+	ErrExceedsInventory     = errors.New("activity stock exceeds available inventory")
+	ErrInventoryUnavailable = errors.New("inventory service unavailable")
+	ErrReservationNotFound  = errors.New("inventory reservation not found")
+)
+
+// 列表每页条数上限
+const maxPageSize = 100
+
+// 修改活动时保留的列，由预热与库存预留结算维护
+var preservedColumns = []string{
+	"id", "created_at", "prewarmed_at",
+	"reservation_id", "reserved_stock", "returned_stock", "settled_at",
+}
+
+// 活动存储：活动定义与版本历史保存在数据库中，Redis 被清空后可从这里重新预热
+type Store struct {
+	db        *gorm.DB
+	inventory *InventoryClient // 为空时不校验可用库存
+	logger    *logrus.Logger
+}
+
+// 创建活动存储
+func NewStore(db *gorm.DB, inventory *InventoryClient, logger *logrus.Logger) *Store {
+	return &Store{
+		db:        db,
+		inventory: inventory,
+		logger:    logger,
+	}
+}
+
+// 校验活动定义
+func Validate(activity *seckill.SeckillActivity) error {
+	switch {
+	case activity.ProductID <= 0:
+		return fmt.Errorf("%w: product_id must be positive", ErrInvalidActivity)
+	case activity.ProductName == "":
+		return fmt.Errorf("%w: product_name is required", ErrInvalidActivity)
+	case activity.Price < 0:
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidActivity)
+	case activity.Stock <= 0:
+		return fmt.Errorf("%w: stock must be positive", ErrInvalidActivity)
+	case activity.StartTime.IsZero() || !activity.EndTime.After(activity.StartTime):
+		return fmt.Errorf("%w: end_time must be after start_time", ErrInvalidActivity)
+	}
+
+	switch activity.Type {
+	case "", seckill.ActivityTypeFCFS, seckill.ActivityTypeCoupon:
+	case seckill.ActivityTypeLottery:
+		lottery := activity.Lottery
+		if lottery == nil || !lottery.EntryEndTime.After(lottery.EntryStartTime) {
+			return fmt.Errorf("%w: lottery entry_end_time must be after entry_start_time", ErrInvalidActivity)
+		}
+		if lottery.EntryEndTime.After(activity.StartTime) {
+			return fmt.Errorf("%w: lottery entry must close before the activity starts", ErrInvalidActivity)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidActivity, activity.Type)
+	}
+	return nil
+}
+
+// 创建活动
+func (s *Store) Create(ctx context.Context, req *model.ActivityRequest, operator string) (*model.Activity, error) {
+	if err := Validate(&req.SeckillActivity); err != nil {
+		return nil, err
+	}
+	if err := s.checkInventory(ctx, &req.SeckillActivity, 0); err != nil {
+		return nil, err
+	}
+
+	activity := &model.Activity{Version: 1, Operator: operator}
+	activity.Apply(&req.SeckillActivity)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkOverlap(tx, activity); err != nil {
+			return err
+		}
+		if err := tx.Create(activity).Error; err != nil {
+			return fmt.Errorf("failed to create activity: %w", err)
+		}
+		return addVersion(tx, activity, model.ActivityActionCreate, req.Reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Activity %d created for product %d by %s", activity.ID, activity.ProductID, operator)
+	return activity, nil
+}
+
+// 修改活动，请求需携带当前版本号；已开始的活动不允许修改
+func (s *Store) Update(ctx context.Context, id uint, req *model.ActivityRequest, operator string) (*model.Activity, error) {
+	if err := Validate(&req.SeckillActivity); err != nil {
+		return nil, err
+	}
+
+	activity, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.ProductID != activity.ProductID {
+		return nil, fmt.Errorf("%w: product_id cannot be changed", ErrInvalidActivity)
+	}
+	if req.Version != activity.Version {
+		return nil, fmt.Errorf("%w: current version is %d", ErrVersionConflict, activity.Version)
+	}
+	if !time.Now().Before(activity.StartTime) {
+		return nil, ErrAlreadyStarted
+	}
+	// 已预热的活动预留的库存已从可用库存中扣除，校验时计入
+	var reserved int64
+	if activity.SettledAt == nil {
+		reserved = activity.ReservedStock
+	}
+	if err := s.checkInventory(ctx, &req.SeckillActivity, reserved); err != nil {
+		return nil, err
+	}
+
+	activity.Apply(&req.SeckillActivity)
+	activity.Version++
+	activity.Operator = operator
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkOverlap(tx, activity); err != nil {
+			return err
+		}
+
+		// 按版本号更新，防止并发修改互相覆盖
+		result := tx.Model(&model.Activity{}).
+			Where("id = ? AND version = ?", activity.ID, req.Version).
+			Select("*").Omit(preservedColumns...).
+			Updates(activity)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update activity: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return addVersion(tx, activity, model.ActivityActionUpdate, req.Reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Activity %d updated to version %d by %s", activity.ID, activity.Version, operator)
+	return activity, nil
+}
+
+// 删除活动（软删除），删除前的定义保留在版本历史中
+func (s *Store) Delete(ctx context.Context, id uint, operator, reason string) error {
+	activity, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Activity{}).
+			Where("id = ? AND version = ?", activity.ID, activity.Version).
+			Updates(map[string]interface{}{
+				"version":  activity.Version + 1,
+				"operator": operator,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update activity: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		activity.Version++
+		activity.Operator = operator
+		if err := addVersion(tx, activity, model.ActivityActionDelete, reason); err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Activity{}, activity.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete activity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infof("Activity %d deleted by %s", id, operator)
+	return nil
+}
+
+// 获取活动
+func (s *Store) Get(ctx context.Context, id uint) (*model.Activity, error) {
+	var activity model.Activity
+	if err := s.db.WithContext(ctx).First(&activity, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	return &activity, nil
+}
+
+// 获取商品当前或下一场未结束的活动
+func (s *Store) Current(ctx context.Context, productID int64) (*model.Activity, error) {
+	var activity model.Activity
+	err := s.db.WithContext(ctx).
+		Where("product_id = ? AND end_time > ?", productID, time.Now()).
+		Order("start_time ASC").
+		First(&activity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	return &activity, nil
+}
+
+// 分页查询活动，按开始时间倒序
+func (s *Store) List(ctx context.Context, query *model.ActivityQuery) ([]model.Activity, int64, error) {
+	db := s.db.WithContext(ctx).Model(&model.Activity{})
+	if query.ProductID > 0 {
+		db = db.Where("product_id = ?", query.ProductID)
+	}
+
+	now := time.Now()
+	switch query.State {
+	case "":
+	case model.ActivityStateUpcoming:
+		db = db.Where("start_time > ?", now)
+	case model.ActivityStateOngoing:
+		db = db.Where("start_time <= ? AND end_time > ?", now, now)
+	case model.ActivityStateEnded:
+		db = db.Where("end_time <= ?", now)
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown state %q", ErrInvalidActivity, query.State)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count activities: %w", err)
+	}
+
+	page := max(query.Page, 1)
+	pageSize := query.PageSize
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = 20
+	}
+
+	var activities []model.Activity
+	err := db.Order("start_time DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&activities).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list activities: %w", err)
+	}
+	return activities, total, nil
+}
+
+// 活动的版本历史，包括已删除的活动
+func (s *Store) Versions(ctx context.Context, id uint) ([]model.ActivityVersion, error) {
+	var versions []model.ActivityVersion
+	err := s.db.WithContext(ctx).
+		Where("activity_id = ?", id).
+		Order("version ASC").
+		Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return versions, nil
+}
+
+// 记录预热时间与库存预留，不产生新版本；reservation 为空表示未预留库存
+func (s *Store) MarkPrewarmed(ctx context.Context, id uint, at time.Time, reservation *seckill.InventoryReservation) error {
+	columns := map[string]interface{}{
+		"prewarmed_at": at,
+	}
+	if reservation != nil {
+		columns["reservation_id"] = reservation.ID
+		columns["reserved_stock"] = reservation.Quantity
+		columns["returned_stock"] = 0
+		columns["settled_at"] = nil
+	}
+
+	err := s.db.WithContext(ctx).Model(&model.Activity{}).
+		Where("id = ?", id).
+		UpdateColumns(columns).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark activity prewarmed: %w", err)
+	}
+	return nil
+}
+
+// 记录库存预留已释放，已删除的活动同样记录
+func (s *Store) MarkSettled(ctx context.Context, reservation *seckill.InventoryReservation) error {
+	err := s.db.WithContext(ctx).Unscoped().Model(&model.Activity{}).
+		Where("reservation_id = ? AND settled_at IS NULL", reservation.ID).
+		UpdateColumns(map[string]interface{}{
+			"returned_stock": reservation.Returned,
+			"settled_at":     reservation.SettledAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark activity settled: %w", err)
+	}
+	return nil
+}
+
+// 秒杀库存不能超过库存服务中的可用库存，reserved 为活动已预留、可以继续使用的数量；
+// 发券活动的库存是券码，不占用库存服务的库存
+func (s *Store) checkInventory(ctx context.Context, activity *seckill.SeckillActivity, reserved int64) error {
+	if s.inventory == nil || activity.IsCoupon() {
+		return nil
+	}
+
+	available, err := s.inventory.Available(ctx, activity.ProductID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInventoryUnavailable, err)
+	}
+	if activity.Stock > available+reserved {
+		return fmt.Errorf("%w: stock %d, available %d", ErrExceedsInventory, activity.Stock, available+reserved)
+	}
+	return nil
+}
+
+// 同一商品的活动时间不能重叠
+func (s *Store) checkOverlap(tx *gorm.DB, activity *model.Activity) error {
+	// PostgreSQL 下按商品加事务级咨询锁，避免并发创建的活动同时通过检查
+	if tx.Dialector.Name() == "postgres" {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", activity.ProductID).Error; err != nil {
+			return fmt.Errorf("failed to lock product: %w", err)
+		}
+	}
+
+	var conflict model.Activity
+	err := tx.Where("product_id = ? AND id <> ? AND start_time < ? AND end_time > ?",
+		activity.ProductID, activity.ID, activity.EndTime, activity.StartTime).
+		First(&conflict).Error
+	if err == nil {
+		return fmt.Errorf("%w: activity %d (%s - %s)", ErrTimeOverlap, conflict.ID,
+			conflict.StartTime.Format(time.RFC3339), conflict.EndTime.Format(time.RFC3339))
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check overlapping activities: %w", err)
+	}
+	return nil
+}
+
+// 保存版本快照
+func addVersion(tx *gorm.DB, activity *model.Activity, action, reason string) error {
+	snapshot, err := json.Marshal(activity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity snapshot: %w", err)
+	}
+
+	version := &model.ActivityVersion{
+		ActivityID: activity.ID,
+		Version:    activity.Version,
+		Action:     action,
+		Snapshot:   string(snapshot),
+		Operator:   activity.Operator,
+		Reason:     reason,
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return fmt.Errorf("failed to save activity version: %w", err)
+	}
+	return nil
+}
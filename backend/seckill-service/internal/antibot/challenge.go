@@ -0,0 +1,104 @@
+package antibot
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 工作量证明挑战签名器（HMAC-SHA256），挑战格式：base64(商品ID:用户ID:难度:过期时间:随机盐).base64(签名)
+// 客户端需要找到 nonce，使 SHA-256("挑战:nonce") 的前导零比特数不少于难度。
+type ChallengeSigner struct {
+	secret []byte
+}
+
+// 创建挑战签名器
+func NewChallengeSigner(secret []byte) *ChallengeSigner {
+	return &ChallengeSigner{secret: secret}
+}
+
+// 签发挑战
+func (s *ChallengeSigner) Sign(productID, userID int64, difficulty int, expiresAt time.Time) (string, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate challenge salt: %w", err)
+	}
+
+	payload := fmt.Sprintf("%d:%d:%d:%d:%s", productID, userID, difficulty, expiresAt.Unix(), hex.EncodeToString(salt))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload)), nil
+}
+
+// 校验挑战属于该商品和用户、未过期，且 nonce 满足挑战签发时的难度
+func (s *ChallengeSigner) Verify(challenge, nonce string, productID, userID int64, now time.Time) error {
+	if challenge == "" || nonce == "" {
+		return ErrChallengeRequired
+	}
+
+	encodedPayload, encodedSignature, ok := strings.Cut(challenge, ".")
+	if !ok {
+		return ErrInvalidChallenge
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+	if !hmac.Equal(signature, s.sign(string(payload))) {
+		return ErrInvalidChallenge
+	}
+
+	fields := strings.Split(string(payload), ":")
+	if len(fields) != 5 {
+		return ErrInvalidChallenge
+	}
+	if fields[0] != strconv.FormatInt(productID, 10) || fields[1] != strconv.FormatInt(userID, 10) {
+		return ErrInvalidChallenge
+	}
+	difficulty, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+	expiresAt, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+	if now.Unix() >= expiresAt {
+		return ErrChallengeExpired
+	}
+
+	if LeadingZeroBits(challenge, nonce) < difficulty {
+		return ErrInsufficientWork
+	}
+	return nil
+}
+
+func (s *ChallengeSigner) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// SHA-256("挑战:nonce") 的前导零比特数
+func LeadingZeroBits(challenge, nonce string) int {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
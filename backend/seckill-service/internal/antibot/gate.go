@@ -0,0 +1,200 @@
+package antibot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotEnabled        = errors.New("anti-bot purchase path is not enabled")
+	ErrInvalidDifficulty = errors.New("invalid proof-of-work difficulty")
+	ErrPathRequired      = errors.New("purchase path required")
+	ErrInvalidPath       = errors.New("purchase path is invalid, expired or already used")
+	ErrTooEarly          = errors.New("purchase path is not available yet")
+	ErrActivityEnded     = errors.New("activity has ended")
+	ErrRateLimited       = errors.New("too many purchase path requests")
+	ErrChallengeRequired = errors.New("proof-of-work challenge and nonce required")
+	ErrInvalidChallenge  = errors.New("invalid proof-of-work challenge")
+	ErrChallengeExpired  = errors.New("proof-of-work challenge expired")
+	ErrInsufficientWork  = errors.New("nonce does not satisfy challenge difficulty")
+)
+
+// 全局工作量证明难度key，所有节点共享，攻击期间可通过管理接口调高
+const difficultyKey = "seckill:antibot:difficulty"
+
+// 难度在本地缓存的时间
+const difficultyCacheTTL = 2 * time.Second
+
+// 核对并删除购买路径令牌，保证令牌只能使用一次
+// KEYS[1]: 购买路径令牌key
+// ARGV[1]: 请求携带的令牌
+// 返回 1 表示校验通过，0 表示令牌不存在、已过期或不匹配
+const consumePathLuaScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    redis.call('DEL', KEYS[1])
+    return 1
+end
+return 0
+`
+
+var consumePathScript = redis.NewScript(consumePathLuaScript)
+
+// 获取购买路径请求
+type PathRequest struct {
+	ProductID int64  `json:"product_id"`
+	UserID    int64  `json:"user_id"`
+	Challenge string `json:"challenge,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
+}
+
+// 工作量证明挑战
+type Challenge struct {
+	ProductID  int64     `json:"product_id"`
+	UserID     int64     `json:"user_id"`
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"` // 要求的前导零比特数，为 0 时无需计算
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// 一次性购买路径
+type PurchasePath struct {
+	ProductID int64     `json:"product_id"`
+	UserID    int64     `json:"user_id"`
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// 防刷购买闸门：开售前签发与用户、活动绑定的一次性购买路径，下单时核销
+type Gate struct {
+	client       redis.Cmdable
+	signer       *ChallengeSigner
+	baseline     int // Redis 中未设置难度时使用的默认难度
+	challengeTTL time.Duration
+	logger       *logrus.Logger
+
+	mu         sync.Mutex
+	difficulty int
+	loadedAt   time.Time
+}
+
+// 创建防刷购买闸门
+func NewGate(client redis.Cmdable, signer *ChallengeSigner, difficulty int, challengeTTL time.Duration, logger *logrus.Logger) *Gate {
+	return &Gate{
+		client:       client,
+		signer:       signer,
+		baseline:     difficulty,
+		challengeTTL: challengeTTL,
+		logger:       logger,
+	}
+}
+
+// 与活动其他 key 使用相同的 {商品ID} hash tag
+func pathKey(productID, userID int64) string {
+	return fmt.Sprintf("seckill:path:{%d}:%d", productID, userID)
+}
+
+// 当前工作量证明难度，Redis 不可用时沿用缓存值
+func (g *Gate) Difficulty(ctx context.Context) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.loadedAt.IsZero() && time.Since(g.loadedAt) < difficultyCacheTTL {
+		return g.difficulty
+	}
+
+	difficulty, err := g.client.Get(ctx, difficultyKey).Int()
+	switch {
+	case err == redis.Nil:
+		g.difficulty = g.baseline
+	case err != nil:
+		g.logger.Warnf("Failed to load proof-of-work difficulty: %v", err)
+		if g.loadedAt.IsZero() {
+			g.difficulty = g.baseline
+		}
+	default:
+		g.difficulty = difficulty
+	}
+	g.loadedAt = time.Now()
+	return g.difficulty
+}
+
+// 调整工作量证明难度，所有节点在缓存过期后生效
+func (g *Gate) SetDifficulty(ctx context.Context, difficulty int) error {
+	if err := g.client.Set(ctx, difficultyKey, difficulty, 0).Err(); err != nil {
+		return fmt.Errorf("failed to set difficulty: %w", err)
+	}
+
+	g.mu.Lock()
+	g.difficulty = difficulty
+	g.loadedAt = time.Now()
+	g.mu.Unlock()
+	return nil
+}
+
+// 签发工作量证明挑战
+func (g *Gate) Challenge(ctx context.Context, productID, userID int64) (*Challenge, error) {
+	difficulty := g.Difficulty(ctx)
+	expiresAt := time.Now().Add(g.challengeTTL)
+
+	challenge, err := g.signer.Sign(productID, userID, difficulty, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{
+		ProductID:  productID,
+		UserID:     userID,
+		Challenge:  challenge,
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// 校验工作量证明并签发购买路径，同一用户重复获取时旧路径失效
+func (g *Gate) IssuePath(ctx context.Context, req *PathRequest, ttl time.Duration) (*PurchasePath, error) {
+	if g.Difficulty(ctx) > 0 {
+		if err := g.signer.Verify(req.Challenge, req.Nonce, req.ProductID, req.UserID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate purchase path: %w", err)
+	}
+	path := hex.EncodeToString(raw)
+
+	if err := g.client.Set(ctx, pathKey(req.ProductID, req.UserID), path, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store purchase path: %w", err)
+	}
+
+	return &PurchasePath{
+		ProductID: req.ProductID,
+		UserID:    req.UserID,
+		Path:      path,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// 核销购买路径，过期、不匹配或已使用时返回 ErrInvalidPath
+func (g *Gate) ConsumePath(ctx context.Context, productID, userID int64, path string) error {
+	if path == "" {
+		return ErrPathRequired
+	}
+
+	consumed, err := consumePathScript.Run(ctx, g.client, []string{pathKey(productID, userID)}, path).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to consume purchase path: %w", err)
+	}
+	if consumed != 1 {
+		return ErrInvalidPath
+	}
+	return nil
+}
@@ -8,23 +8,66 @@ import (
 
 type Config struct {
 	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
 	Redis        RedisConfig        `mapstructure:"redis"`
 	CacheService CacheServiceConfig `mapstructure:"cache_service"`
+	Inventory    InventoryConfig    `mapstructure:"inventory_service"`
+	MQ           MQConfig           `mapstructure:"mq"`
 	RabbitMQ     RabbitMQConfig     `mapstructure:"rabbitmq"`
 	Kafka        KafkaConfig        `mapstructure:"kafka"`
+	RedisStream  RedisStreamConfig  `mapstructure:"redis_stream"`
 	Seckill      SeckillConfig      `mapstructure:"seckill"`
+	IDGenerator  IDGeneratorConfig  `mapstructure:"id_generator"`
 	Log          LogConfig          `mapstructure:"log"`
 	Monitoring   MonitoringConfig   `mapstructure:"monitoring"`
 }
 
 type ServerConfig struct {
-	Port     int `mapstructure:"port"`
-	GrpcPort int `mapstructure:"grpc_port"`
+	Port            int           `mapstructure:"port"`
+	GrpcPort        int           `mapstructure:"grpc_port"`
+	DrainTimeout    time.Duration `mapstructure:"drain_timeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+	// 管理接口的角色校验：网关根据 JWT 转发的角色请求头中需包含 AdminRole
+	AdminRolesHeader string `mapstructure:"admin_roles_header"`
+	AdminRole        string `mapstructure:"admin_role"`
+}
+
+type DatabaseConfig struct {
+	Driver          string         `mapstructure:"driver"` // 为空时不启用活动管理
+	Postgres        PostgresConfig `mapstructure:"postgres"`
+	MySQL           MySQLConfig    `mapstructure:"mysql"`
+	MaxIdleConns    int            `mapstructure:"max_idle_conns"`
+	MaxOpenConns    int            `mapstructure:"max_open_conns"`
+	ConnMaxLifetime time.Duration  `mapstructure:"conn_max_lifetime"`
+}
+
+type PostgresConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	DBName   string `mapstructure:"dbname"`
+	SSLMode  string `mapstructure:"sslmode"`
+	TimeZone string `mapstructure:"timezone"`
+}
+
+type MySQLConfig struct {
+	Host      string `mapstructure:"host"`
+	Port      int    `mapstructure:"port"`
+	User      string `mapstructure:"user"`
+	Password  string `mapstructure:"password"`
+	DBName    string `mapstructure:"dbname"`
+	Charset   string `mapstructure:"charset"`
+	ParseTime bool   `mapstructure:"parse_time"`
+	Loc       string `mapstructure:"loc"`
 }
 
 type RedisConfig struct {
 	Host         string        `mapstructure:"host"`
 	Port         int           `mapstructure:"port"`
+	Cluster      bool          `mapstructure:"cluster"` // 是否使用 Redis Cluster
+	Addrs        []string      `mapstructure:"addrs"`   // 集群种子节点，为空时使用 host:port
 	Password     string        `mapstructure:"password"`
 	DB           int           `mapstructure:"db"`
 	PoolSize     int           `mapstructure:"pool_size"`
@@ -41,6 +84,24 @@ type CacheServiceConfig struct {
 	Timeout time.Duration `mapstructure:"timeout"`
 }
 
+type InventoryConfig struct {
+	Host           string        `mapstructure:"host"` // 为空时创建活动不校验可用库存，预热不预留库存
+	Port           int           `mapstructure:"port"`
+	Timeout        time.Duration `mapstructure:"timeout"`
+	SettleInterval time.Duration `mapstructure:"settle_interval"` // 检查已结束活动并释放库存预留的间隔
+}
+
+// 消息队列类型
+const (
+	MQTypeRabbitMQ    = "rabbitmq"
+	MQTypeKafka       = "kafka"
+	MQTypeRedisStream = "redis_stream"
+)
+
+type MQConfig struct {
+	Type string `mapstructure:"type"`
+}
+
 type RabbitMQConfig struct {
 	URL        string `mapstructure:"url"`
 	Exchange   string `mapstructure:"exchange"`
@@ -57,30 +118,238 @@ type KafkaConfig struct {
 	Timeout   time.Duration `mapstructure:"timeout"`
 }
 
+type RedisStreamConfig struct {
+	Stream string `mapstructure:"stream"`
+	MaxLen int64  `mapstructure:"max_len"`
+}
+
 type SeckillConfig struct {
 	MaxConcurrentRequests int                  `mapstructure:"max_concurrent_requests"`
 	QueueSize             int                  `mapstructure:"queue_size"`
 	RequestTimeout        time.Duration        `mapstructure:"request_timeout"`
+	TicketTTL             time.Duration        `mapstructure:"ticket_ttl"`
 	RateLimit             RateLimitConfig      `mapstructure:"rate_limit"`
+	AdaptiveLimit         AdaptiveLimitConfig  `mapstructure:"adaptive_limit"`
+	Hotspot               HotspotConfig        `mapstructure:"hotspot"`
+	Priority              PriorityConfig       `mapstructure:"priority"`
+	WaitingRoom           WaitingRoomConfig    `mapstructure:"waiting_room"`
 	CircuitBreaker        CircuitBreakerConfig `mapstructure:"circuit_breaker"`
 	Degradation           DegradationConfig    `mapstructure:"degradation"`
+	Outbox                OutboxConfig         `mapstructure:"outbox"`
+	Reservation           ReservationConfig    `mapstructure:"reservation"`
+	Lottery               LotteryConfig        `mapstructure:"lottery"`
+	SoldOut               SoldOutConfig        `mapstructure:"sold_out"`
+	AntiBot               AntiBotConfig        `mapstructure:"anti_bot"`
+	Risk                  RiskConfig           `mapstructure:"risk"`
+	Buyers                BuyersConfig         `mapstructure:"buyers"`
+	Report                ReportConfig         `mapstructure:"report"`
+	Dashboard             DashboardConfig      `mapstructure:"dashboard"`
+	Coupon                CouponConfig         `mapstructure:"coupon"`
 }
 
 type RateLimitConfig struct {
-	RequestsPerSecond int `mapstructure:"requests_per_second"`
-	BurstSize         int `mapstructure:"burst_size"`
+	RequestsPerSecond int                    `mapstructure:"requests_per_second"`
+	BurstSize         int                    `mapstructure:"burst_size"`
+	Cluster           ClusterRateLimitConfig `mapstructure:"cluster"`
+}
+
+type ClusterRateLimitConfig struct {
+	Enable                    bool   `mapstructure:"enable"`
+	Key                       string `mapstructure:"key"`
+	RequestsPerSecond         int    `mapstructure:"requests_per_second"`
+	BurstSize                 int    `mapstructure:"burst_size"`
+	FallbackRequestsPerSecond int    `mapstructure:"fallback_requests_per_second"`
+}
+
+type AdaptiveLimitConfig struct {
+	Enable       bool          `mapstructure:"enable"`
+	InitialLimit int           `mapstructure:"initial_limit"`
+	MinLimit     int           `mapstructure:"min_limit"`
+	MaxLimit     int           `mapstructure:"max_limit"`
+	Window       time.Duration `mapstructure:"window"`
+	Smoothing    float64       `mapstructure:"smoothing"`
+	Tolerance    float64       `mapstructure:"tolerance"`
+	BackoffRatio float64       `mapstructure:"backoff_ratio"`
+}
+
+type HotspotConfig struct {
+	Enable  bool               `mapstructure:"enable"`
+	MaxKeys int                `mapstructure:"max_keys"`
+	TopN    int                `mapstructure:"top_n"`
+	Product HotspotParamConfig `mapstructure:"product"`
+	User    HotspotParamConfig `mapstructure:"user"`
+}
+
+type HotspotParamConfig struct {
+	RequestsPerSecond float64                          `mapstructure:"requests_per_second"`
+	BurstSize         int                              `mapstructure:"burst_size"`
+	Overrides         map[string]HotspotOverrideConfig `mapstructure:"overrides"`
+}
+
+type HotspotOverrideConfig struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	BurstSize         int     `mapstructure:"burst_size"`
+}
+
+type PriorityConfig struct {
+	Enable         bool         `mapstructure:"enable"`
+	Header         string       `mapstructure:"header"`
+	RedisKeyPrefix string       `mapstructure:"redis_key_prefix"`
+	DefaultTier    string       `mapstructure:"default_tier"`
+	Tiers          []TierConfig `mapstructure:"tiers"`
+}
+
+type TierConfig struct {
+	Name     string  `mapstructure:"name"`
+	Priority int     `mapstructure:"priority"`
+	Share    float64 `mapstructure:"share"`
+}
+
+type WaitingRoomConfig struct {
+	Enable        bool                                 `mapstructure:"enable"`
+	TokenHeader   string                               `mapstructure:"token_header"`
+	TokenSecret   string                               `mapstructure:"token_secret"`
+	TokenTTL      time.Duration                        `mapstructure:"token_ttl"`
+	AdmitBatch    int64                                `mapstructure:"admit_batch"`
+	AdmitInterval time.Duration                        `mapstructure:"admit_interval"`
+	SkipAhead     map[string]time.Duration             `mapstructure:"skip_ahead"`
+	Activities    map[string]WaitingRoomActivityConfig `mapstructure:"activities"`
+}
+
+type WaitingRoomActivityConfig struct {
+	AdmitBatch    int64         `mapstructure:"admit_batch"`
+	AdmitInterval time.Duration `mapstructure:"admit_interval"`
+	TokenTTL      time.Duration `mapstructure:"token_ttl"`
 }
 
 type CircuitBreakerConfig struct {
-	FailureThreshold int           `mapstructure:"failure_threshold"`
-	RecoveryTimeout  time.Duration `mapstructure:"recovery_timeout"`
-	HalfOpenRequests int           `mapstructure:"half_open_requests"`
+	SlidingWindowType     string        `mapstructure:"sliding_window_type"` // count / time
+	SlidingWindowSize     int           `mapstructure:"sliding_window_size"` // 计数窗口为调用次数，时间窗口为秒数
+	MinimumCalls          int           `mapstructure:"minimum_calls"`
+	FailureRateThreshold  float64       `mapstructure:"failure_rate_threshold"`   // 百分比
+	SlowCallRateThreshold float64       `mapstructure:"slow_call_rate_threshold"` // 百分比
+	SlowCallDuration      time.Duration `mapstructure:"slow_call_duration"`
+	RecoveryTimeout       time.Duration `mapstructure:"recovery_timeout"`
+	HalfOpenRequests      int           `mapstructure:"half_open_requests"`
+	EventChannel          string        `mapstructure:"event_channel"` // 状态变化事件的广播频道，为空时不广播
 }
 
 type DegradationConfig struct {
-	Enable          bool    `mapstructure:"enable"`
-	Threshold       float64 `mapstructure:"threshold"`
-	ResponseMessage string  `mapstructure:"response_message"`
+	Enable           bool          `mapstructure:"enable"`
+	Threshold        float64       `mapstructure:"threshold"`
+	RecoverThreshold float64       `mapstructure:"recover_threshold"`
+	MinDuration      time.Duration `mapstructure:"min_duration"`
+	SampleInterval   time.Duration `mapstructure:"sample_interval"`
+	MaxGoroutines    int           `mapstructure:"max_goroutines"`
+	MaxRedisLatency  time.Duration `mapstructure:"max_redis_latency"`
+	ResponseMessage  string        `mapstructure:"response_message"`
+}
+
+type IDGeneratorConfig struct {
+	WorkerID         int64         `mapstructure:"worker_id"`
+	Lease            bool          `mapstructure:"lease"`
+	LeaseTTL         time.Duration `mapstructure:"lease_ttl"`
+	MaxClockBackward time.Duration `mapstructure:"max_clock_backward"`
+}
+
+type OutboxConfig struct {
+	Enable        bool          `mapstructure:"enable"`
+	Group         string        `mapstructure:"group"`
+	BatchSize     int64         `mapstructure:"batch_size"`
+	BlockTimeout  time.Duration `mapstructure:"block_timeout"`
+	ClaimIdle     time.Duration `mapstructure:"claim_idle"`
+	MaxRetries    int           `mapstructure:"max_retries"`
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+	RefreshPeriod time.Duration `mapstructure:"refresh_period"`
+}
+
+type ReservationConfig struct {
+	Enable       bool          `mapstructure:"enable"`
+	TTL          time.Duration `mapstructure:"ttl"`
+	ReapInterval time.Duration `mapstructure:"reap_interval"`
+	ReapBatch    int64         `mapstructure:"reap_batch"`
+}
+
+type LotteryConfig struct {
+	ClaimWindow   time.Duration `mapstructure:"claim_window"`
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+	PromoteBatch  int64         `mapstructure:"promote_batch"`
+}
+
+type SoldOutConfig struct {
+	Enable  bool          `mapstructure:"enable"`
+	FlagTTL time.Duration `mapstructure:"flag_ttl"`
+}
+
+type AntiBotConfig struct {
+	Enable          bool                 `mapstructure:"enable"`
+	UserHeader      string               `mapstructure:"user_header"`
+	IssueAhead      time.Duration        `mapstructure:"issue_ahead"`
+	PathTTL         time.Duration        `mapstructure:"path_ttl"`
+	ChallengeSecret string               `mapstructure:"challenge_secret"`
+	ChallengeTTL    time.Duration        `mapstructure:"challenge_ttl"`
+	Difficulty      int                  `mapstructure:"difficulty"`
+	MaxDifficulty   int                  `mapstructure:"max_difficulty"`
+	IssueRateLimit  PathIssueLimitConfig `mapstructure:"issue_rate_limit"`
+}
+
+type PathIssueLimitConfig struct {
+	RequestsPerSecond     int     `mapstructure:"requests_per_second"`
+	BurstSize             int     `mapstructure:"burst_size"`
+	UserRequestsPerSecond float64 `mapstructure:"user_requests_per_second"`
+	UserBurst             int     `mapstructure:"user_burst"`
+}
+
+type RiskConfig struct {
+	Enable           bool                `mapstructure:"enable"`
+	DeviceHeader     string              `mapstructure:"device_header"`
+	AccountKeyPrefix string              `mapstructure:"account_key_prefix"`
+	NewAccountAge    time.Duration       `mapstructure:"new_account_age"`
+	NewAccountScore  int                 `mapstructure:"new_account_score"`
+	DevicePurchases  RiskCounterConfig   `mapstructure:"device_purchases"`
+	IPPurchases      RiskCounterConfig   `mapstructure:"ip_purchases"`
+	FailureBurst     RiskCounterConfig   `mapstructure:"failure_burst"`
+	DegradeScore     int                 `mapstructure:"degrade_score"`
+	ChallengeScore   int                 `mapstructure:"challenge_score"`
+	RejectScore      int                 `mapstructure:"reject_score"`
+	Challenge        RiskChallengeConfig `mapstructure:"challenge"`
+	AssessmentTTL    time.Duration       `mapstructure:"assessment_ttl"`
+}
+
+type RiskCounterConfig struct {
+	Limit  int64         `mapstructure:"limit"`
+	Window time.Duration `mapstructure:"window"`
+	Score  int           `mapstructure:"score"`
+}
+
+type RiskChallengeConfig struct {
+	Header      string        `mapstructure:"header"`
+	NonceHeader string        `mapstructure:"nonce_header"`
+	Secret      string        `mapstructure:"secret"`
+	TTL         time.Duration `mapstructure:"ttl"`
+	Difficulty  int           `mapstructure:"difficulty"`
+}
+
+type BuyersConfig struct {
+	BatchMaxUsers   int   `mapstructure:"batch_max_users"`
+	ExportScanCount int64 `mapstructure:"export_scan_count"`
+}
+
+type ReportConfig struct {
+	Enable        bool          `mapstructure:"enable"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	TTL           time.Duration `mapstructure:"ttl"`
+}
+
+type DashboardConfig struct {
+	Enable   bool          `mapstructure:"enable"`
+	Channel  string        `mapstructure:"channel"`
+	Interval time.Duration `mapstructure:"interval"`
+	NodeTTL  time.Duration `mapstructure:"node_ttl"`
+}
+
+type CouponConfig struct {
+	MaxCodesPerRequest int `mapstructure:"max_codes_per_request"`
 }
 
 type LogConfig struct {
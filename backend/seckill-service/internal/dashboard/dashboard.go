@@ -0,0 +1,245 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"seckill-service/internal/report"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNotEnabled = errors.New("dashboard is not enabled")
+
+// 看板广播 Redis 客户端接口
+type Client interface {
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// 节点状态：请求队列积压数与熔断器状态
+type StateFunc func() (queueDepth int64, breakerState string)
+
+type Config struct {
+	Channel  string        // 广播频道
+	Instance string        // 节点标识
+	Interval time.Duration // 广播间隔
+	NodeTTL  time.Duration // 超过该时间未广播的节点不再计入汇总
+}
+
+// 节点每个广播周期发布的统计
+type NodeSnapshot struct {
+	Instance     string                             `json:"instance"`
+	Time         time.Time                          `json:"time"`
+	QueueDepth   int64                              `json:"queue_depth"`
+	BreakerState string                             `json:"breaker_state"`
+	Products     map[int64]map[report.Outcome]int64 `json:"products"` // 上个周期各活动按结果分类的请求数
+}
+
+// 看板中展示的节点状态
+type NodeStatus struct {
+	Instance     string    `json:"instance"`
+	QueueDepth   int64     `json:"queue_depth"`
+	BreakerState string    `json:"breaker_state"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// 推送给看板的集群汇总，速率为各节点上个广播周期的请求数之和
+type Frame struct {
+	ProductID          int64                    `json:"product_id"`
+	Time               time.Time                `json:"time"`
+	RemainingStock     int64                    `json:"remaining_stock"`
+	RequestsPerSecond  int64                    `json:"requests_per_second"`
+	PurchasesPerSecond int64                    `json:"purchases_per_second"`
+	Rejections         map[report.Outcome]int64 `json:"rejections"`      // 每秒按原因分类的拒绝数
+	QueueDepth         int64                    `json:"queue_depth"`     // 各节点请求队列积压之和
+	CircuitBreaker     map[string]int           `json:"circuit_breaker"` // 熔断器状态 -> 节点数
+	Nodes              []*NodeStatus            `json:"nodes"`
+}
+
+// 实时看板：各节点按周期汇总本地请求结果，通过 Redis pub/sub 广播，
+// 每个节点订阅全部广播，按节点保留最近一次统计用于集群汇总
+type Dashboard struct {
+	client Client
+	config Config
+	state  StateFunc
+	logger *logrus.Logger
+
+	mu       sync.Mutex
+	counters map[int64]map[report.Outcome]int64
+
+	nodesMutex sync.RWMutex
+	nodes      map[string]*NodeSnapshot
+}
+
+// 创建实时看板
+func New(client Client, cfg Config, state StateFunc, logger *logrus.Logger) *Dashboard {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.NodeTTL <= 0 {
+		cfg.NodeTTL = 3 * cfg.Interval
+	}
+	return &Dashboard{
+		client:   client,
+		config:   cfg,
+		state:    state,
+		logger:   logger,
+		counters: make(map[int64]map[report.Outcome]int64),
+		nodes:    make(map[string]*NodeSnapshot),
+	}
+}
+
+// 推送间隔
+func (d *Dashboard) Interval() time.Duration {
+	return d.config.Interval
+}
+
+// 记录一个请求的结果
+func (d *Dashboard) Record(productID int64, outcome report.Outcome) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	outcomes, ok := d.counters[productID]
+	if !ok {
+		outcomes = make(map[report.Outcome]int64)
+		d.counters[productID] = outcomes
+	}
+	outcomes[outcome]++
+}
+
+// 订阅其他节点的广播并定期广播本节点统计
+func (d *Dashboard) Start(ctx context.Context) {
+	pubsub := d.client.Subscribe(ctx, d.config.Channel)
+
+	go func() {
+		defer pubsub.Close()
+
+		channel := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-channel:
+				if !ok {
+					return
+				}
+				d.handleMessage(msg.Payload)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(d.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.publish(ctx); err != nil {
+					d.logger.Warnf("Failed to publish dashboard snapshot: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// 广播本节点上个周期的统计，没有请求时也广播，维持节点状态
+func (d *Dashboard) publish(ctx context.Context) error {
+	d.mu.Lock()
+	counters := d.counters
+	d.counters = make(map[int64]map[report.Outcome]int64)
+	d.mu.Unlock()
+
+	snapshot := &NodeSnapshot{
+		Instance: d.config.Instance,
+		Time:     time.Now(),
+		Products: counters,
+	}
+	if d.state != nil {
+		snapshot.QueueDepth, snapshot.BreakerState = d.state()
+	}
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return d.client.Publish(ctx, d.config.Channel, payload).Err()
+}
+
+func (d *Dashboard) handleMessage(payload string) {
+	var snapshot NodeSnapshot
+	if err := json.Unmarshal([]byte(payload), &snapshot); err != nil || snapshot.Instance == "" {
+		d.logger.Debugf("Ignoring invalid dashboard message: %s", payload)
+		return
+	}
+
+	d.nodesMutex.Lock()
+	d.nodes[snapshot.Instance] = &snapshot
+	d.nodesMutex.Unlock()
+}
+
+// 按各节点最近一次广播汇总活动的实时统计
+func (d *Dashboard) Frame(productID, remainingStock int64) *Frame {
+	now := time.Now()
+	frame := &Frame{
+		ProductID:      productID,
+		Time:           now,
+		RemainingStock: remainingStock,
+		Rejections:     make(map[report.Outcome]int64, len(report.Outcomes)-1),
+		CircuitBreaker: make(map[string]int),
+		Nodes:          []*NodeStatus{},
+	}
+	for _, outcome := range report.Outcomes {
+		if outcome != report.OutcomeSuccess {
+			frame.Rejections[outcome] = 0
+		}
+	}
+
+	// 广播周期不是 1 秒时换算为每秒
+	perSecond := func(count int64) int64 {
+		return int64(float64(count) / d.config.Interval.Seconds())
+	}
+
+	d.nodesMutex.Lock()
+	defer d.nodesMutex.Unlock()
+
+	for instance, snapshot := range d.nodes {
+		if now.Sub(snapshot.Time) > d.config.NodeTTL {
+			delete(d.nodes, instance)
+			continue
+		}
+
+		frame.QueueDepth += snapshot.QueueDepth
+		if snapshot.BreakerState != "" {
+			frame.CircuitBreaker[snapshot.BreakerState]++
+		}
+		frame.Nodes = append(frame.Nodes, &NodeStatus{
+			Instance:     snapshot.Instance,
+			QueueDepth:   snapshot.QueueDepth,
+			BreakerState: snapshot.BreakerState,
+			UpdatedAt:    snapshot.Time,
+		})
+
+		for outcome, count := range snapshot.Products[productID] {
+			frame.RequestsPerSecond += perSecond(count)
+			if outcome == report.OutcomeSuccess {
+				frame.PurchasesPerSecond += perSecond(count)
+			} else {
+				frame.Rejections[outcome] += perSecond(count)
+			}
+		}
+	}
+
+	sort.Slice(frame.Nodes, func(i, j int) bool {
+		return frame.Nodes[i].Instance < frame.Nodes[j].Instance
+	})
+	return frame
+}
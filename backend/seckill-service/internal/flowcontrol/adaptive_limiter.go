@@ -0,0 +1,236 @@
+package flowcontrol
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 自适应并发限流器配置
+type AdaptiveLimiterConfig struct {
+	InitialLimit int           // 初始并发上限
+	MinLimit     int           // 并发上限下界
+	MaxLimit     int           // 并发上限上界
+	Window       time.Duration // 采样窗口，每个窗口调整一次上限
+	Smoothing    float64       // 平滑系数（0-1），越大调整越快
+	Tolerance    float64       // 允许的延迟上升倍数，超过后开始收缩
+	BackoffRatio float64       // 请求失败时的乘性减小系数
+}
+
+// 自适应并发限流器统计信息
+type AdaptiveLimiterStats struct {
+	Limit    int
+	InFlight int
+	MinLimit int
+	MaxLimit int
+	LongRTT  time.Duration // 长期延迟基线
+	ShortRTT time.Duration // 最近窗口的平均延迟
+	Drops    int64
+}
+
+// 自适应并发限流器（梯度算法）
+// 以长期延迟基线与最近窗口延迟之比作为梯度：延迟上升时收缩并发上限，
+// 延迟平稳时按 sqrt(limit) 逐步探测更高的上限；请求失败时乘性减小。
+type AdaptiveLimiter struct {
+	config AdaptiveLimiterConfig
+	logger *logrus.Logger
+
+	mu          sync.Mutex
+	limit       float64
+	inFlight    int
+	released    chan struct{} // 有配额归还时关闭并替换，用于唤醒等待者
+	windowStart time.Time
+	rttSum      time.Duration
+	rttCount    int
+	maxInFlight int
+	dropped     bool
+	longRTT     float64
+	shortRTT    time.Duration
+	drops       int64
+}
+
+// 创建自适应并发限流器
+func NewAdaptiveLimiter(config AdaptiveLimiterConfig, logger *logrus.Logger) *AdaptiveLimiter {
+	if config.MinLimit <= 0 {
+		config.MinLimit = 1
+	}
+	if config.MaxLimit < config.MinLimit {
+		config.MaxLimit = config.MinLimit
+	}
+	if config.InitialLimit < config.MinLimit || config.InitialLimit > config.MaxLimit {
+		config.InitialLimit = config.MinLimit
+	}
+	if config.Window <= 0 {
+		config.Window = time.Second
+	}
+	if config.Smoothing <= 0 || config.Smoothing > 1 {
+		config.Smoothing = 0.2
+	}
+	if config.Tolerance < 1 {
+		config.Tolerance = 1.5
+	}
+	if config.BackoffRatio <= 0 || config.BackoffRatio >= 1 {
+		config.BackoffRatio = 0.9
+	}
+
+	return &AdaptiveLimiter{
+		config:      config,
+		logger:      logger,
+		limit:       float64(config.InitialLimit),
+		released:    make(chan struct{}),
+		windowStart: time.Now(),
+	}
+}
+
+// 检查是否允许请求，通过后需调用 Complete 或 Release 归还
+func (l *AdaptiveLimiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// 检查是否允许 n 个请求
+func (l *AdaptiveLimiter) AllowN(n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight+n > int(l.limit) {
+		return false
+	}
+
+	l.inFlight += n
+	if l.inFlight > l.maxInFlight {
+		l.maxInFlight = l.inFlight
+	}
+	return true
+}
+
+// 等待直到可以处理请求
+func (l *AdaptiveLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// 等待直到可以处理 n 个请求
+func (l *AdaptiveLimiter) WaitN(ctx context.Context, n int) error {
+	for {
+		l.mu.Lock()
+		released := l.released
+		l.mu.Unlock()
+
+		if l.AllowN(n) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+			continue
+		}
+	}
+}
+
+// 请求完成，归还配额并记录延迟样本
+func (l *AdaptiveLimiter) Complete(n int, latency time.Duration, success bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if success {
+		l.rttSum += latency
+		l.rttCount++
+	} else {
+		l.dropped = true
+		l.drops++
+	}
+
+	l.release(n)
+	l.maybeUpdate(time.Now())
+}
+
+// 归还配额，不记录样本（请求未实际执行）
+func (l *AdaptiveLimiter) Release(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.release(n)
+}
+
+func (l *AdaptiveLimiter) release(n int) {
+	l.inFlight -= n
+	if l.inFlight < 0 {
+		l.inFlight = 0
+	}
+
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// 窗口结束时调整并发上限
+func (l *AdaptiveLimiter) maybeUpdate(now time.Time) {
+	if now.Sub(l.windowStart) < l.config.Window {
+		return
+	}
+
+	oldLimit := l.limit
+	newLimit := l.limit
+
+	if l.dropped {
+		newLimit = l.limit * l.config.BackoffRatio
+	} else if l.rttCount > 0 {
+		shortRTT := float64(l.rttSum) / float64(l.rttCount)
+		l.shortRTT = time.Duration(shortRTT)
+
+		if l.longRTT == 0 {
+			l.longRTT = shortRTT
+		} else {
+			l.longRTT = l.longRTT*0.95 + shortRTT*0.05
+		}
+
+		// 梯度小于 1 表示延迟明显上升，需收缩
+		gradient := math.Max(0.5, math.Min(1, l.config.Tolerance*l.longRTT/shortRTT))
+		newLimit = l.limit*gradient + math.Sqrt(l.limit)
+
+		// 并发未被充分使用时不继续上调，避免上限无界增长
+		if float64(l.maxInFlight) < l.limit/2 {
+			newLimit = math.Min(newLimit, l.limit)
+		}
+	}
+
+	newLimit = l.limit*(1-l.config.Smoothing) + newLimit*l.config.Smoothing
+	l.limit = math.Max(float64(l.config.MinLimit), math.Min(float64(l.config.MaxLimit), newLimit))
+
+	if int(l.limit) != int(oldLimit) {
+		l.logger.Debugf("Adaptive limit changed: %d -> %d, short_rtt=%v, long_rtt=%v, dropped=%v",
+			int(oldLimit), int(l.limit), l.shortRTT, time.Duration(l.longRTT), l.dropped)
+	}
+
+	l.windowStart = now
+	l.rttSum = 0
+	l.rttCount = 0
+	l.maxInFlight = l.inFlight
+	l.dropped = false
+}
+
+// 获取当前并发上限
+func (l *AdaptiveLimiter) GetLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// 获取统计信息
+func (l *AdaptiveLimiter) GetStats() AdaptiveLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return AdaptiveLimiterStats{
+		Limit:    int(l.limit),
+		InFlight: l.inFlight,
+		MinLimit: l.config.MinLimit,
+		MaxLimit: l.config.MaxLimit,
+		LongRTT:  time.Duration(l.longRTT),
+		ShortRTT: l.shortRTT,
+		Drops:    l.drops,
+	}
+}
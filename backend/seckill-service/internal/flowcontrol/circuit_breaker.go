@@ -3,6 +3,7 @@ package flowcontrol
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,29 +32,54 @@ func (s CircuitBreakerState) String() string {
 	}
 }
 
+// 序列化为状态名
+func (s CircuitBreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // 熔断器错误
 var (
 	ErrCircuitBreakerOpen = errors.New("circuit breaker is open")
 	ErrTooManyRequests    = errors.New("too many requests")
 )
 
+// 状态变化原因
+const (
+	TripReasonFailureRate   = "failure_rate"     // 窗口内失败率达到阈值
+	TripReasonSlowCallRate  = "slow_call_rate"   // 窗口内慢调用比例达到阈值
+	TripReasonReadyToTrip   = "ready_to_trip"    // 自定义熔断判断
+	TripReasonHalfOpenFail  = "half_open_fail"   // 半开状态下试探请求失败或过慢
+	ReasonRecoveryTimeout   = "recovery_timeout" // 打开状态超时，进入半开
+	ReasonHalfOpenRecovered = "half_open_recovered"
+	ReasonManualReset       = "manual_reset"
+)
+
 // 熔断器配置
 type CircuitBreakerConfig struct {
-	MaxRequests   uint32                                                              // 半开状态下的最大请求数
-	Interval      time.Duration                                                       // 统计时间窗口
-	Timeout       time.Duration                                                       // 熔断超时时间
-	ReadyToTrip   func(counts Counts) bool                                            // 判断是否应该熔断
-	OnStateChange func(name string, from CircuitBreakerState, to CircuitBreakerState) // 状态变化回调
-	IsSuccessful  func(err error) bool                                                // 判断请求是否成功
+	MaxRequests uint32        // 半开状态下的试探请求数，全部成功后关闭
+	Interval    time.Duration // Counts 的统计周期，关闭状态下到期清零
+	Timeout     time.Duration // 熔断超时时间，之后进入半开
+
+	SlidingWindowType     string        // count / time，默认 count
+	SlidingWindowSize     int           // 计数窗口为调用次数，时间窗口为秒数
+	MinimumCalls          uint32        // 窗口内调用数达到该值后才判断是否熔断
+	FailureRateThreshold  float64       // 失败率阈值（百分比）
+	SlowCallRateThreshold float64       // 慢调用比例阈值（百分比）
+	SlowCallDuration      time.Duration // 耗时不低于该值的调用视为慢调用
+
+	ReadyToTrip   func(counts Counts) bool                 // 自定义熔断判断，为空时按滑动窗口的失败率与慢调用比例判断
+	OnStateChange func(event CircuitBreakerEvent)          // 状态变化回调，在熔断器锁内调用，不能阻塞
+	IsSuccessful  func(result interface{}, err error) bool // 判断请求是否成功
 }
 
-// 统计信息
+// 统计信息（当前统计周期）
 type Counts struct {
-	Requests             uint32 // 总请求数
-	TotalSuccesses       uint32 // 总成功数
-	TotalFailures        uint32 // 总失败数
-	ConsecutiveSuccesses uint32 // 连续成功数
-	ConsecutiveFailures  uint32 // 连续失败数
+	Requests             uint32 `json:"requests"`              // 总请求数
+	TotalSuccesses       uint32 `json:"total_successes"`       // 总成功数
+	TotalFailures        uint32 `json:"total_failures"`        // 总失败数
+	TotalSlowCalls       uint32 `json:"total_slow_calls"`      // 总慢调用数
+	ConsecutiveSuccesses uint32 `json:"consecutive_successes"` // 连续成功数
+	ConsecutiveFailures  uint32 `json:"consecutive_failures"`  // 连续失败数
 }
 
 // 请求是否成功
@@ -61,6 +87,32 @@ func (c Counts) IsSuccessful() bool {
 	return c.TotalFailures == 0 || c.Requests < 3
 }
 
+// 状态变化事件
+type CircuitBreakerEvent struct {
+	Name   string              `json:"name"`
+	From   CircuitBreakerState `json:"from"`
+	To     CircuitBreakerState `json:"to"`
+	Reason string              `json:"reason"`
+	Window WindowMetrics       `json:"window"` // 状态变化时的窗口统计
+	Time   time.Time           `json:"time"`
+}
+
+// 熔断器状态快照
+type CircuitBreakerSnapshot struct {
+	Name                  string              `json:"name"`
+	State                 CircuitBreakerState `json:"state"`
+	Since                 time.Time           `json:"since"`              // 进入当前状态的时间
+	RetryAt               *time.Time          `json:"retry_at,omitempty"` // 打开状态下进入半开的时间
+	Counts                Counts              `json:"counts"`
+	Window                WindowMetrics       `json:"window"`
+	SlidingWindowType     string              `json:"sliding_window_type"`
+	SlidingWindowSize     int                 `json:"sliding_window_size"`
+	MinimumCalls          uint32              `json:"minimum_calls"`
+	FailureRateThreshold  float64             `json:"failure_rate_threshold"`
+	SlowCallRateThreshold float64             `json:"slow_call_rate_threshold"`
+	SlowCallDurationMs    int64               `json:"slow_call_duration_ms"`
+}
+
 // 熔断器
 type CircuitBreaker struct {
 	name          string
@@ -68,14 +120,23 @@ type CircuitBreaker struct {
 	interval      time.Duration
 	timeout       time.Duration
 	readyToTrip   func(counts Counts) bool
-	isSuccessful  func(err error) bool
-	onStateChange func(name string, from CircuitBreakerState, to CircuitBreakerState)
+	isSuccessful  func(result interface{}, err error) bool
+	onStateChange func(event CircuitBreakerEvent)
+
+	windowType            string
+	windowSize            int
+	minimumCalls          uint32
+	failureRateThreshold  float64
+	slowCallRateThreshold float64
+	slowCallDuration      time.Duration
 
 	mutex      sync.Mutex
 	state      CircuitBreakerState
 	generation uint64
 	counts     Counts
 	expiry     time.Time
+	window     slidingWindow
+	since      time.Time
 
 	logger *logrus.Logger
 }
@@ -83,14 +144,20 @@ type CircuitBreaker struct {
 // 创建熔断器
 func NewCircuitBreaker(name string, config CircuitBreakerConfig, logger *logrus.Logger) *CircuitBreaker {
 	cb := &CircuitBreaker{
-		name:          name,
-		maxRequests:   config.MaxRequests,
-		interval:      config.Interval,
-		timeout:       config.Timeout,
-		readyToTrip:   config.ReadyToTrip,
-		isSuccessful:  config.IsSuccessful,
-		onStateChange: config.OnStateChange,
-		logger:        logger,
+		name:                  name,
+		maxRequests:           config.MaxRequests,
+		interval:              config.Interval,
+		timeout:               config.Timeout,
+		readyToTrip:           config.ReadyToTrip,
+		isSuccessful:          config.IsSuccessful,
+		onStateChange:         config.OnStateChange,
+		windowType:            config.SlidingWindowType,
+		windowSize:            config.SlidingWindowSize,
+		minimumCalls:          config.MinimumCalls,
+		failureRateThreshold:  config.FailureRateThreshold,
+		slowCallRateThreshold: config.SlowCallRateThreshold,
+		slowCallDuration:      config.SlowCallDuration,
+		logger:                logger,
 	}
 
 	if cb.maxRequests == 0 {
@@ -105,67 +172,82 @@ func NewCircuitBreaker(name string, config CircuitBreakerConfig, logger *logrus.
 		cb.timeout = 60 * time.Second
 	}
 
-	if cb.readyToTrip == nil {
-		cb.readyToTrip = defaultReadyToTrip
+	if cb.windowType != SlidingWindowTime {
+		cb.windowType = SlidingWindowCount
+	}
+
+	if cb.windowSize <= 0 {
+		cb.windowSize = 100
+		if cb.windowType == SlidingWindowTime {
+			cb.windowSize = 10
+		}
+	}
+
+	if cb.minimumCalls == 0 {
+		cb.minimumCalls = 10
+	}
+
+	// 计数窗口最多保留 windowSize 次调用，minimumCalls 更大时永远不会熔断
+	if cb.windowType == SlidingWindowCount && cb.minimumCalls > uint32(cb.windowSize) {
+		logger.Warnf("Circuit breaker %s minimum calls %d exceeds count window size %d, clamped to window size",
+			name, cb.minimumCalls, cb.windowSize)
+		cb.minimumCalls = uint32(cb.windowSize)
+	}
+
+	if cb.failureRateThreshold <= 0 {
+		cb.failureRateThreshold = 50
+	}
+
+	if cb.slowCallRateThreshold <= 0 {
+		cb.slowCallRateThreshold = 100
+	}
+
+	if cb.slowCallDuration <= 0 {
+		cb.slowCallDuration = 60 * time.Second
 	}
 
 	if cb.isSuccessful == nil {
 		cb.isSuccessful = defaultIsSuccessful
 	}
 
-	cb.toNewGeneration(time.Now())
+	now := time.Now()
+	cb.window = newSlidingWindow(cb.windowType, cb.windowSize)
+	cb.since = now
+	cb.toNewGeneration(now)
 
 	return cb
 }
 
-// 默认熔断判断逻辑
-func defaultReadyToTrip(counts Counts) bool {
-	return counts.Requests >= 3 && counts.TotalFailures > 0 &&
-		float64(counts.TotalFailures)/float64(counts.Requests) >= 0.6
-}
-
 // 默认成功判断逻辑
-func defaultIsSuccessful(err error) bool {
+func defaultIsSuccessful(_ interface{}, err error) bool {
 	return err == nil
 }
 
 // 执行函数
 func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
-	generation, err := cb.beforeRequest()
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		e := recover()
-		if e != nil {
-			cb.afterRequest(generation, false)
-			panic(e)
-		}
-	}()
-
-	result, err := req()
-	cb.afterRequest(generation, cb.isSuccessful(err))
-	return result, err
+	return cb.ExecuteWithContext(context.Background(), func(context.Context) (interface{}, error) {
+		return req()
+	})
 }
 
-// 执行函数（带上下文）
+// 执行函数（带上下文），请求耗时计入慢调用统计
 func (cb *CircuitBreaker) ExecuteWithContext(ctx context.Context, req func(ctx context.Context) (interface{}, error)) (interface{}, error) {
 	generation, err := cb.beforeRequest()
 	if err != nil {
 		return nil, err
 	}
 
+	start := time.Now()
 	defer func() {
 		e := recover()
 		if e != nil {
-			cb.afterRequest(generation, false)
+			cb.afterRequest(generation, false, time.Since(start))
 			panic(e)
 		}
 	}()
 
 	result, err := req(ctx)
-	cb.afterRequest(generation, cb.isSuccessful(err))
+	cb.afterRequest(generation, cb.isSuccessful(result, err), time.Since(start))
 	return result, err
 }
 
@@ -188,7 +270,7 @@ func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 }
 
 // 请求后处理
-func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
+func (cb *CircuitBreaker) afterRequest(before uint64, success bool, duration time.Duration) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
@@ -198,29 +280,52 @@ func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
 		return
 	}
 
+	slow := duration >= cb.slowCallDuration
+	if slow {
+		cb.counts.TotalSlowCalls++
+	}
 	if success {
-		cb.onSuccess(state, now)
+		cb.counts.onSuccess()
 	} else {
-		cb.onFailure(state, now)
+		cb.counts.onFailure()
 	}
-}
-
-// 成功处理
-func (cb *CircuitBreaker) onSuccess(state CircuitBreakerState, now time.Time) {
-	cb.counts.onSuccess()
 
-	if state == StateHalfOpen {
-		cb.setState(StateClosed, now)
+	switch state {
+	case StateClosed:
+		cb.window.record(now, !success, slow)
+		if reason := cb.tripReason(now); reason != "" {
+			cb.setState(StateOpen, now, reason)
+		}
+	case StateHalfOpen:
+		// 试探请求失败或过慢时重新打开，全部成功后关闭
+		if !success || slow {
+			cb.setState(StateOpen, now, TripReasonHalfOpenFail)
+		} else if cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
+			cb.setState(StateClosed, now, ReasonHalfOpenRecovered)
+		}
 	}
 }
 
-// 失败处理
-func (cb *CircuitBreaker) onFailure(state CircuitBreakerState, now time.Time) {
-	cb.counts.onFailure()
+// 关闭状态下判断是否熔断，返回熔断原因，不熔断时为空
+func (cb *CircuitBreaker) tripReason(now time.Time) string {
+	if cb.readyToTrip != nil {
+		if cb.readyToTrip(cb.counts) {
+			return TripReasonReadyToTrip
+		}
+		return ""
+	}
 
-	if cb.readyToTrip(cb.counts) {
-		cb.setState(StateOpen, now)
+	metrics := cb.window.metrics(now)
+	if metrics.Calls < cb.minimumCalls {
+		return ""
+	}
+	if metrics.FailureRate >= cb.failureRateThreshold {
+		return TripReasonFailureRate
+	}
+	if metrics.SlowCallRate >= cb.slowCallRateThreshold {
+		return TripReasonSlowCallRate
 	}
+	return ""
 }
 
 // 获取当前状态
@@ -232,28 +337,39 @@ func (cb *CircuitBreaker) currentState(now time.Time) (CircuitBreakerState, uint
 		}
 	case StateOpen:
 		if cb.expiry.Before(now) {
-			cb.setState(StateHalfOpen, now)
+			cb.setState(StateHalfOpen, now, ReasonRecoveryTimeout)
 		}
 	}
 	return cb.state, cb.generation
 }
 
-// 设置状态
-func (cb *CircuitBreaker) setState(state CircuitBreakerState, now time.Time) {
+// 设置状态，重新关闭时清空滑动窗口
+func (cb *CircuitBreaker) setState(state CircuitBreakerState, now time.Time, reason string) {
 	if cb.state == state {
 		return
 	}
 
-	prev := cb.state
-	cb.state = state
+	event := CircuitBreakerEvent{
+		Name:   cb.name,
+		From:   cb.state,
+		To:     state,
+		Reason: reason,
+		Window: cb.window.metrics(now),
+		Time:   now,
+	}
 
+	cb.state = state
+	cb.since = now
 	cb.toNewGeneration(now)
+	if state == StateClosed {
+		cb.window.reset()
+	}
 
 	if cb.onStateChange != nil {
-		cb.onStateChange(cb.name, prev, state)
+		cb.onStateChange(event)
 	}
 
-	cb.logger.Infof("Circuit breaker %s state changed from %s to %s", cb.name, prev, state)
+	cb.logger.Infof("Circuit breaker %s state changed from %s to %s (%s)", cb.name, event.From, state, reason)
 }
 
 // 新的统计周期
@@ -294,6 +410,48 @@ func (cb *CircuitBreaker) Counts() Counts {
 	return cb.counts
 }
 
+// 获取状态快照
+func (cb *CircuitBreaker) Snapshot() CircuitBreakerSnapshot {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	state, _ := cb.currentState(now)
+
+	snapshot := CircuitBreakerSnapshot{
+		Name:                  cb.name,
+		State:                 state,
+		Since:                 cb.since,
+		Counts:                cb.counts,
+		Window:                cb.window.metrics(now),
+		SlidingWindowType:     cb.windowType,
+		SlidingWindowSize:     cb.windowSize,
+		MinimumCalls:          cb.minimumCalls,
+		FailureRateThreshold:  cb.failureRateThreshold,
+		SlowCallRateThreshold: cb.slowCallRateThreshold,
+		SlowCallDurationMs:    cb.slowCallDuration.Milliseconds(),
+	}
+	if state == StateOpen {
+		retryAt := cb.expiry
+		snapshot.RetryAt = &retryAt
+	}
+	return snapshot
+}
+
+// 重置为关闭状态并清空统计
+func (cb *CircuitBreaker) Reset() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	if cb.state != StateClosed {
+		cb.setState(StateClosed, now, ReasonManualReset)
+		return
+	}
+	cb.window.reset()
+	cb.toNewGeneration(now)
+}
+
 // 统计信息方法
 func (c *Counts) onRequest() {
 	c.Requests++
@@ -315,6 +473,7 @@ func (c *Counts) clear() {
 	c.Requests = 0
 	c.TotalSuccesses = 0
 	c.TotalFailures = 0
+	c.TotalSlowCalls = 0
 	c.ConsecutiveSuccesses = 0
 	c.ConsecutiveFailures = 0
 }
@@ -368,15 +527,41 @@ func (m *CircuitBreakerManager) GetAllStates() map[string]CircuitBreakerState {
 	return states
 }
 
-// 重置熔断器
-func (m *CircuitBreakerManager) ResetCircuitBreaker(name string) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+// 获取所有熔断器的状态快照，按名称排序
+func (m *CircuitBreakerManager) Snapshots() []CircuitBreakerSnapshot {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
-	if cb, exists := m.breakers[name]; exists {
-		cb.mutex.Lock()
-		cb.toNewGeneration(time.Now())
-		cb.state = StateClosed
-		cb.mutex.Unlock()
+	snapshots := make([]CircuitBreakerSnapshot, 0, len(m.breakers))
+	for _, cb := range m.breakers {
+		snapshots = append(snapshots, cb.Snapshot())
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots
+}
+
+// 获取指定熔断器的状态快照
+func (m *CircuitBreakerManager) Snapshot(name string) (CircuitBreakerSnapshot, bool) {
+	m.mutex.RLock()
+	cb, exists := m.breakers[name]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return CircuitBreakerSnapshot{}, false
+	}
+	return cb.Snapshot(), true
+}
+
+// 重置熔断器，返回熔断器是否存在
+func (m *CircuitBreakerManager) ResetCircuitBreaker(name string) bool {
+	m.mutex.RLock()
+	cb, exists := m.breakers[name]
+	m.mutex.RUnlock()
+
+	if exists {
+		cb.Reset()
 	}
+	return exists
 }
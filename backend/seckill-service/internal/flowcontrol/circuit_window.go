@@ -0,0 +1,142 @@
+package flowcontrol
+
+import (
+	"math"
+	"time"
+)
+
+// 熔断器滑动窗口类型
+const (
+	SlidingWindowCount = "count" // 最近 N 次调用
+	SlidingWindowTime  = "time"  // 最近 N 秒内的调用
+)
+
+// 滑动窗口内的调用统计，比例为百分比
+type WindowMetrics struct {
+	Calls        uint32  `json:"calls"`
+	Failures     uint32  `json:"failures"`
+	SlowCalls    uint32  `json:"slow_calls"`
+	FailureRate  float64 `json:"failure_rate"`
+	SlowCallRate float64 `json:"slow_call_rate"`
+}
+
+func newWindowMetrics(calls, failures, slowCalls uint32) WindowMetrics {
+	metrics := WindowMetrics{Calls: calls, Failures: failures, SlowCalls: slowCalls}
+	if calls > 0 {
+		metrics.FailureRate = percentage(failures, calls)
+		metrics.SlowCallRate = percentage(slowCalls, calls)
+	}
+	return metrics
+}
+
+func percentage(n, total uint32) float64 {
+	return math.Round(float64(n)/float64(total)*10000) / 100
+}
+
+// 熔断器滑动窗口
+type slidingWindow interface {
+	record(now time.Time, failure, slow bool)
+	metrics(now time.Time) WindowMetrics
+	reset()
+}
+
+func newSlidingWindow(windowType string, size int) slidingWindow {
+	if windowType == SlidingWindowTime {
+		return &timeWindow{buckets: make([]timeBucket, size)}
+	}
+	return &countWindow{outcomes: make([]callOutcome, size)}
+}
+
+type callOutcome struct {
+	failure bool
+	slow    bool
+}
+
+// 计数窗口：环形缓冲区保存最近 size 次调用的结果
+type countWindow struct {
+	outcomes  []callOutcome
+	next      int
+	filled    int
+	failures  uint32
+	slowCalls uint32
+}
+
+func (w *countWindow) record(_ time.Time, failure, slow bool) {
+	if w.filled == len(w.outcomes) {
+		evicted := w.outcomes[w.next]
+		if evicted.failure {
+			w.failures--
+		}
+		if evicted.slow {
+			w.slowCalls--
+		}
+	} else {
+		w.filled++
+	}
+
+	w.outcomes[w.next] = callOutcome{failure: failure, slow: slow}
+	if failure {
+		w.failures++
+	}
+	if slow {
+		w.slowCalls++
+	}
+	w.next = (w.next + 1) % len(w.outcomes)
+}
+
+func (w *countWindow) metrics(time.Time) WindowMetrics {
+	return newWindowMetrics(uint32(w.filled), w.failures, w.slowCalls)
+}
+
+func (w *countWindow) reset() {
+	clear(w.outcomes)
+	w.next, w.filled, w.failures, w.slowCalls = 0, 0, 0, 0
+}
+
+// 时间窗口的每秒统计
+type timeBucket struct {
+	second    int64
+	calls     uint32
+	failures  uint32
+	slowCalls uint32
+}
+
+// 时间窗口：按秒分桶，统计最近 size 秒（含当前秒）的调用
+type timeWindow struct {
+	buckets []timeBucket
+}
+
+func (w *timeWindow) record(now time.Time, failure, slow bool) {
+	second := now.Unix()
+	bucket := &w.buckets[second%int64(len(w.buckets))]
+	if bucket.second != second {
+		*bucket = timeBucket{second: second}
+	}
+
+	bucket.calls++
+	if failure {
+		bucket.failures++
+	}
+	if slow {
+		bucket.slowCalls++
+	}
+}
+
+func (w *timeWindow) metrics(now time.Time) WindowMetrics {
+	second := now.Unix()
+	size := int64(len(w.buckets))
+
+	var calls, failures, slowCalls uint32
+	for _, bucket := range w.buckets {
+		if age := second - bucket.second; age >= 0 && age < size {
+			calls += bucket.calls
+			failures += bucket.failures
+			slowCalls += bucket.slowCalls
+		}
+	}
+	return newWindowMetrics(calls, failures, slowCalls)
+}
+
+func (w *timeWindow) reset() {
+	clear(w.buckets)
+}
@@ -0,0 +1,171 @@
+package flowcontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type windowCall struct {
+	offset  time.Duration // 相对起始时间
+	failure bool
+	slow    bool
+}
+
+func TestCountWindowEviction(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		calls []windowCall
+		want  WindowMetrics
+	}{
+		{
+			name: "empty",
+			size: 3,
+			want: WindowMetrics{},
+		},
+		{
+			name:  "partially filled",
+			size:  3,
+			calls: []windowCall{{failure: true}, {slow: true}},
+			want:  WindowMetrics{Calls: 2, Failures: 1, SlowCalls: 1, FailureRate: 50, SlowCallRate: 50},
+		},
+		{
+			name:  "oldest failure evicted",
+			size:  3,
+			calls: []windowCall{{failure: true}, {}, {}, {}},
+			want:  WindowMetrics{Calls: 3},
+		},
+		{
+			name:  "oldest slow call evicted",
+			size:  2,
+			calls: []windowCall{{slow: true}, {failure: true}, {failure: true, slow: true}},
+			want:  WindowMetrics{Calls: 2, Failures: 2, SlowCalls: 1, FailureRate: 100, SlowCallRate: 50},
+		},
+		{
+			name:  "wrapped several times",
+			size:  3,
+			calls: []windowCall{{failure: true}, {failure: true}, {failure: true}, {}, {}, {failure: true}, {}, {slow: true}},
+			want:  WindowMetrics{Calls: 3, Failures: 1, SlowCalls: 1, FailureRate: 33.33, SlowCallRate: 33.33},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			w := newSlidingWindow(SlidingWindowCount, tt.size)
+			for _, call := range tt.calls {
+				w.record(now.Add(call.offset), call.failure, call.slow)
+			}
+
+			if got := w.metrics(now); got != tt.want {
+				t.Errorf("metrics = %+v, want %+v", got, tt.want)
+			}
+
+			w.reset()
+			if got := w.metrics(now); got != (WindowMetrics{}) {
+				t.Errorf("metrics after reset = %+v, want empty", got)
+			}
+		})
+	}
+}
+
+func TestTimeWindowEviction(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		calls []windowCall
+		at    time.Duration // 读取统计的时间
+		want  WindowMetrics
+	}{
+		{
+			name:  "same second",
+			size:  3,
+			calls: []windowCall{{failure: true}, {}, {slow: true}, {}},
+			want:  WindowMetrics{Calls: 4, Failures: 1, SlowCalls: 1, FailureRate: 25, SlowCallRate: 25},
+		},
+		{
+			name:  "within window",
+			size:  3,
+			calls: []windowCall{{failure: true}, {offset: time.Second}, {offset: 2 * time.Second}},
+			at:    2 * time.Second,
+			want:  WindowMetrics{Calls: 3, Failures: 1, FailureRate: 33.33},
+		},
+		{
+			name:  "oldest second expired",
+			size:  3,
+			calls: []windowCall{{failure: true}, {offset: time.Second}, {offset: 2 * time.Second}},
+			at:    3 * time.Second,
+			want:  WindowMetrics{Calls: 2},
+		},
+		{
+			name:  "all expired without new calls",
+			size:  3,
+			calls: []windowCall{{failure: true}, {offset: time.Second, slow: true}},
+			at:    10 * time.Second,
+			want:  WindowMetrics{},
+		},
+		{
+			name:  "bucket reused after wrap",
+			size:  3,
+			calls: []windowCall{{failure: true}, {failure: true}, {offset: 3 * time.Second}},
+			at:    3 * time.Second,
+			want:  WindowMetrics{Calls: 1},
+		},
+		{
+			name:  "sub-second offsets share bucket",
+			size:  2,
+			calls: []windowCall{{offset: 100 * time.Millisecond, failure: true}, {offset: 900 * time.Millisecond}, {offset: 1500 * time.Millisecond}},
+			at:    1900 * time.Millisecond,
+			want:  WindowMetrics{Calls: 3, Failures: 1, FailureRate: 33.33},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(1700000000, 0)
+			w := newSlidingWindow(SlidingWindowTime, tt.size)
+			for _, call := range tt.calls {
+				w.record(start.Add(call.offset), call.failure, call.slow)
+			}
+
+			if got := w.metrics(start.Add(tt.at)); got != tt.want {
+				t.Errorf("metrics = %+v, want %+v", got, tt.want)
+			}
+
+			w.reset()
+			if got := w.metrics(start.Add(tt.at)); got != (WindowMetrics{}) {
+				t.Errorf("metrics after reset = %+v, want empty", got)
+			}
+		})
+	}
+}
+
+func TestNewCircuitBreakerClampsMinimumCalls(t *testing.T) {
+	tests := []struct {
+		name         string
+		windowType   string
+		windowSize   int
+		minimumCalls uint32
+		want         uint32
+	}{
+		{name: "count window below size", windowType: SlidingWindowCount, windowSize: 100, minimumCalls: 20, want: 20},
+		{name: "count window above size", windowType: SlidingWindowCount, windowSize: 10, minimumCalls: 20, want: 10},
+		{name: "count window default size", windowType: SlidingWindowCount, minimumCalls: 200, want: 100},
+		{name: "time window not clamped", windowType: SlidingWindowTime, windowSize: 10, minimumCalls: 20, want: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewCircuitBreaker(tt.name, CircuitBreakerConfig{
+				SlidingWindowType: tt.windowType,
+				SlidingWindowSize: tt.windowSize,
+				MinimumCalls:      tt.minimumCalls,
+			}, logrus.New())
+			if cb.minimumCalls != tt.want {
+				t.Errorf("minimumCalls = %d, want %d", cb.minimumCalls, tt.want)
+			}
+		})
+	}
+}
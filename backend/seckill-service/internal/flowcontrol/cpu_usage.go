@@ -0,0 +1,171 @@
+package flowcontrol
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errCPUStatUnavailable = errors.New("cpu statistics unavailable")
+
+// CPU 使用率采样器，优先读取 cgroup（容器配额），否则读取 /proc/stat
+type cpuSampler struct {
+	read      func() (busy, total float64, err error)
+	source    string
+	lastBusy  float64
+	lastTotal float64
+	primed    bool
+}
+
+// 创建 CPU 采样器
+func newCPUSampler() *cpuSampler {
+	if fileExists("/sys/fs/cgroup/cpu.stat") {
+		return &cpuSampler{read: cgroupUsageReader(readCgroupV2Usage, cgroupV2CPULimit()), source: "cgroup v2"}
+	}
+	for _, dir := range []string{"/sys/fs/cgroup/cpuacct", "/sys/fs/cgroup/cpu,cpuacct"} {
+		if fileExists(dir + "/cpuacct.usage") {
+			usageFile := dir + "/cpuacct.usage"
+			readUsage := func() (time.Duration, error) {
+				ns, err := readInt(usageFile)
+				return time.Duration(ns), err
+			}
+			return &cpuSampler{read: cgroupUsageReader(readUsage, cgroupV1CPULimit()), source: "cgroup v1"}
+		}
+	}
+	return &cpuSampler{read: readProcStat, source: "procfs"}
+}
+
+// 采样 CPU 使用率（0-1），首次采样只记录基线
+func (s *cpuSampler) Sample() (float64, error) {
+	busy, total, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		s.lastBusy, s.lastTotal, s.primed = busy, total, true
+	}()
+	if !s.primed || total <= s.lastTotal {
+		return 0, nil
+	}
+
+	usage := (busy - s.lastBusy) / (total - s.lastTotal)
+	if usage < 0 {
+		usage = 0
+	}
+	if usage > 1 {
+		usage = 1
+	}
+	return usage, nil
+}
+
+// cgroup 用量读取：busy 为累计 CPU 时间，total 为墙钟时间乘以可用核数
+func cgroupUsageReader(readUsage func() (time.Duration, error), cores float64) func() (float64, float64, error) {
+	return func() (float64, float64, error) {
+		usage, err := readUsage()
+		if err != nil {
+			return 0, 0, err
+		}
+		now := float64(time.Now().UnixNano())
+		return float64(usage), now * cores, nil
+	}
+}
+
+// 读取 cgroup v2 累计 CPU 时间
+func readCgroupV2Usage() (time.Duration, error) {
+	file, err := os.Open("/sys/fs/cgroup/cpu.stat")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usec, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(usec) * time.Microsecond, nil
+		}
+	}
+	return 0, errCPUStatUnavailable
+}
+
+// cgroup v2 CPU 配额（核数），未设置时为本机核数
+func cgroupV2CPULimit() float64 {
+	data, err := os.ReadFile("/sys/fs/cgroup/cpu.max")
+	if err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, err1 := strconv.ParseFloat(fields[0], 64)
+			period, err2 := strconv.ParseFloat(fields[1], 64)
+			if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+				return quota / period
+			}
+		}
+	}
+	return float64(runtime.NumCPU())
+}
+
+// cgroup v1 CPU 配额（核数），未设置时为本机核数
+func cgroupV1CPULimit() float64 {
+	for _, dir := range []string{"/sys/fs/cgroup/cpu", "/sys/fs/cgroup/cpu,cpuacct"} {
+		quota, err1 := readInt(dir + "/cpu.cfs_quota_us")
+		period, err2 := readInt(dir + "/cpu.cfs_period_us")
+		if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+			return float64(quota) / float64(period)
+		}
+	}
+	return float64(runtime.NumCPU())
+}
+
+// 读取 /proc/stat 中的整机 CPU 时间
+func readProcStat() (float64, float64, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return 0, 0, errCPUStatUnavailable
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, errCPUStatUnavailable
+	}
+
+	var total, idle float64
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		total += value
+		// idle 与 iowait 视为空闲
+		if i == 3 || i == 4 {
+			idle += value
+		}
+	}
+	return total - idle, total, nil
+}
+
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
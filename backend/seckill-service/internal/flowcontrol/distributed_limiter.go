@@ -0,0 +1,171 @@
+package flowcontrol
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// 分布式限流 Redis 客户端接口
+type DistributedLimiterClient interface {
+	redis.Scripter
+}
+
+// GCRA 限流脚本，使用 Redis 服务器时间保证各节点时钟一致
+// KEYS[1]: 限流 key
+// ARGV[1]: 单个请求的发放间隔（微秒）
+// ARGV[2]: 突发容量
+// ARGV[3]: 本次请求数
+// 返回 {是否允许, 需等待的微秒数}
+const gcraLuaScript = `
+if redis.replicate_commands then
+    redis.replicate_commands()
+end
+
+local now_parts = redis.call('TIME')
+local now = tonumber(now_parts[1]) * 1000000 + tonumber(now_parts[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+    tat = now
+end
+
+local new_tat = tat + interval * cost
+local allow_at = new_tat - interval * burst
+if allow_at > now then
+    return {0, allow_at - now}
+end
+
+redis.call('SET', KEYS[1], string.format('%.0f', new_tat), 'PX', math.ceil((new_tat - now) / 1000) + 1)
+return {1, 0}
+`
+
+var gcraScript = redis.NewScript(gcraLuaScript)
+
+// 分布式限流器（基于 Redis 的 GCRA 算法），Redis 不可用时退化为本地限流
+type DistributedLimiter struct {
+	key         string
+	limit       int
+	window      time.Duration
+	burst       int
+	interval    time.Duration // 单个请求的发放间隔
+	timeout     time.Duration // 单次 Redis 调用超时
+	redisClient DistributedLimiterClient
+	fallback    Limiter
+	degraded    int32 // 是否处于本地降级状态
+	logger      *logrus.Logger
+}
+
+// 创建分布式限流器：window 内最多 limit 个请求，允许 burst 个突发
+func NewDistributedLimiter(key string, limit int, window time.Duration, burst int, redisClient DistributedLimiterClient, logger *logrus.Logger) *DistributedLimiter {
+	if limit <= 0 {
+		limit = 1
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	rate := float64(limit) / window.Seconds()
+
+	return &DistributedLimiter{
+		key:         key,
+		limit:       limit,
+		window:      window,
+		burst:       burst,
+		interval:    window / time.Duration(limit),
+		timeout:     100 * time.Millisecond,
+		redisClient: redisClient,
+		fallback:    NewTokenBucketLimiter(rate, burst, logger),
+		logger:      logger,
+	}
+}
+
+// 设置 Redis 不可用时使用的本地限流器
+func (l *DistributedLimiter) SetFallback(fallback Limiter) {
+	l.fallback = fallback
+}
+
+// 检查是否允许请求
+func (l *DistributedLimiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// 检查是否允许 n 个请求
+func (l *DistributedLimiter) AllowN(n int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	allowed, _, err := l.reserve(ctx, n)
+	if err != nil {
+		return l.fallback.AllowN(n)
+	}
+	return allowed
+}
+
+// 等待直到可以处理请求
+func (l *DistributedLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// 等待直到可以处理 n 个请求，按脚本返回的等待时间休眠
+func (l *DistributedLimiter) WaitN(ctx context.Context, n int) error {
+	if n > l.burst {
+		return fmt.Errorf("requested tokens %d exceeds burst %d", n, l.burst)
+	}
+
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, l.timeout)
+		allowed, retryAfter, err := l.reserve(callCtx, n)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return l.fallback.WaitN(ctx, n)
+		}
+		if allowed {
+			return nil
+		}
+
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// 执行 GCRA 脚本，返回是否允许以及需等待的时间
+func (l *DistributedLimiter) reserve(ctx context.Context, n int) (bool, time.Duration, error) {
+	result, err := gcraScript.Run(ctx, l.redisClient, []string{l.key},
+		l.interval.Microseconds(), l.burst, n).Int64Slice()
+	if err == nil && len(result) != 2 {
+		err = fmt.Errorf("unexpected limiter script result: %v", result)
+	}
+	if err != nil {
+		if atomic.CompareAndSwapInt32(&l.degraded, 0, 1) {
+			l.logger.Warnf("Distributed limiter %s falling back to local limiter: %v", l.key, err)
+		}
+		return false, 0, err
+	}
+
+	if atomic.CompareAndSwapInt32(&l.degraded, 1, 0) {
+		l.logger.Infof("Distributed limiter %s recovered", l.key)
+	}
+
+	return result[0] == 1, time.Duration(result[1]) * time.Microsecond, nil
+}
+
+// 是否处于本地降级状态
+func (l *DistributedLimiter) Degraded() bool {
+	return atomic.LoadInt32(&l.degraded) == 1
+}
@@ -0,0 +1,120 @@
+package flowcontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+type gcraStep struct {
+	advance        time.Duration // 本次请求前 Redis 时间前进
+	n              int
+	wantAllowed    bool
+	wantRetryAfter time.Duration
+}
+
+func TestDistributedLimiterRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		window time.Duration
+		burst  int
+		steps  []gcraStep
+	}{
+		{
+			name: "burst then retry after one interval", limit: 10, window: time.Second, burst: 3,
+			steps: []gcraStep{
+				{n: 1, wantAllowed: true},
+				{n: 1, wantAllowed: true},
+				{n: 1, wantAllowed: true},
+				{n: 1, wantRetryAfter: 100 * time.Millisecond},
+				{advance: 40 * time.Millisecond, n: 1, wantRetryAfter: 60 * time.Millisecond},
+				{advance: 60 * time.Millisecond, n: 1, wantAllowed: true},
+				{n: 1, wantRetryAfter: 100 * time.Millisecond},
+			},
+		},
+		{
+			name: "batch request waits for enough emissions", limit: 10, window: time.Second, burst: 3,
+			steps: []gcraStep{
+				{n: 2, wantAllowed: true},
+				{n: 2, wantRetryAfter: 100 * time.Millisecond},
+				{n: 1, wantAllowed: true},
+				{n: 2, wantRetryAfter: 200 * time.Millisecond},
+			},
+		},
+		{
+			name: "idle time refills up to burst only", limit: 10, window: time.Second, burst: 2,
+			steps: []gcraStep{
+				{n: 2, wantAllowed: true},
+				{advance: 10 * time.Second, n: 2, wantAllowed: true},
+				{n: 1, wantRetryAfter: 100 * time.Millisecond},
+			},
+		},
+		{
+			name: "slow rate", limit: 60, window: time.Minute, burst: 1,
+			steps: []gcraStep{
+				{n: 1, wantAllowed: true},
+				{advance: 250 * time.Millisecond, n: 1, wantRetryAfter: 750 * time.Millisecond},
+				{advance: 750 * time.Millisecond, n: 1, wantAllowed: true},
+			},
+		},
+		{
+			name: "denied request does not consume", limit: 10, window: time.Second, burst: 1,
+			steps: []gcraStep{
+				{n: 1, wantAllowed: true},
+				{n: 1, wantRetryAfter: 100 * time.Millisecond},
+				{n: 1, wantRetryAfter: 100 * time.Millisecond},
+				{advance: 100 * time.Millisecond, n: 1, wantAllowed: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer client.Close()
+
+			now := time.Unix(1700000000, 0)
+			mr.SetTime(now)
+
+			limiter := NewDistributedLimiter("test:limiter", tt.limit, tt.window, tt.burst, client, logrus.New())
+			for i, step := range tt.steps {
+				now = now.Add(step.advance)
+				mr.SetTime(now)
+
+				allowed, retryAfter, err := limiter.reserve(context.Background(), step.n)
+				if err != nil {
+					t.Fatalf("step %d: reserve: %v", i, err)
+				}
+				if allowed != step.wantAllowed || retryAfter != step.wantRetryAfter {
+					t.Fatalf("step %d: reserve(%d) = %v, %v, want %v, %v",
+						i, step.n, allowed, retryAfter, step.wantAllowed, step.wantRetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestDistributedLimiterFallback(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	limiter := NewDistributedLimiter("test:limiter", 10, time.Second, 1, client, logrus.New())
+	limiter.SetFallback(NewTokenBucketLimiter(0, 2, logrus.New()))
+
+	mr.Close()
+	for i, want := range []bool{true, true, false} {
+		if got := limiter.Allow(); got != want {
+			t.Fatalf("Allow #%d with Redis down = %v, want %v", i, got, want)
+		}
+	}
+	if !limiter.Degraded() {
+		t.Errorf("Degraded() = false, want true")
+	}
+}
@@ -0,0 +1,186 @@
+package flowcontrol
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 热点参数单值限流配置
+type HotspotOverride struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// 热点参数限流配置
+type HotspotLimiterConfig struct {
+	Name              string                     // 参数名，如 product、user
+	RequestsPerSecond float64                    // 每个参数值的默认限流速率
+	Burst             int                        // 每个参数值的默认突发容量
+	Overrides         map[string]HotspotOverride // 指定参数值的限流配置
+	MaxKeys           int                        // 最多跟踪的参数值数量，超出后淘汰最久未访问的
+}
+
+// 热点参数值统计
+type HotKey struct {
+	Value    string
+	QPS      int64 // 最近一秒的请求数
+	Total    int64
+	Rejected int64
+	LastSeen time.Time
+}
+
+// 单个参数值的限流状态
+type hotspotEntry struct {
+	value       string
+	limiter     *TokenBucketLimiter
+	total       int64
+	rejected    int64
+	secondStart time.Time
+	secondCount int64
+	lastQPS     int64
+	lastSeen    time.Time
+}
+
+// 热点参数限流器：按参数值（如商品ID、用户ID）分别限流，LRU 限制跟踪的参数值数量
+type HotspotLimiter struct {
+	config  HotspotLimiterConfig
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	evicted int64
+	logger  *logrus.Logger
+}
+
+// 创建热点参数限流器
+func NewHotspotLimiter(config HotspotLimiterConfig, logger *logrus.Logger) *HotspotLimiter {
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = 10000
+	}
+	if config.Burst <= 0 {
+		config.Burst = int(config.RequestsPerSecond)
+	}
+
+	return &HotspotLimiter{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		logger:  logger,
+	}
+}
+
+// 检查参数值是否允许请求
+func (h *HotspotLimiter) Allow(value string) bool {
+	return h.AllowN(value, 1)
+}
+
+// 检查参数值是否允许 n 个请求
+func (h *HotspotLimiter) AllowN(value string, n int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	entry := h.getEntry(value, now)
+
+	// 按秒统计请求数，用于识别热点
+	if now.Sub(entry.secondStart) >= time.Second {
+		if now.Sub(entry.secondStart) < 2*time.Second {
+			entry.lastQPS = entry.secondCount
+		} else {
+			entry.lastQPS = 0
+		}
+		entry.secondStart = now.Truncate(time.Second)
+		entry.secondCount = 0
+	}
+	entry.secondCount += int64(n)
+	entry.total += int64(n)
+	entry.lastSeen = now
+
+	if !entry.limiter.AllowN(n) {
+		entry.rejected += int64(n)
+		return false
+	}
+	return true
+}
+
+// 获取或创建参数值的限流状态，并移到 LRU 头部
+func (h *HotspotLimiter) getEntry(value string, now time.Time) *hotspotEntry {
+	if element, exists := h.entries[value]; exists {
+		h.lru.MoveToFront(element)
+		return element.Value.(*hotspotEntry)
+	}
+
+	rate, burst := h.config.RequestsPerSecond, h.config.Burst
+	if override, ok := h.config.Overrides[value]; ok {
+		rate, burst = override.RequestsPerSecond, override.Burst
+		if burst <= 0 {
+			burst = int(rate)
+		}
+	}
+
+	entry := &hotspotEntry{
+		value:       value,
+		limiter:     NewTokenBucketLimiter(rate, burst, h.logger),
+		secondStart: now.Truncate(time.Second),
+	}
+	h.entries[value] = h.lru.PushFront(entry)
+
+	// 淘汰最久未访问的参数值
+	for h.lru.Len() > h.config.MaxKeys {
+		oldest := h.lru.Back()
+		h.lru.Remove(oldest)
+		delete(h.entries, oldest.Value.(*hotspotEntry).value)
+		h.evicted++
+	}
+
+	return entry
+}
+
+// 获取请求量最高的 topN 个参数值
+func (h *HotspotLimiter) HotKeys(topN int) []HotKey {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	keys := make([]HotKey, 0, len(h.entries))
+	for _, element := range h.entries {
+		entry := element.Value.(*hotspotEntry)
+
+		// 取上一秒与当前秒中较大的请求数
+		var qps int64
+		switch elapsed := now.Sub(entry.secondStart); {
+		case elapsed < time.Second:
+			qps = max(entry.lastQPS, entry.secondCount)
+		case elapsed < 2*time.Second:
+			qps = entry.secondCount
+		}
+
+		keys = append(keys, HotKey{
+			Value:    entry.value,
+			QPS:      qps,
+			Total:    entry.total,
+			Rejected: entry.rejected,
+			LastSeen: entry.lastSeen,
+		})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].QPS != keys[j].QPS {
+			return keys[i].QPS > keys[j].QPS
+		}
+		return keys[i].Total > keys[j].Total
+	})
+
+	if topN > 0 && len(keys) > topN {
+		keys = keys[:topN]
+	}
+	return keys
+}
+
+// 获取参数名
+func (h *HotspotLimiter) Name() string {
+	return h.config.Name
+}
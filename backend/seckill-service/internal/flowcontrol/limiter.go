@@ -17,6 +17,11 @@ type Limiter interface {
 	WaitN(ctx context.Context, n int) error
 }
 
+// 需归还配额的限流器（如并发限流器）
+type Releaser interface {
+	Release(n int)
+}
+
 // 令牌桶限流器
 type TokenBucketLimiter struct {
 	rate       float64   // 令牌生成速率（每秒）
@@ -291,62 +296,6 @@ func (l *FixedWindowLimiter) GetCurrentCount() int {
 	return l.count
 }
 
-// 分布式限流器（基于 Redis）
-type DistributedLimiter struct {
-	key         string
-	limit       int
-	window      time.Duration
-	redisClient interface{} // Redis 客户端接口
-	logger      *logrus.Logger
-}
-
-// 创建分布式限流器
-func NewDistributedLimiter(key string, limit int, window time.Duration, redisClient interface{}, logger *logrus.Logger) *DistributedLimiter {
-	return &DistributedLimiter{
-		key:         key,
-		limit:       limit,
-		window:      window,
-		redisClient: redisClient,
-		logger:      logger,
-	}
-}
-
-// 检查是否允许请求
-func (l *DistributedLimiter) Allow() bool {
-	return l.AllowN(1)
-}
-
-// 检查是否允许 n 个请求
-func (l *DistributedLimiter) AllowN(n int) bool {
-	// 这里需要实现基于 Redis 的分布式限流逻辑
-	// 使用 Redis 的 INCR 和 EXPIRE 命令
-	// 或者使用 Lua 脚本实现更复杂的逻辑
-
-	// 简化实现，实际应该使用 Redis
-	return true
-}
-
-// 等待直到可以处理请求
-func (l *DistributedLimiter) Wait(ctx context.Context) error {
-	return l.WaitN(ctx, 1)
-}
-
-// 等待直到可以处理 n 个请求
-func (l *DistributedLimiter) WaitN(ctx context.Context, n int) error {
-	for {
-		if l.AllowN(n) {
-			return nil
-		}
-
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(100 * time.Millisecond):
-			continue
-		}
-	}
-}
-
 // 多级限流器
 type MultiLevelLimiter struct {
 	limiters []Limiter
@@ -368,8 +317,9 @@ func (l *MultiLevelLimiter) Allow() bool {
 
 // 检查是否允许 n 个请求
 func (l *MultiLevelLimiter) AllowN(n int) bool {
-	for _, limiter := range l.limiters {
+	for i, limiter := range l.limiters {
 		if !limiter.AllowN(n) {
+			l.release(i, n)
 			return false
 		}
 	}
@@ -383,10 +333,25 @@ func (l *MultiLevelLimiter) Wait(ctx context.Context) error {
 
 // 等待直到可以处理 n 个请求
 func (l *MultiLevelLimiter) WaitN(ctx context.Context, n int) error {
-	for _, limiter := range l.limiters {
+	for i, limiter := range l.limiters {
 		if err := limiter.WaitN(ctx, n); err != nil {
+			l.release(i, n)
 			return err
 		}
 	}
 	return nil
 }
+
+// 后续级别拒绝时，归还前 count 级已获取的并发配额
+func (l *MultiLevelLimiter) release(count, n int) {
+	for _, limiter := range l.limiters[:count] {
+		if releaser, ok := limiter.(Releaser); ok {
+			releaser.Release(n)
+		}
+	}
+}
+
+// 获取各级限流器
+func (l *MultiLevelLimiter) Limiters() []Limiter {
+	return l.limiters
+}
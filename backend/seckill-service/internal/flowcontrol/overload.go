@@ -0,0 +1,190 @@
+package flowcontrol
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 过载检测配置
+type OverloadConfig struct {
+	Threshold        float64       // 负载分数达到该值时开始降级
+	RecoverThreshold float64       // 负载分数低于该值时恢复（滞回，需小于 Threshold）
+	MinDuration      time.Duration // 进入降级后至少保持的时长
+	SampleInterval   time.Duration // 采样间隔
+	MaxGoroutines    int           // 协程数达到该值时该项负载记为 1
+	MaxRedisLatency  time.Duration // Redis 往返延迟达到该值时该项负载记为 1
+}
+
+// 负载来源
+type LoadSources struct {
+	RedisPing  func(ctx context.Context) error // Redis 探测，用于测量往返延迟
+	QueueUsage func() float64                  // 请求队列使用率（0-1）
+}
+
+// 负载快照
+type LoadSnapshot struct {
+	Score        float64
+	CPU          float64
+	Goroutines   int
+	RedisLatency time.Duration
+	QueueUsage   float64
+	Overloaded   bool
+	Since        time.Time // 当前降级状态的开始时间
+	Transitions  int64     // 降级状态切换次数
+}
+
+// 过载检测器
+type OverloadDetector struct {
+	config  OverloadConfig
+	sources LoadSources
+	cpu     *cpuSampler
+	logger  *logrus.Logger
+
+	overloaded int32
+	mutex      sync.RWMutex
+	snapshot   LoadSnapshot
+}
+
+// 创建过载检测器
+func NewOverloadDetector(config OverloadConfig, sources LoadSources, logger *logrus.Logger) *OverloadDetector {
+	if config.Threshold <= 0 {
+		config.Threshold = 0.8
+	}
+	if config.RecoverThreshold <= 0 || config.RecoverThreshold >= config.Threshold {
+		config.RecoverThreshold = config.Threshold * 0.8
+	}
+	if config.SampleInterval <= 0 {
+		config.SampleInterval = time.Second
+	}
+	if config.MaxGoroutines <= 0 {
+		config.MaxGoroutines = 10000
+	}
+	if config.MaxRedisLatency <= 0 {
+		config.MaxRedisLatency = 100 * time.Millisecond
+	}
+
+	return &OverloadDetector{
+		config:   config,
+		sources:  sources,
+		cpu:      newCPUSampler(),
+		logger:   logger,
+		snapshot: LoadSnapshot{Since: time.Now()},
+	}
+}
+
+// 启动后台采样
+func (d *OverloadDetector) Start(ctx context.Context) {
+	d.logger.Infof("Overload detector started, cpu source: %s, threshold: %.2f/%.2f",
+		d.cpu.source, d.config.Threshold, d.config.RecoverThreshold)
+
+	go func() {
+		ticker := time.NewTicker(d.config.SampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.sample(ctx)
+			}
+		}
+	}()
+}
+
+// 是否处于过载降级状态
+func (d *OverloadDetector) Overloaded() bool {
+	return atomic.LoadInt32(&d.overloaded) == 1
+}
+
+// 获取最近一次负载快照
+func (d *OverloadDetector) Snapshot() LoadSnapshot {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.snapshot
+}
+
+// 采样并更新降级状态
+func (d *OverloadDetector) sample(ctx context.Context) {
+	cpuUsage, err := d.cpu.Sample()
+	if err != nil {
+		d.logger.Debugf("Failed to sample cpu usage: %v", err)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	redisLatency := d.measureRedisLatency(ctx)
+
+	var queueUsage float64
+	if d.sources.QueueUsage != nil {
+		queueUsage = d.sources.QueueUsage()
+	}
+
+	// 取各项中最高的负载：任何一项资源饱和都视为过载
+	score := max(
+		cpuUsage,
+		ratio(float64(goroutines), float64(d.config.MaxGoroutines)),
+		ratio(float64(redisLatency), float64(d.config.MaxRedisLatency)),
+		ratio(queueUsage, 1),
+	)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	overloaded := d.snapshot.Overloaded
+	switch {
+	case !overloaded && score >= d.config.Threshold:
+		overloaded = true
+	case overloaded && score < d.config.RecoverThreshold && now.Sub(d.snapshot.Since) >= d.config.MinDuration:
+		overloaded = false
+	}
+
+	if overloaded != d.snapshot.Overloaded {
+		d.snapshot.Since = now
+		d.snapshot.Transitions++
+		if overloaded {
+			atomic.StoreInt32(&d.overloaded, 1)
+			d.logger.Warnf("System overloaded, shedding requests: score=%.2f, cpu=%.2f, goroutines=%d, redis_latency=%v, queue=%.2f",
+				score, cpuUsage, goroutines, redisLatency, queueUsage)
+		} else {
+			atomic.StoreInt32(&d.overloaded, 0)
+			d.logger.Infof("System load recovered: score=%.2f", score)
+		}
+	}
+
+	d.snapshot.Score = score
+	d.snapshot.CPU = cpuUsage
+	d.snapshot.Goroutines = goroutines
+	d.snapshot.RedisLatency = redisLatency
+	d.snapshot.QueueUsage = queueUsage
+	d.snapshot.Overloaded = overloaded
+}
+
+// 测量 Redis 往返延迟，探测失败按最大延迟计
+func (d *OverloadDetector) measureRedisLatency(ctx context.Context) time.Duration {
+	if d.sources.RedisPing == nil {
+		return 0
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, d.config.MaxRedisLatency)
+	defer cancel()
+
+	start := time.Now()
+	if err := d.sources.RedisPing(pingCtx); err != nil {
+		return d.config.MaxRedisLatency
+	}
+	return time.Since(start)
+}
+
+// 计算使用率，上限为 1
+func ratio(value, limit float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return min(value/limit, 1)
+}
@@ -3,7 +3,9 @@ package flowcontrol
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -34,10 +36,12 @@ type RequestQueue struct {
 	processor  func(ctx context.Context, item *QueueItem) (interface{}, error)
 	mutex      sync.RWMutex
 	closed     bool
+	active     int64 // 已调度但尚未处理完成的请求数
 	logger     *logrus.Logger
 
 	// 统计信息
-	stats QueueStats
+	stats      QueueStats
+	statsMutex sync.RWMutex
 }
 
 // 队列统计信息
@@ -49,7 +53,6 @@ type QueueStats struct {
 	QueuedRequests    int64
 	AverageWaitTime   time.Duration
 	MaxWaitTime       time.Duration
-	mutex             sync.RWMutex
 }
 
 // 创建请求队列
@@ -85,11 +88,13 @@ func (q *RequestQueue) scheduler(ctx context.Context) {
 			q.close()
 			return
 		case item := <-q.queue:
+			atomic.AddInt64(&q.active, 1)
 			select {
 			case q.processing <- item:
 				// 成功发送到处理队列
 			case <-ctx.Done():
 				q.sendError(item, ErrQueueClosed)
+				atomic.AddInt64(&q.active, -1)
 				return
 			}
 		}
@@ -113,6 +118,7 @@ func (q *RequestQueue) worker(ctx context.Context, workerID int) {
 
 // 处理队列项
 func (q *RequestQueue) processItem(ctx context.Context, item *QueueItem, workerID int) {
+	defer atomic.AddInt64(&q.active, -1)
 	startTime := time.Now()
 
 	// 检查超时
@@ -187,17 +193,17 @@ func (q *RequestQueue) Submit(ctx context.Context, id string, request interface{
 	// 尝试加入队列
 	select {
 	case q.queue <- item:
-		q.stats.mutex.Lock()
+		q.statsMutex.Lock()
 		q.stats.TotalRequests++
 		q.stats.QueuedRequests++
-		q.stats.mutex.Unlock()
+		q.statsMutex.Unlock()
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		q.stats.mutex.Lock()
+		q.statsMutex.Lock()
 		q.stats.TotalRequests++
 		q.stats.FailedRequests++
-		q.stats.mutex.Unlock()
+		q.statsMutex.Unlock()
 		return nil, ErrQueueFull
 	}
 
@@ -233,17 +239,17 @@ func (q *RequestQueue) SubmitAsync(ctx context.Context, id string, request inter
 	// 尝试加入队列
 	select {
 	case q.queue <- item:
-		q.stats.mutex.Lock()
+		q.statsMutex.Lock()
 		q.stats.TotalRequests++
 		q.stats.QueuedRequests++
-		q.stats.mutex.Unlock()
+		q.statsMutex.Unlock()
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		q.stats.mutex.Lock()
+		q.statsMutex.Lock()
 		q.stats.TotalRequests++
 		q.stats.FailedRequests++
-		q.stats.mutex.Unlock()
+		q.statsMutex.Unlock()
 		return ErrQueueFull
 	}
 
@@ -264,8 +270,8 @@ func (q *RequestQueue) SubmitAsync(ctx context.Context, id string, request inter
 
 // 更新统计信息
 func (q *RequestQueue) updateStats(success, timeout bool, waitTime time.Duration) {
-	q.stats.mutex.Lock()
-	defer q.stats.mutex.Unlock()
+	q.statsMutex.Lock()
+	defer q.statsMutex.Unlock()
 
 	q.stats.QueuedRequests--
 
@@ -294,8 +300,8 @@ func (q *RequestQueue) updateStats(success, timeout bool, waitTime time.Duration
 
 // 获取统计信息
 func (q *RequestQueue) GetStats() QueueStats {
-	q.stats.mutex.RLock()
-	defer q.stats.mutex.RUnlock()
+	q.statsMutex.RLock()
+	defer q.statsMutex.RUnlock()
 
 	return q.stats
 }
@@ -326,57 +332,249 @@ func (q *RequestQueue) close() {
 	q.logger.Info("Request queue closed")
 }
 
-// 优先级队列项
-type PriorityQueueItem struct {
-	*QueueItem
-	Priority int
+// 优先级配置
+type PriorityLevel struct {
+	Priority int     // 数值越大越优先
+	Share    float64 // 有积压时保证的最低调度占比（0-1），避免低优先级饿死
 }
 
-// 优先级队列
+// 优先级统计信息
+type PriorityStats struct {
+	Priority        int
+	Share           float64
+	Queued          int
+	TotalRequests   int64
+	Dispatched      int64
+	Rejected        int64
+	AverageWaitTime time.Duration
+	MaxWaitTime     time.Duration
+}
+
+// 单个优先级的等待队列
+type priorityBucket struct {
+	level     PriorityLevel
+	items     []*QueueItem
+	stats     PriorityStats
+	totalWait time.Duration
+}
+
+// 计算调度占比的最近调度次数
+const priorityShareWindow = 100
+
+// 优先级队列：高优先级先调度，同时按配置为每个优先级保证最低调度占比
 type PriorityRequestQueue struct {
 	*RequestQueue
-	priorityQueue []*PriorityQueueItem
-	mutex         sync.Mutex
+	buckets     []*priorityBucket // 按优先级从高到低排列
+	recent      []int             // 最近调度的优先级下标（环形缓冲）
+	recentPos   int
+	recentCount []int
+	queued      int
+	notify      chan struct{}
+	mutex       sync.Mutex
 }
 
 // 创建优先级队列
-func NewPriorityRequestQueue(capacity, workers int, processor func(ctx context.Context, item *QueueItem) (interface{}, error), logger *logrus.Logger) *PriorityRequestQueue {
+func NewPriorityRequestQueue(capacity, workers int, levels []PriorityLevel, processor func(ctx context.Context, item *QueueItem) (interface{}, error), logger *logrus.Logger) *PriorityRequestQueue {
+	if len(levels) == 0 {
+		levels = []PriorityLevel{{Priority: 0}}
+	}
+
+	sorted := make([]PriorityLevel, len(levels))
+	copy(sorted, levels)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	buckets := make([]*priorityBucket, len(sorted))
+	for i, level := range sorted {
+		buckets[i] = &priorityBucket{
+			level: level,
+			stats: PriorityStats{Priority: level.Priority, Share: level.Share},
+		}
+	}
+
+	queue := NewRequestQueue(capacity, workers, processor, logger)
+	// 不缓冲，保证调度决策在有空闲工作协程时才做出
+	queue.processing = make(chan *QueueItem)
+
 	return &PriorityRequestQueue{
-		RequestQueue:  NewRequestQueue(capacity, workers, processor, logger),
-		priorityQueue: make([]*PriorityQueueItem, 0),
+		RequestQueue: queue,
+		buckets:      buckets,
+		recent:       make([]int, 0, priorityShareWindow),
+		recentCount:  make([]int, len(buckets)),
+		notify:       make(chan struct{}, 1),
 	}
 }
 
-// 提交优先级请求
-func (pq *PriorityRequestQueue) SubmitWithPriority(ctx context.Context, id string, request interface{}, timeout time.Duration, priority int) (interface{}, error) {
+// 启动队列处理
+func (pq *PriorityRequestQueue) Start(ctx context.Context) {
+	for i := 0; i < pq.workers; i++ {
+		go pq.worker(ctx, i)
+	}
+
+	go pq.scheduler(ctx)
+
+	pq.logger.Infof("Priority request queue started with %d workers, capacity: %d, levels: %d",
+		pq.workers, pq.capacity, len(pq.buckets))
+}
+
+// 调度器
+func (pq *PriorityRequestQueue) scheduler(ctx context.Context) {
+	for {
+		item := pq.next()
+		if item == nil {
+			select {
+			case <-ctx.Done():
+				pq.close()
+				return
+			case <-pq.notify:
+				continue
+			}
+		}
+
+		select {
+		case pq.processing <- item:
+		case <-ctx.Done():
+			pq.sendError(item, ErrQueueClosed)
+			pq.updateStats(false, false, time.Since(item.Timestamp))
+			atomic.AddInt64(&pq.active, -1)
+			pq.close()
+			return
+		}
+	}
+}
+
+// 取出下一个待处理项：优先满足保证占比，其余按优先级从高到低
+func (pq *PriorityRequestQueue) next() *QueueItem {
 	pq.mutex.Lock()
-	if pq.closed {
-		pq.mutex.Unlock()
-		return nil, ErrQueueClosed
+	defer pq.mutex.Unlock()
+
+	chosen := -1
+	var maxDeficit float64
+	window := len(pq.recent)
+	for i, bucket := range pq.buckets {
+		if len(bucket.items) == 0 || bucket.level.Share <= 0 {
+			continue
+		}
+		actual := 0.0
+		if window > 0 {
+			actual = float64(pq.recentCount[i]) / float64(window)
+		}
+		if deficit := bucket.level.Share - actual; deficit > maxDeficit {
+			chosen, maxDeficit = i, deficit
+		}
 	}
 
-	item := &QueueItem{
-		ID:        id,
-		Request:   request,
-		Response:  make(chan interface{}, 1),
-		Error:     make(chan error, 1),
-		Timestamp: time.Now(),
-		Timeout:   timeout,
+	if chosen < 0 {
+		for i, bucket := range pq.buckets {
+			if len(bucket.items) > 0 {
+				chosen = i
+				break
+			}
+		}
+	}
+	if chosen < 0 {
+		return nil
 	}
 
-	priorityItem := &PriorityQueueItem{
-		QueueItem: item,
-		Priority:  priority,
+	bucket := pq.buckets[chosen]
+	item := bucket.items[0]
+	bucket.items[0] = nil
+	bucket.items = bucket.items[1:]
+	pq.queued--
+	atomic.AddInt64(&pq.active, 1)
+
+	// 记录调度历史
+	if len(pq.recent) < priorityShareWindow {
+		pq.recent = append(pq.recent, chosen)
+	} else {
+		pq.recentCount[pq.recent[pq.recentPos]]--
+		pq.recent[pq.recentPos] = chosen
+		pq.recentPos = (pq.recentPos + 1) % priorityShareWindow
 	}
+	pq.recentCount[chosen]++
 
-	// 按优先级插入
-	pq.insertByPriority(priorityItem)
-	pq.mutex.Unlock()
+	waitTime := time.Since(item.Timestamp)
+	bucket.stats.Dispatched++
+	bucket.totalWait += waitTime
+	if waitTime > bucket.stats.MaxWaitTime {
+		bucket.stats.MaxWaitTime = waitTime
+	}
+
+	return item
+}
 
-	pq.stats.mutex.Lock()
+// 按优先级加入队列
+func (pq *PriorityRequestQueue) enqueue(item *QueueItem, priority int) error {
+	pq.mutex.Lock()
+	defer pq.mutex.Unlock()
+
+	bucket := pq.bucketFor(priority)
+	bucket.stats.TotalRequests++
+
+	pq.RequestQueue.mutex.RLock()
+	closed := pq.closed
+	pq.RequestQueue.mutex.RUnlock()
+	if closed {
+		bucket.stats.Rejected++
+		return ErrQueueClosed
+	}
+
+	if pq.queued >= pq.capacity {
+		bucket.stats.Rejected++
+		pq.statsMutex.Lock()
+		pq.stats.TotalRequests++
+		pq.stats.FailedRequests++
+		pq.statsMutex.Unlock()
+		return ErrQueueFull
+	}
+
+	bucket.items = append(bucket.items, item)
+	pq.queued++
+
+	pq.statsMutex.Lock()
 	pq.stats.TotalRequests++
 	pq.stats.QueuedRequests++
-	pq.stats.mutex.Unlock()
+	pq.statsMutex.Unlock()
+
+	select {
+	case pq.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// 查找优先级对应的队列：取不高于该优先级的最高一级，均高于时取最低一级
+func (pq *PriorityRequestQueue) bucketFor(priority int) *priorityBucket {
+	for _, bucket := range pq.buckets {
+		if bucket.level.Priority <= priority {
+			return bucket
+		}
+	}
+	return pq.buckets[len(pq.buckets)-1]
+}
+
+// 最低优先级
+func (pq *PriorityRequestQueue) LowestPriority() int {
+	return pq.buckets[len(pq.buckets)-1].level.Priority
+}
+
+// 提交请求（最低优先级）
+func (pq *PriorityRequestQueue) Submit(ctx context.Context, id string, request interface{}, timeout time.Duration) (interface{}, error) {
+	return pq.SubmitWithPriority(ctx, id, request, timeout, pq.LowestPriority())
+}
+
+// 异步提交请求（最低优先级）
+func (pq *PriorityRequestQueue) SubmitAsync(ctx context.Context, id string, request interface{}, timeout time.Duration, callback func(interface{}, error)) error {
+	return pq.SubmitAsyncWithPriority(ctx, id, request, timeout, pq.LowestPriority(), callback)
+}
+
+// 提交优先级请求
+func (pq *PriorityRequestQueue) SubmitWithPriority(ctx context.Context, id string, request interface{}, timeout time.Duration, priority int) (interface{}, error) {
+	item := newQueueItem(id, request, timeout)
+	if err := pq.enqueue(item, priority); err != nil {
+		return nil, err
+	}
 
 	// 等待响应
 	select {
@@ -389,33 +587,141 @@ func (pq *PriorityRequestQueue) SubmitWithPriority(ctx context.Context, id strin
 	}
 }
 
-// 按优先级插入
-func (pq *PriorityRequestQueue) insertByPriority(item *PriorityQueueItem) {
-	// 简单的插入排序，优先级高的在前面
-	inserted := false
-	for i, existing := range pq.priorityQueue {
-		if item.Priority > existing.Priority {
-			pq.priorityQueue = append(pq.priorityQueue[:i], append([]*PriorityQueueItem{item}, pq.priorityQueue[i:]...)...)
-			inserted = true
+// 异步提交优先级请求
+func (pq *PriorityRequestQueue) SubmitAsyncWithPriority(ctx context.Context, id string, request interface{}, timeout time.Duration, priority int, callback func(interface{}, error)) error {
+	item := newQueueItem(id, request, timeout)
+	if err := pq.enqueue(item, priority); err != nil {
+		return err
+	}
+
+	// 异步处理响应
+	go func() {
+		select {
+		case result := <-item.Response:
+			callback(result, nil)
+		case err := <-item.Error:
+			callback(nil, err)
+		case <-ctx.Done():
+			callback(nil, ctx.Err())
+		}
+	}()
+
+	return nil
+}
+
+// 获取队列长度
+func (pq *PriorityRequestQueue) QueueLength() int {
+	pq.mutex.Lock()
+	defer pq.mutex.Unlock()
+	return pq.queued
+}
+
+// 获取指定优先级前方排队的请求数（不含保证占比带来的插队）
+func (pq *PriorityRequestQueue) QueueLengthAhead(priority int) int {
+	pq.mutex.Lock()
+	defer pq.mutex.Unlock()
+
+	target := pq.bucketFor(priority)
+	ahead := 0
+	for _, bucket := range pq.buckets {
+		ahead += len(bucket.items)
+		if bucket == target {
 			break
 		}
 	}
+	return ahead
+}
+
+// 获取各优先级统计信息（按优先级从高到低）
+func (pq *PriorityRequestQueue) GetPriorityStats() []PriorityStats {
+	pq.mutex.Lock()
+	defer pq.mutex.Unlock()
+
+	stats := make([]PriorityStats, len(pq.buckets))
+	for i, bucket := range pq.buckets {
+		stats[i] = bucket.stats
+		stats[i].Queued = len(bucket.items)
+		if bucket.stats.Dispatched > 0 {
+			stats[i].AverageWaitTime = bucket.totalWait / time.Duration(bucket.stats.Dispatched)
+		}
+	}
+	return stats
+}
 
-	if !inserted {
-		pq.priorityQueue = append(pq.priorityQueue, item)
+// 关闭队列，拒绝所有未调度的请求
+func (pq *PriorityRequestQueue) close() {
+	if !pq.markClosed() {
+		return
 	}
+
+	rejected := pq.rejectPending()
+	pq.logger.Infof("Priority request queue closed, rejected %d pending requests", rejected)
 }
 
-// 获取下一个优先级项
-func (pq *PriorityRequestQueue) getNextPriorityItem() *QueueItem {
+// 停止接收新请求，返回 false 表示队列已关闭
+func (pq *PriorityRequestQueue) markClosed() bool {
+	pq.RequestQueue.mutex.Lock()
+	defer pq.RequestQueue.mutex.Unlock()
+
+	if pq.closed {
+		return false
+	}
+	pq.closed = true
+	return true
+}
+
+// 拒绝所有未调度的请求，返回拒绝数量
+func (pq *PriorityRequestQueue) rejectPending() int {
 	pq.mutex.Lock()
-	defer pq.mutex.Unlock()
+	var pending []*QueueItem
+	for _, bucket := range pq.buckets {
+		pending = append(pending, bucket.items...)
+		bucket.stats.Rejected += int64(len(bucket.items))
+		bucket.items = nil
+	}
+	pq.queued = 0
+	pq.mutex.Unlock()
 
-	if len(pq.priorityQueue) == 0 {
-		return nil
+	for _, item := range pending {
+		pq.sendError(item, ErrQueueClosed)
+		pq.updateStats(false, false, time.Since(item.Timestamp))
 	}
+	return len(pending)
+}
+
+// 排空队列：停止接收新请求，工作协程继续处理已排队的请求，直到队列为空且没有处理中的请求；
+// ctx 结束时拒绝尚未调度的请求，处理中的请求不受影响。返回被拒绝的请求数
+func (pq *PriorityRequestQueue) Drain(ctx context.Context) int {
+	pq.markClosed()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if pq.QueueLength() == 0 && atomic.LoadInt64(&pq.active) == 0 {
+			pq.logger.Info("Priority request queue drained")
+			return 0
+		}
 
-	item := pq.priorityQueue[0]
-	pq.priorityQueue = pq.priorityQueue[1:]
-	return item.QueueItem
+		select {
+		case <-ctx.Done():
+			rejected := pq.rejectPending()
+			pq.logger.Warnf("Priority request queue drain timed out, rejected %d pending requests, %d dispatched requests still processing",
+				rejected, atomic.LoadInt64(&pq.active))
+			return rejected
+		case <-ticker.C:
+		}
+	}
+}
+
+// 创建队列项
+func newQueueItem(id string, request interface{}, timeout time.Duration) *QueueItem {
+	return &QueueItem{
+		ID:        id,
+		Request:   request,
+		Response:  make(chan interface{}, 1),
+		Error:     make(chan error, 1),
+		Timestamp: time.Now(),
+		Timeout:   timeout,
+	}
 }
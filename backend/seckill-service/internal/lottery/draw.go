@@ -0,0 +1,48 @@
+package lottery
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 计算参与者名单哈希：用户ID升序排列后以换行连接，取 SHA-256
+func ParticipantsHash(participants []int64) string {
+	sorted := sortedCopy(participants)
+
+	ids := make([]string, len(sorted))
+	for i, userID := range sorted {
+		ids[i] = strconv.FormatInt(userID, 10)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(ids, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// 可复现的抽签：参与者按用户ID升序排列后做 Fisher-Yates 洗牌，
+// 第 i 步的随机数取 SHA-256("种子:名单哈希:i") 的前 8 字节，
+// 任何人持有种子与参与者名单都能独立验证结果。
+// 返回中签者与候补名单（候补按洗牌顺序排列）。
+func Draw(participants []int64, seed string, winners int) ([]int64, []int64) {
+	shuffled := sortedCopy(participants)
+	hash := ParticipantsHash(shuffled)
+
+	for i := len(shuffled) - 1; i > 0; i-- {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", seed, hash, i)))
+		j := binary.BigEndian.Uint64(sum[:8]) % uint64(i+1)
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+
+	winners = min(max(winners, 0), len(shuffled))
+	return shuffled[:winners], shuffled[winners:]
+}
+
+func sortedCopy(participants []int64) []int64 {
+	sorted := make([]int64, len(participants))
+	copy(sorted, participants)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
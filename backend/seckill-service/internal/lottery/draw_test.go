@@ -0,0 +1,125 @@
+package lottery
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParticipantsHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     []int64
+		b     []int64
+		equal bool
+	}{
+		{name: "same order", a: []int64{1, 2, 3}, b: []int64{1, 2, 3}, equal: true},
+		{name: "different order", a: []int64{3, 1, 2}, b: []int64{2, 3, 1}, equal: true},
+		{name: "different members", a: []int64{1, 2, 3}, b: []int64{1, 2, 4}, equal: false},
+		{name: "missing member", a: []int64{1, 2, 3}, b: []int64{1, 2}, equal: false},
+		{name: "digits not concatenated", a: []int64{1, 23}, b: []int64{12, 3}, equal: false},
+		{name: "empty", a: nil, b: []int64{}, equal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := ParticipantsHash(tt.a), ParticipantsHash(tt.b)
+			if (a == b) != tt.equal {
+				t.Errorf("ParticipantsHash(%v) = %s, ParticipantsHash(%v) = %s, equal = %v, want %v",
+					tt.a, a, tt.b, b, a == b, tt.equal)
+			}
+		})
+	}
+}
+
+func TestParticipantsHashDoesNotModifyInput(t *testing.T) {
+	participants := []int64{3, 1, 2}
+	ParticipantsHash(participants)
+	if !reflect.DeepEqual(participants, []int64{3, 1, 2}) {
+		t.Errorf("ParticipantsHash modified input: %v", participants)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	participants := make([]int64, 200)
+	for i := range participants {
+		participants[i] = int64(1000 + i*7)
+	}
+
+	tests := []struct {
+		name         string
+		participants []int64
+		seed         string
+		winners      int
+		wantWinners  int
+	}{
+		{name: "fewer winners than participants", participants: participants, seed: "seed-a", winners: 10, wantWinners: 10},
+		{name: "all win", participants: participants, seed: "seed-a", winners: 500, wantWinners: 200},
+		{name: "no winners", participants: participants, seed: "seed-a", winners: 0, wantWinners: 0},
+		{name: "negative winners", participants: participants, seed: "seed-a", winners: -1, wantWinners: 0},
+		{name: "no participants", participants: nil, seed: "seed-a", winners: 3, wantWinners: 0},
+		{name: "single participant", participants: []int64{42}, seed: "seed-b", winners: 1, wantWinners: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			winners, waitlist := Draw(tt.participants, tt.seed, tt.winners)
+			if len(winners) != tt.wantWinners {
+				t.Fatalf("len(winners) = %d, want %d", len(winners), tt.wantWinners)
+			}
+			if len(winners)+len(waitlist) != len(tt.participants) {
+				t.Fatalf("len(winners)+len(waitlist) = %d, want %d", len(winners)+len(waitlist), len(tt.participants))
+			}
+
+			// 中签与候补合起来恰好是参与者名单
+			all := append(append([]int64{}, winners...), waitlist...)
+			sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+			if want := sortedCopy(tt.participants); !reflect.DeepEqual(all, want) {
+				t.Fatalf("winners and waitlist = %v, want permutation of %v", all, want)
+			}
+
+			// 相同种子与名单（与登记顺序无关）复现相同结果
+			reversed := make([]int64, len(tt.participants))
+			for i, userID := range tt.participants {
+				reversed[len(reversed)-1-i] = userID
+			}
+			againWinners, againWaitlist := Draw(reversed, tt.seed, tt.winners)
+			if !reflect.DeepEqual(winners, againWinners) || !reflect.DeepEqual(waitlist, againWaitlist) {
+				t.Errorf("Draw not reproducible: winners %v / %v, waitlist %v / %v",
+					winners, againWinners, waitlist, againWaitlist)
+			}
+		})
+	}
+}
+
+func TestDrawSeedChangesOrder(t *testing.T) {
+	participants := make([]int64, 100)
+	for i := range participants {
+		participants[i] = int64(i + 1)
+	}
+
+	a, _ := Draw(participants, "seed-a", len(participants))
+	b, _ := Draw(participants, "seed-b", len(participants))
+	if reflect.DeepEqual(a, b) {
+		t.Errorf("Draw with different seeds produced the same order")
+	}
+}
+
+// 固定种子与名单的结果，防止抽签算法改动后历史开奖无法复现
+var (
+	knownParticipantsHash = "b5584a1464119498204d2bffb86944984de652acb277cb0d2a6810944ee13350" // sha256("1\n2\n3\n4\n5")
+	knownWinners          = []int64{1, 3}
+	knownWaitlist         = []int64{4, 5, 2}
+)
+
+func TestDrawKnownResult(t *testing.T) {
+	participants := []int64{5, 4, 3, 2, 1}
+	winners, waitlist := Draw(participants, "fixed-seed", 2)
+
+	if got := ParticipantsHash(participants); got != knownParticipantsHash {
+		t.Errorf("ParticipantsHash = %s, want %s", got, knownParticipantsHash)
+	}
+	if !reflect.DeepEqual(winners, knownWinners) || !reflect.DeepEqual(waitlist, knownWaitlist) {
+		t.Errorf("Draw = %v, %v, want %v, %v", winners, waitlist, knownWinners, knownWaitlist)
+	}
+}
@@ -0,0 +1,574 @@
+package lottery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"seckill-service/internal/seckill"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrAlreadyDrawn = errors.New("lottery already drawn")
+	ErrNotDrawn     = errors.New("lottery not drawn yet")
+	ErrNotLottery   = errors.New("activity is not a lottery")
+	ErrEntryClosed  = errors.New("lottery entry window is closed")
+	ErrEntryOpen    = errors.New("lottery entry window is still open")
+	ErrDrawing      = errors.New("lottery draw in progress")
+)
+
+// 开奖时每批写入的用户数，避免单个脚本长时间阻塞 Redis
+const drawChunkSize = 1000
+
+// 开奖锁的过期时间，持有者异常退出后其他节点可重新开奖
+const drawLockTTL = time.Minute
+
+// 参与状态
+type EntryState string
+
+const (
+	EntryNotRegistered EntryState = "not_registered"
+	EntryRegistered    EntryState = "registered" // 已登记，等待开奖
+	EntryWon           EntryState = "won"        // 中签，需在截止时间前购买
+	EntryClaimed       EntryState = "claimed"    // 已购买
+	EntryWaitlisted    EntryState = "waitlisted" // 候补中
+	EntryExpired       EntryState = "expired"    // 购买资格已过期
+)
+
+// 开奖记录，用于纠纷核查：持有种子与参与者名单即可用 Draw 复现中签与候补名单
+type DrawRecord struct {
+	ProductID        int64     `json:"product_id"`
+	Seed             string    `json:"seed"`
+	ParticipantsHash string    `json:"participants_hash"`
+	Participants     int       `json:"participants"`
+	Winners          int       `json:"winners"`  // 中签人数
+	Waitlist         int       `json:"waitlist"` // 候补人数
+	DrawnAt          time.Time `json:"drawn_at"`
+	ClaimDeadline    time.Time `json:"claim_deadline"`
+}
+
+// 开奖记录与开奖时的参与者名单（升序，即计算名单哈希的顺序）、中签名单（按中签顺序）
+type DrawDetail struct {
+	DrawRecord
+	ParticipantIDs []int64 `json:"participant_ids"`
+	WinnerIDs      []int64 `json:"winner_ids"`
+}
+
+// 用户参与情况
+type Entry struct {
+	ProductID        int64      `json:"product_id"`
+	UserID           int64      `json:"user_id"`
+	State            EntryState `json:"state"`
+	ClaimDeadline    *time.Time `json:"claim_deadline,omitempty"`
+	WaitlistPosition int64      `json:"waitlist_position,omitempty"` // 候补顺位，从 1 开始
+}
+
+// 登记参与，已开奖时拒绝
+// KEYS[1]: 参与者 ZSET（分数为登记时间）
+// KEYS[2]: 开奖记录
+// 返回 1 新登记 / 0 已登记 / -1 已开奖
+const registerLuaScript = `
+if redis.call('EXISTS', KEYS[2]) == 1 then
+    return -1
+end
+return redis.call('ZADD', KEYS[1], 'NX', ARGV[2], ARGV[1])
+`
+
+// 分批写入待生效的购买资格或候补，仍持有开奖锁时才写入
+// KEYS[1]: 开奖锁
+// KEYS[2]: 待生效的购买资格 ZSET 或候补队列
+// ARGV[1]: 开奖锁持有者
+// ARGV[2]: 购买截止时间（毫秒），为空时写入候补队列
+// ARGV[3..]: 用户ID
+// 返回 1 成功 / 0 开奖锁已丢失
+const stageLuaScript = `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+    return 0
+end
+
+if ARGV[2] ~= '' then
+    for i = 3, #ARGV do
+        redis.call('ZADD', KEYS[2], ARGV[2], ARGV[i])
+    end
+else
+    redis.call('RPUSH', KEYS[2], unpack(ARGV, 3))
+end
+return 1
+`
+
+// 提交开奖：写入开奖记录，待生效的购买资格、候补与开奖名单改名为正式 key，同一活动只能开奖一次
+// KEYS[1]: 开奖记录
+// KEYS[2]: 开奖锁
+// KEYS[3]: 待生效的购买资格 ZSET
+// KEYS[4]: 购买资格 ZSET（分数为截止时间）
+// KEYS[5]: 待生效的候补队列
+// KEYS[6]: 候补队列
+// KEYS[7]: 待生效的开奖参与者名单
+// KEYS[8]: 开奖参与者名单
+// KEYS[9]: 待生效的中签名单
+// KEYS[10]: 中签名单
+// ARGV[1]: 开奖锁持有者
+// ARGV[2]: 开奖记录 JSON
+// 返回 1 成功 / 0 已开奖 / -1 开奖锁已丢失
+const commitDrawLuaScript = `
+if redis.call('GET', KEYS[2]) ~= ARGV[1] then
+    return -1
+end
+redis.call('DEL', KEYS[2])
+
+if not redis.call('SET', KEYS[1], ARGV[2], 'NX') then
+    redis.call('DEL', KEYS[3], KEYS[5], KEYS[7], KEYS[9])
+    return 0
+end
+
+for i = 3, 9, 2 do
+    if redis.call('EXISTS', KEYS[i]) == 1 then
+        redis.call('RENAME', KEYS[i], KEYS[i + 1])
+    end
+end
+return 1
+`
+
+// 释放开奖锁并删除未提交的开奖数据
+// KEYS[1]: 开奖锁
+// KEYS[2..]: 待生效的购买资格与候补
+// ARGV[1]: 开奖锁持有者
+const abortDrawLuaScript = `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+    return 0
+end
+redis.call('DEL', unpack(KEYS))
+return 1
+`
+
+// 回收过期未使用的购买资格，转给候补用户
+// KEYS[1]: 购买资格 ZSET
+// KEYS[2]: 候补队列
+// KEYS[3]: 已购买用户集合
+// ARGV[1]: 当前时间（毫秒）
+// ARGV[2]: 候补用户的购买截止时间（毫秒）
+// ARGV[3]: 单次处理上限
+// 返回转给候补的资格数
+const promoteLuaScript = `
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[3]))
+local promoted = 0
+for _, user_id in ipairs(expired) do
+    redis.call('ZREM', KEYS[1], user_id)
+    if redis.call('SISMEMBER', KEYS[3], user_id) == 0 then
+        local next_user = redis.call('LPOP', KEYS[2])
+        if next_user then
+            redis.call('ZADD', KEYS[1], ARGV[2], next_user)
+            promoted = promoted + 1
+        end
+    end
+end
+return promoted
+`
+
+var (
+	registerScript   = redis.NewScript(registerLuaScript)
+	stageScript      = redis.NewScript(stageLuaScript)
+	commitDrawScript = redis.NewScript(commitDrawLuaScript)
+	abortDrawScript  = redis.NewScript(abortDrawLuaScript)
+	promoteScript    = redis.NewScript(promoteLuaScript)
+)
+
+// 抽签活动：登记期内报名，开奖后中签者在截止时间前获得购买资格，逾期资格依次转给候补
+type Lottery struct {
+	client redis.Cmdable
+	logger *logrus.Logger
+}
+
+// 创建抽签活动管理
+func NewLottery(client redis.Cmdable, logger *logrus.Logger) *Lottery {
+	return &Lottery{
+		client: client,
+		logger: logger,
+	}
+}
+
+// 与秒杀活动的 key 使用相同的 {商品ID} hash tag，资格回收脚本需要同时访问已购买用户集合
+func participantsKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:participants:{%d}", productID)
+}
+
+func drawKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:draw:{%d}", productID)
+}
+
+func rightsKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:rights:{%d}", productID)
+}
+
+func waitlistKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:waitlist:{%d}", productID)
+}
+
+// 开奖时的参与者名单与中签名单，清理活动时与开奖记录一起保留
+func drawParticipantsKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:draw:participants:{%d}", productID)
+}
+
+func drawWinnersKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:draw:winners:{%d}", productID)
+}
+
+func drawLockKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:drawing:{%d}", productID)
+}
+
+// 开奖提交前写入的购买资格与候补，提交时改名为正式 key
+func stagedRightsKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:rights:staged:{%d}", productID)
+}
+
+func stagedWaitlistKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:waitlist:staged:{%d}", productID)
+}
+
+func stagedDrawParticipantsKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:draw:participants:staged:{%d}", productID)
+}
+
+func stagedDrawWinnersKey(productID int64) string {
+	return fmt.Sprintf("seckill:lottery:draw:winners:staged:{%d}", productID)
+}
+
+// 开奖提交前写入的全部 key
+func stagedKeys(productID int64) []string {
+	return []string{
+		stagedRightsKey(productID), stagedWaitlistKey(productID),
+		stagedDrawParticipantsKey(productID), stagedDrawWinnersKey(productID),
+	}
+}
+
+// 登记参与抽签，重复登记不改变登记时间
+func (l *Lottery) Register(ctx context.Context, productID, userID int64) error {
+	result, err := registerScript.Run(ctx, l.client,
+		[]string{participantsKey(productID), drawKey(productID)},
+		userID, time.Now().UnixMilli(),
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to register lottery entry: %w", err)
+	}
+	if result < 0 {
+		return ErrAlreadyDrawn
+	}
+	return nil
+}
+
+// 开奖：种子随机生成，不接受外部指定，中签人数不超过 stock。
+// 持有开奖锁期间分批写入待生效的购买资格、候补与开奖名单，最后一次写入开奖记录并改名生效，
+// 写入过程中失败时已写入的数据不会生效，开奖锁释放后可重新开奖
+func (l *Lottery) Draw(ctx context.Context, productID, stock int64, claimWindow time.Duration) (*DrawRecord, error) {
+	if drawn, err := l.Drawn(ctx, productID); err != nil {
+		return nil, err
+	} else if drawn {
+		return nil, ErrAlreadyDrawn
+	}
+
+	seed, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate lottery seed: %w", err)
+	}
+	owner, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate lottery lock owner: %w", err)
+	}
+
+	locked, err := l.client.SetNX(ctx, drawLockKey(productID), owner, drawLockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock lottery draw: %w", err)
+	}
+	if !locked {
+		return nil, ErrDrawing
+	}
+
+	record, err := l.draw(ctx, productID, stock, seed, owner, claimWindow)
+	if err != nil {
+		abortErr := abortDrawScript.Run(ctx, l.client,
+			append([]string{drawLockKey(productID)}, stagedKeys(productID)...),
+			owner,
+		).Err()
+		if abortErr != nil {
+			l.logger.Warnf("Failed to abort lottery draw for product %d: %v", productID, abortErr)
+		}
+		return nil, err
+	}
+
+	l.logger.Infof("Lottery drawn for product %d: seed=%s, participants=%d, participants_hash=%s, winners=%d, waitlist=%d",
+		productID, seed, record.Participants, record.ParticipantsHash, record.Winners, record.Waitlist)
+	return record, nil
+}
+
+func (l *Lottery) draw(ctx context.Context, productID, stock int64, seed, owner string, claimWindow time.Duration) (*DrawRecord, error) {
+	// 清除上次中断的开奖留下的数据
+	if err := l.client.Del(ctx, stagedKeys(productID)...).Err(); err != nil {
+		return nil, fmt.Errorf("failed to reset lottery draw: %w", err)
+	}
+
+	members, err := l.client.ZRange(ctx, participantsKey(productID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lottery participants: %w", err)
+	}
+	participants := make([]int64, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		participants = append(participants, userID)
+	}
+
+	winners, waitlist := Draw(participants, seed, int(stock))
+	now := time.Now()
+	record := &DrawRecord{
+		ProductID:        productID,
+		Seed:             seed,
+		ParticipantsHash: ParticipantsHash(participants),
+		Participants:     len(participants),
+		Winners:          len(winners),
+		Waitlist:         len(waitlist),
+		DrawnAt:          now,
+		ClaimDeadline:    now.Add(claimWindow),
+	}
+
+	if err := l.stage(ctx, productID, owner, stagedRightsKey(productID), record.ClaimDeadline.UnixMilli(), winners); err != nil {
+		return nil, err
+	}
+	if err := l.stage(ctx, productID, owner, stagedWaitlistKey(productID), "", waitlist); err != nil {
+		return nil, err
+	}
+	if err := l.stage(ctx, productID, owner, stagedDrawParticipantsKey(productID), "", sortedCopy(participants)); err != nil {
+		return nil, err
+	}
+	if err := l.stage(ctx, productID, owner, stagedDrawWinnersKey(productID), "", winners); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal draw record: %w", err)
+	}
+
+	saved, err := commitDrawScript.Run(ctx, l.client,
+		[]string{
+			drawKey(productID), drawLockKey(productID),
+			stagedRightsKey(productID), rightsKey(productID),
+			stagedWaitlistKey(productID), waitlistKey(productID),
+			stagedDrawParticipantsKey(productID), drawParticipantsKey(productID),
+			stagedDrawWinnersKey(productID), drawWinnersKey(productID),
+		},
+		owner, string(data),
+	).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save lottery draw: %w", err)
+	}
+	switch saved {
+	case 0:
+		return nil, ErrAlreadyDrawn
+	case -1:
+		return nil, ErrDrawing
+	}
+	return record, nil
+}
+
+// 分批写入待生效的用户，deadline 为空时按顺序写入列表（候补队列与开奖名单）
+func (l *Lottery) stage(ctx context.Context, productID int64, owner, key string, deadline interface{}, users []int64) error {
+	for start := 0; start < len(users); start += drawChunkSize {
+		chunk := users[start:min(start+drawChunkSize, len(users))]
+		args := make([]interface{}, 0, 2+len(chunk))
+		args = append(args, owner, deadline)
+		for _, userID := range chunk {
+			args = append(args, userID)
+		}
+
+		staged, err := stageScript.Run(ctx, l.client, []string{drawLockKey(productID), key}, args...).Int64()
+		if err != nil {
+			return fmt.Errorf("failed to stage lottery draw: %w", err)
+		}
+		if staged == 0 {
+			return ErrDrawing
+		}
+	}
+	return nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// 获取开奖记录
+func (l *Lottery) GetDraw(ctx context.Context, productID int64) (*DrawRecord, error) {
+	data, err := l.client.Get(ctx, drawKey(productID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrNotDrawn
+		}
+		return nil, fmt.Errorf("failed to get lottery draw: %w", err)
+	}
+
+	var record DrawRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal draw record: %w", err)
+	}
+	return &record, nil
+}
+
+// 获取开奖记录与开奖名单
+func (l *Lottery) GetDrawDetail(ctx context.Context, productID int64) (*DrawDetail, error) {
+	record, err := l.GetDraw(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := l.userList(ctx, drawParticipantsKey(productID))
+	if err != nil {
+		return nil, err
+	}
+	winners, err := l.userList(ctx, drawWinnersKey(productID))
+	if err != nil {
+		return nil, err
+	}
+	return &DrawDetail{
+		DrawRecord:     *record,
+		ParticipantIDs: participants,
+		WinnerIDs:      winners,
+	}, nil
+}
+
+func (l *Lottery) userList(ctx context.Context, key string) ([]int64, error) {
+	members, err := l.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lottery draw list: %w", err)
+	}
+	users := make([]int64, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user in lottery draw list: %w", err)
+		}
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+// 是否已开奖
+func (l *Lottery) Drawn(ctx context.Context, productID int64) (bool, error) {
+	exists, err := l.client.Exists(ctx, drawKey(productID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check lottery draw: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// 用户是否持有有效的购买资格
+func (l *Lottery) HasRight(ctx context.Context, productID, userID int64) (bool, error) {
+	deadline, err := l.client.ZScore(ctx, rightsKey(productID), strconv.FormatInt(userID, 10)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check purchase right: %w", err)
+	}
+	return int64(deadline) > time.Now().UnixMilli(), nil
+}
+
+// 查询用户参与情况
+func (l *Lottery) GetEntry(ctx context.Context, productID, userID int64) (*Entry, error) {
+	member := strconv.FormatInt(userID, 10)
+	entry := &Entry{
+		ProductID: productID,
+		UserID:    userID,
+	}
+
+	drawn, err := l.Drawn(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !drawn {
+		entry.State = EntryNotRegistered
+		if _, err := l.client.ZScore(ctx, participantsKey(productID), member).Result(); err == nil {
+			entry.State = EntryRegistered
+		} else if err != redis.Nil {
+			return nil, fmt.Errorf("failed to get lottery entry: %w", err)
+		}
+		return entry, nil
+	}
+
+	purchased, err := l.client.SIsMember(ctx, seckill.UsersKey(productID), member).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lottery entry: %w", err)
+	}
+	if purchased {
+		entry.State = EntryClaimed
+		return entry, nil
+	}
+
+	deadline, err := l.client.ZScore(ctx, rightsKey(productID), member).Result()
+	if err == nil {
+		claimDeadline := time.UnixMilli(int64(deadline))
+		entry.State = EntryWon
+		if !claimDeadline.After(time.Now()) {
+			entry.State = EntryExpired
+		}
+		entry.ClaimDeadline = &claimDeadline
+		return entry, nil
+	} else if err != redis.Nil {
+		return nil, fmt.Errorf("failed to get lottery entry: %w", err)
+	}
+
+	position, err := l.client.LPos(ctx, waitlistKey(productID), member, redis.LPosArgs{}).Result()
+	if err == nil {
+		entry.State = EntryWaitlisted
+		entry.WaitlistPosition = position + 1
+		return entry, nil
+	} else if err != redis.Nil {
+		return nil, fmt.Errorf("failed to get lottery entry: %w", err)
+	}
+
+	// 不在资格与候补中：参与者的资格已过期被回收（未中签者都在候补中）
+	entry.State = EntryExpired
+	if _, err := l.client.ZScore(ctx, participantsKey(productID), member).Result(); err != nil {
+		if err != redis.Nil {
+			return nil, fmt.Errorf("failed to get lottery entry: %w", err)
+		}
+		entry.State = EntryNotRegistered
+	}
+	return entry, nil
+}
+
+// 回收过期的购买资格并转给候补用户，返回转出的资格数
+func (l *Lottery) PromoteWaitlist(ctx context.Context, productID int64, claimWindow time.Duration, limit int64) (int, error) {
+	now := time.Now()
+	promoted, err := promoteScript.Run(ctx, l.client,
+		[]string{rightsKey(productID), waitlistKey(productID), seckill.UsersKey(productID)},
+		now.UnixMilli(), now.Add(claimWindow).UnixMilli(), limit,
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to promote lottery waitlist: %w", err)
+	}
+	return promoted, nil
+}
+
+// 清理抽签数据，开奖记录与开奖名单保留用于纠纷核查
+func (l *Lottery) Cleanup(ctx context.Context, productID int64) error {
+	keys := append([]string{participantsKey(productID), rightsKey(productID), waitlistKey(productID)}, stagedKeys(productID)...)
+	err := l.client.Del(ctx, keys...).Err()
+	if err != nil {
+		return fmt.Errorf("failed to cleanup lottery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package lottery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDrawDetailKeptThroughCleanup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	l := NewLottery(client, logrus.New())
+	const productID = 1001
+
+	registered := []int64{30, 10, 50, 20, 40}
+	for _, userID := range registered {
+		if err := l.Register(ctx, productID, userID); err != nil {
+			t.Fatalf("Register(%d): %v", userID, err)
+		}
+	}
+
+	record, err := l.Draw(ctx, productID, 2, time.Hour)
+	if err != nil {
+		t.Fatalf("Draw: %v", err)
+	}
+	if err := l.Cleanup(ctx, productID); err != nil {
+		t.Fatalf("Cleanup: %v", err)
+	}
+
+	detail, err := l.GetDrawDetail(ctx, productID)
+	if err != nil {
+		t.Fatalf("GetDrawDetail: %v", err)
+	}
+	if detail.Seed != record.Seed || detail.Winners != record.Winners || detail.Waitlist != record.Waitlist {
+		t.Errorf("DrawRecord = %+v, want %+v", detail.DrawRecord, *record)
+	}
+	if want := []int64{10, 20, 30, 40, 50}; !reflect.DeepEqual(detail.ParticipantIDs, want) {
+		t.Errorf("ParticipantIDs = %v, want %v", detail.ParticipantIDs, want)
+	}
+	if got := ParticipantsHash(detail.ParticipantIDs); got != record.ParticipantsHash {
+		t.Errorf("ParticipantsHash(ParticipantIDs) = %s, want %s", got, record.ParticipantsHash)
+	}
+
+	// 保留的名单与种子能复现中签名单
+	winners, _ := Draw(detail.ParticipantIDs, record.Seed, 2)
+	if !reflect.DeepEqual(detail.WinnerIDs, winners) {
+		t.Errorf("WinnerIDs = %v, want %v", detail.WinnerIDs, winners)
+	}
+
+	for _, key := range stagedKeys(productID) {
+		if mr.Exists(key) {
+			t.Errorf("staged key %s not removed", key)
+		}
+	}
+}
+
+func TestGetDrawDetailNotDrawn(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	l := NewLottery(client, logrus.New())
+	if _, err := l.GetDrawDetail(context.Background(), 1001); err != ErrNotDrawn {
+		t.Errorf("GetDrawDetail error = %v, want %v", err, ErrNotDrawn)
+	}
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"time"
+
+	"seckill-service/internal/seckill"
+
+	"gorm.io/gorm"
+)
+
+// 活动版本变更类型
+const (
+	ActivityActionCreate = "create" // 创建
+	ActivityActionUpdate = "update" // 修改
+	ActivityActionDelete = "delete" // 删除
+)
+
+// 活动表
+type Activity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ProductID   int64      `gorm:"index;not null" json:"product_id"`
+	ProductName string     `gorm:"size:255;not null" json:"product_name"`
+	Price       float64    `gorm:"type:decimal(10,2);not null" json:"price"`
+	Stock       int64      `gorm:"not null" json:"stock"`
+	StartTime   time.Time  `gorm:"not null;index" json:"start_time"`
+	EndTime     time.Time  `gorm:"not null;index" json:"end_time"`
+	Status      string     `gorm:"size:20;not null" json:"status"`
+	Type        string     `gorm:"size:20;not null" json:"type"`      // fcfs / lottery / coupon
+	Version     int64      `gorm:"not null;default:1" json:"version"` // 乐观锁版本号，每次变更加 1
+	Operator    string     `gorm:"size:100" json:"operator"`          // 最近一次变更的操作者
+	PrewarmedAt *time.Time `json:"prewarmed_at,omitempty"`            // 最近一次预热时间
+
+	// 库存服务中的库存预留，预热时预留，活动结束后释放
+	ReservationID string     `gorm:"size:64;index" json:"reservation_id,omitempty"`
+	ReservedStock int64      `gorm:"not null;default:0" json:"reserved_stock"`
+	ReturnedStock int64      `gorm:"not null;default:0" json:"returned_stock"` // 释放时归还的未售出数量
+	SettledAt     *time.Time `json:"settled_at,omitempty"`
+
+	// 抽签活动配置
+	LotteryEntryStartTime     *time.Time `json:"lottery_entry_start_time,omitempty"`
+	LotteryEntryEndTime       *time.Time `json:"lottery_entry_end_time,omitempty"`
+	LotteryClaimWindowSeconds int64      `gorm:"not null;default:0" json:"lottery_claim_window_seconds,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// 活动版本历史表，每次变更保存变更后的完整快照
+type ActivityVersion struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ActivityID uint   `gorm:"uniqueIndex:idx_activity_version;not null" json:"activity_id"`
+	Version    int64  `gorm:"uniqueIndex:idx_activity_version;not null" json:"version"`
+	Action     string `gorm:"size:20;not null" json:"action"`     // 变更类型
+	Snapshot   string `gorm:"type:text;not null" json:"snapshot"` // 活动 JSON
+	Operator   string `gorm:"size:100" json:"operator"`
+	Reason     string `gorm:"size:255" json:"reason"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 创建或修改活动请求DTO，活动字段与预热接口一致
+type ActivityRequest struct {
+	seckill.SeckillActivity
+	Version int64  `json:"version,omitempty"` // 修改时必须携带当前版本号
+	Reason  string `json:"reason,omitempty"`  // 变更原因，记录到版本历史
+}
+
+// 活动列表查询条件
+type ActivityQuery struct {
+	ProductID int64
+	State     string // upcoming / ongoing / ended，为空时不过滤
+	Page      int
+	PageSize  int
+}
+
+// 活动所处阶段
+const (
+	ActivityStateUpcoming = "upcoming"
+	ActivityStateOngoing  = "ongoing"
+	ActivityStateEnded    = "ended"
+)
+
+// 用请求内容覆盖活动定义
+func (a *Activity) Apply(req *seckill.SeckillActivity) {
+	a.ProductID = req.ProductID
+	a.ProductName = req.ProductName
+	a.Price = req.Price
+	a.Stock = req.Stock
+	a.StartTime = req.StartTime
+	a.EndTime = req.EndTime
+	a.Status = req.Status
+	a.Type = req.Type
+	if a.Type == "" {
+		a.Type = seckill.ActivityTypeFCFS
+	}
+
+	a.LotteryEntryStartTime = nil
+	a.LotteryEntryEndTime = nil
+	a.LotteryClaimWindowSeconds = 0
+	if req.Lottery != nil {
+		entryStart, entryEnd := req.Lottery.EntryStartTime, req.Lottery.EntryEndTime
+		a.LotteryEntryStartTime = &entryStart
+		a.LotteryEntryEndTime = &entryEnd
+		a.LotteryClaimWindowSeconds = req.Lottery.ClaimWindowSeconds
+	}
+}
+
+// 转换为预热使用的活动定义
+func (a *Activity) ToSeckillActivity() *seckill.SeckillActivity {
+	activity := &seckill.SeckillActivity{
+		ProductID:   a.ProductID,
+		ProductName: a.ProductName,
+		Price:       a.Price,
+		Stock:       a.Stock,
+		StartTime:   a.StartTime,
+		EndTime:     a.EndTime,
+		Status:      a.Status,
+		Type:        a.Type,
+	}
+	if a.LotteryEntryStartTime != nil && a.LotteryEntryEndTime != nil {
+		activity.Lottery = &seckill.LotterySettings{
+			EntryStartTime:     *a.LotteryEntryStartTime,
+			EntryEndTime:       *a.LotteryEntryEndTime,
+			ClaimWindowSeconds: a.LotteryClaimWindowSeconds,
+		}
+	}
+	return activity
+}
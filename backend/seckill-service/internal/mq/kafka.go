@@ -64,18 +64,29 @@ func (p *KafkaProducer) SendMessage(ctx context.Context, key string, message []b
 func (p *KafkaProducer) SendSeckillOrderMessage(ctx context.Context, msg *SeckillOrderMessage) error {
 	data, err := msg.Marshal()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
 	}
 
 	key := fmt.Sprintf("seckill_order_%d_%d", msg.ProductID, msg.UserID)
 	return p.SendMessage(ctx, key, data)
 }
 
+// 发送发券消息
+func (p *KafkaProducer) SendCouponGrantedMessage(ctx context.Context, msg *CouponGrantedMessage) error {
+	data, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
+	}
+
+	key := fmt.Sprintf("coupon_granted_%d_%d", msg.ProductID, msg.UserID)
+	return p.SendMessage(ctx, key, data)
+}
+
 // 发送库存更新消息
 func (p *KafkaProducer) SendStockUpdateMessage(ctx context.Context, msg *StockUpdateMessage) error {
 	data, err := msg.Marshal()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
 	}
 
 	key := fmt.Sprintf("stock_update_%d", msg.ProductID)
@@ -86,7 +97,7 @@ func (p *KafkaProducer) SendStockUpdateMessage(ctx context.Context, msg *StockUp
 func (p *KafkaProducer) SendUserNotifyMessage(ctx context.Context, msg *UserNotifyMessage) error {
 	data, err := msg.Marshal()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
 	}
 
 	key := fmt.Sprintf("user_notify_%d", msg.UserID)
@@ -229,11 +240,13 @@ func (c *KafkaConsumer) Close() error {
 // 消息队列接口
 type MessageQueue interface {
 	SendSeckillOrderMessage(ctx context.Context, msg *SeckillOrderMessage) error
+	SendCouponGrantedMessage(ctx context.Context, msg *CouponGrantedMessage) error
 	SendStockUpdateMessage(ctx context.Context, msg *StockUpdateMessage) error
 	SendUserNotifyMessage(ctx context.Context, msg *UserNotifyMessage) error
 	Close() error
 }
 
-// 确保 RabbitMQ 和 Kafka 生产者都实现了 MessageQueue 接口
+// 确保 RabbitMQ、Kafka 和 Redis Stream 生产者都实现了 MessageQueue 接口
 var _ MessageQueue = (*RabbitMQProducer)(nil)
 var _ MessageQueue = (*KafkaProducer)(nil)
+var _ MessageQueue = (*RedisStreamProducer)(nil)
@@ -2,9 +2,14 @@ package mq
 
 import (
 	"encoding/json"
+	"errors"
 	"time"
 )
 
+// ErrNotPublished 表示消息确定没有被消息队列接受（序列化失败、发布调用失败、Broker nack），
+// 调用方可以安全地回滚；其他发送错误（如等待确认超时）无法确定消息是否已投递
+var ErrNotPublished = errors.New("message not published")
+
 // 秒杀订单消息
 type SeckillOrderMessage struct {
 	OrderID     string    `json:"order_id"`
@@ -19,9 +24,10 @@ type SeckillOrderMessage struct {
 
 // 消息类型常量
 const (
-	MessageTypeSeckillOrder = "seckill_order"
-	MessageTypeStockUpdate  = "stock_update"
-	MessageTypeUserNotify   = "user_notify"
+	MessageTypeSeckillOrder  = "seckill_order"
+	MessageTypeStockUpdate   = "stock_update"
+	MessageTypeUserNotify    = "user_notify"
+	MessageTypeCouponGranted = "coupon_granted"
 )
 
 // 序列化消息
@@ -48,6 +54,40 @@ func NewSeckillOrderMessage(orderID string, productID, userID, quantity int64, p
 	}
 }
 
+// 发券消息，发券活动不创建订单，由下游把券码发放到用户账户
+type CouponGrantedMessage struct {
+	GrantID     string    `json:"grant_id"` // 发放流水号，用于下游去重
+	ProductID   int64     `json:"product_id"`
+	UserID      int64     `json:"user_id"`
+	CouponCode  string    `json:"coupon_code,omitempty"` // 写入 outbox 时由发券脚本追加
+	GrantTime   time.Time `json:"grant_time"`
+	MessageType string    `json:"message_type"`
+	TraceID     string    `json:"trace_id"`
+}
+
+// 创建发券消息
+func NewCouponGrantedMessage(grantID string, productID, userID int64, couponCode, traceID string) *CouponGrantedMessage {
+	return &CouponGrantedMessage{
+		GrantID:     grantID,
+		ProductID:   productID,
+		UserID:      userID,
+		CouponCode:  couponCode,
+		GrantTime:   time.Now(),
+		MessageType: MessageTypeCouponGranted,
+		TraceID:     traceID,
+	}
+}
+
+// 序列化发券消息
+func (m *CouponGrantedMessage) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// 反序列化发券消息
+func (m *CouponGrantedMessage) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, m)
+}
+
 // 库存更新消息
 type StockUpdateMessage struct {
 	ProductID      int64     `json:"product_id"`
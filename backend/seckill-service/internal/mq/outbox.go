@@ -0,0 +1,386 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Outbox 条目字段
+const (
+	OutboxFieldType    = "type"
+	OutboxFieldPayload = "payload"
+)
+
+// Outbox 中继配置
+type OutboxRelayConfig struct {
+	StreamSet     string        // 记录所有 outbox 流的集合，每个活动一个流
+	Group         string        // 消费者组
+	Consumer      string        // 消费者名称（每个节点唯一）
+	BatchSize     int64         // 每次读取条数
+	BlockTimeout  time.Duration // 读取阻塞时间
+	ClaimIdle     time.Duration // 超过该空闲时间的未确认条目会被重新认领
+	RefreshPeriod time.Duration // 重新读取流集合的间隔
+	MaxRetries    int           // 单次投递的重试次数
+	RetryInterval time.Duration // 重试间隔（指数退避基数）
+}
+
+// Outbox 统计信息
+type OutboxStats struct {
+	Published     int64
+	PublishFailed int64
+	Reclaimed     int64
+	DeadLettered  int64
+	Backlog       int64         // 尚未投递成功的条目数
+	Pending       int64         // 已读取但尚未确认的条目数
+	Lag           time.Duration // 最早未投递条目的等待时间
+}
+
+// Outbox 中继：从各活动的 Redis Stream 读取订单事件并投递到消息队列。
+// Redis Cluster 下不同活动的流位于不同的槽，因此逐个流读取而不是一次读取多个流。
+type OutboxRelay struct {
+	client redis.Cmdable
+	queue  MessageQueue
+	config OutboxRelayConfig
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	streams []string        // 当前消费的流
+	groups  map[string]bool // 已创建消费者组的流
+
+	published     int64
+	publishFailed int64
+	reclaimed     int64
+	deadLettered  int64
+}
+
+// 创建 Outbox 中继
+func NewOutboxRelay(client redis.Cmdable, queue MessageQueue, config OutboxRelayConfig, logger *logrus.Logger) *OutboxRelay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.BlockTimeout <= 0 {
+		config.BlockTimeout = time.Second
+	}
+	if config.ClaimIdle <= 0 {
+		config.ClaimIdle = 30 * time.Second
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = 100 * time.Millisecond
+	}
+	if config.RefreshPeriod <= 0 {
+		config.RefreshPeriod = 5 * time.Second
+	}
+
+	return &OutboxRelay{
+		client: client,
+		queue:  queue,
+		config: config,
+		logger: logger,
+		groups: make(map[string]bool),
+	}
+}
+
+// 启动中继
+func (r *OutboxRelay) Start(ctx context.Context) error {
+	if err := r.refreshStreams(ctx); err != nil {
+		return err
+	}
+
+	go r.run(ctx)
+
+	r.logger.Infof("Outbox relay started: streams=%s, group=%s, consumer=%s",
+		r.config.StreamSet, r.config.Group, r.config.Consumer)
+	return nil
+}
+
+// 重新读取流集合，并为新出现的流创建消费者组
+func (r *OutboxRelay) refreshStreams(ctx context.Context) error {
+	streams, err := r.client.SMembers(ctx, r.config.StreamSet).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list outbox streams: %w", err)
+	}
+
+	for _, stream := range streams {
+		if r.hasGroup(stream) {
+			continue
+		}
+		err := r.client.XGroupCreateMkStream(ctx, stream, r.config.Group, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("failed to create outbox consumer group on %s: %w", stream, err)
+		}
+		r.setGroup(stream, true)
+	}
+
+	r.mu.Lock()
+	r.streams = streams
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *OutboxRelay) hasGroup(stream string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.groups[stream]
+}
+
+func (r *OutboxRelay) setGroup(stream string, created bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if created {
+		r.groups[stream] = true
+	} else {
+		delete(r.groups, stream)
+	}
+}
+
+// 当前消费的流
+func (r *OutboxRelay) currentStreams() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.streams
+}
+
+// 中继主循环：依次读取各个流，全部没有新条目时等待 BlockTimeout
+func (r *OutboxRelay) run(ctx context.Context) {
+	lastRefresh := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		default:
+		}
+
+		if time.Since(lastRefresh) >= r.config.RefreshPeriod {
+			if err := r.refreshStreams(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Errorf("Failed to refresh outbox streams: %v", err)
+			}
+			lastRefresh = time.Now()
+		}
+
+		relayed := 0
+		for _, stream := range r.currentStreams() {
+			// 先认领其他节点（或本节点之前）投递失败的条目
+			relayed += r.reclaim(ctx, stream)
+			relayed += r.read(ctx, stream)
+		}
+
+		if relayed == 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.config.BlockTimeout):
+			}
+		}
+	}
+}
+
+// 非阻塞读取一个流的新条目，返回处理的条目数
+func (r *OutboxRelay) read(ctx context.Context, stream string) int {
+	streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    r.config.Group,
+		Consumer: r.config.Consumer,
+		Streams:  []string{stream, ">"},
+		Count:    r.config.BatchSize,
+		Block:    -1,
+	}).Result()
+	if err != nil {
+		if err != redis.Nil && ctx.Err() == nil {
+			// 流被删除后重建时消费者组随之丢失，下次刷新时重新创建
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				r.setGroup(stream, false)
+			}
+			r.logger.Errorf("Failed to read outbox %s: %v", stream, err)
+		}
+		return 0
+	}
+
+	relayed := 0
+	for _, result := range streams {
+		for _, message := range result.Messages {
+			r.relay(ctx, stream, message)
+			relayed++
+		}
+	}
+	return relayed
+}
+
+// 认领超时未确认的条目，返回认领的条目数
+func (r *OutboxRelay) reclaim(ctx context.Context, stream string) int {
+	reclaimed := 0
+	start := "0-0"
+	for {
+		messages, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    r.config.Group,
+			Consumer: r.config.Consumer,
+			MinIdle:  r.config.ClaimIdle,
+			Start:    start,
+			Count:    r.config.BatchSize,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil && !strings.HasPrefix(err.Error(), "NOGROUP") {
+				r.logger.Errorf("Failed to reclaim outbox entries from %s: %v", stream, err)
+			}
+			return reclaimed
+		}
+
+		for _, message := range messages {
+			atomic.AddInt64(&r.reclaimed, 1)
+			r.relay(ctx, stream, message)
+		}
+		reclaimed += len(messages)
+
+		if next == "0-0" || len(messages) == 0 {
+			return reclaimed
+		}
+		start = next
+	}
+}
+
+// 投递单个条目，仅在消息队列确认后才确认并删除
+func (r *OutboxRelay) relay(ctx context.Context, stream string, message redis.XMessage) {
+	messageType, _ := message.Values[OutboxFieldType].(string)
+	payload, _ := message.Values[OutboxFieldPayload].(string)
+
+	var err error
+	for attempt := 0; attempt < r.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.config.RetryInterval * time.Duration(1<<uint(attempt-1))):
+			}
+		}
+
+		err = r.publish(ctx, messageType, payload)
+		if err == nil {
+			break
+		}
+		if _, ok := err.(*invalidOutboxEntryError); ok {
+			r.deadLetter(ctx, stream, message, err)
+			return
+		}
+	}
+
+	if err != nil {
+		// 保留在待确认列表中，等待 ClaimIdle 后重新认领
+		atomic.AddInt64(&r.publishFailed, 1)
+		r.logger.Errorf("Failed to relay outbox entry %s after %d attempts: %v",
+			message.ID, r.config.MaxRetries, err)
+		return
+	}
+
+	r.ack(ctx, stream, message.ID)
+	atomic.AddInt64(&r.published, 1)
+}
+
+// 无法解析的条目
+type invalidOutboxEntryError struct {
+	reason string
+}
+
+func (e *invalidOutboxEntryError) Error() string {
+	return e.reason
+}
+
+// 按消息类型投递
+func (r *OutboxRelay) publish(ctx context.Context, messageType, payload string) error {
+	switch messageType {
+	case MessageTypeSeckillOrder:
+		var msg SeckillOrderMessage
+		if err := msg.Unmarshal([]byte(payload)); err != nil {
+			return &invalidOutboxEntryError{reason: fmt.Sprintf("invalid payload: %v", err)}
+		}
+		return r.queue.SendSeckillOrderMessage(ctx, &msg)
+	case MessageTypeCouponGranted:
+		var msg CouponGrantedMessage
+		if err := msg.Unmarshal([]byte(payload)); err != nil {
+			return &invalidOutboxEntryError{reason: fmt.Sprintf("invalid payload: %v", err)}
+		}
+		return r.queue.SendCouponGrantedMessage(ctx, &msg)
+	default:
+		return &invalidOutboxEntryError{reason: fmt.Sprintf("unknown message type: %q", messageType)}
+	}
+}
+
+// 确认并删除条目
+func (r *OutboxRelay) ack(ctx context.Context, stream, id string) {
+	if err := r.client.XAck(ctx, stream, r.config.Group, id).Err(); err != nil {
+		r.logger.Errorf("Failed to ack outbox entry %s: %v", id, err)
+		return
+	}
+	if err := r.client.XDel(ctx, stream, id).Err(); err != nil {
+		r.logger.Warnf("Failed to delete outbox entry %s: %v", id, err)
+	}
+}
+
+// 转入死信流
+func (r *OutboxRelay) deadLetter(ctx context.Context, stream string, message redis.XMessage, reason error) {
+	values := make(map[string]interface{}, len(message.Values)+2)
+	for k, v := range message.Values {
+		values[k] = v
+	}
+	values["origin_id"] = message.ID
+	values["error"] = reason.Error()
+
+	if err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream + ":dead",
+		Values: values,
+	}).Err(); err != nil {
+		r.logger.Errorf("Failed to dead-letter outbox entry %s: %v", message.ID, err)
+		return
+	}
+
+	r.ack(ctx, stream, message.ID)
+	atomic.AddInt64(&r.deadLettered, 1)
+	r.logger.Errorf("Outbox entry %s moved to dead letter stream: %v", message.ID, reason)
+}
+
+// 获取统计信息（含各流合计的 outbox 积压与最大延迟）
+func (r *OutboxRelay) GetStats(ctx context.Context) OutboxStats {
+	stats := OutboxStats{
+		Published:     atomic.LoadInt64(&r.published),
+		PublishFailed: atomic.LoadInt64(&r.publishFailed),
+		Reclaimed:     atomic.LoadInt64(&r.reclaimed),
+		DeadLettered:  atomic.LoadInt64(&r.deadLettered),
+	}
+
+	for _, stream := range r.currentStreams() {
+		if backlog, err := r.client.XLen(ctx, stream).Result(); err == nil {
+			stats.Backlog += backlog
+		}
+
+		if pending, err := r.client.XPending(ctx, stream, r.config.Group).Result(); err == nil {
+			stats.Pending += pending.Count
+		}
+
+		// 已投递的条目会被删除，因此流中最早的条目即最早未投递的事件
+		if oldest, err := r.client.XRangeN(ctx, stream, "-", "+", 1).Result(); err == nil && len(oldest) > 0 {
+			if ts, ok := streamIDTime(oldest[0].ID); ok {
+				stats.Lag = max(stats.Lag, time.Since(ts))
+			}
+		}
+	}
+
+	return stats
+}
+
+// 从 Stream ID 中解析写入时间
+func streamIDTime(id string) (time.Time, bool) {
+	ms, err := strconv.ParseInt(strings.SplitN(id, "-", 2)[0], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
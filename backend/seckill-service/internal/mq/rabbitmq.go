@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -14,6 +15,9 @@ import (
 type RabbitMQProducer struct {
 	conn       *amqp.Connection
 	channel    *amqp.Channel
+	confirms   chan amqp.Confirmation
+	publishMu  sync.Mutex // 发布与确认需串行，保证确认与消息一一对应
+	nextTag    uint64     // 下一条消息的投递标签
 	exchange   string
 	queue      string
 	routingKey string
@@ -45,9 +49,18 @@ func NewRabbitMQProducer(config *RabbitMQConfig, logger *logrus.Logger) (*Rabbit
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
+	// 开启发布确认，只有 Broker 确认后才视为发送成功
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
 	producer := &RabbitMQProducer{
 		conn:       conn,
 		channel:    channel,
+		confirms:   channel.NotifyPublish(make(chan amqp.Confirmation, 64)),
+		nextTag:    1,
 		exchange:   config.Exchange,
 		queue:      config.Queue,
 		routingKey: config.RoutingKey,
@@ -118,6 +131,9 @@ func (p *RabbitMQProducer) SendMessageWithRoutingKey(ctx context.Context, messag
 		MessageId:    fmt.Sprintf("%d", time.Now().UnixNano()),
 	}
 
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
 	// 发送消息
 	err := p.channel.Publish(
 		p.exchange, // exchange
@@ -129,7 +145,29 @@ func (p *RabbitMQProducer) SendMessageWithRoutingKey(ctx context.Context, messag
 
 	if err != nil {
 		p.logger.Errorf("Failed to publish message: %v", err)
-		return fmt.Errorf("failed to publish message: %w", err)
+		return fmt.Errorf("%w: failed to publish message: %v", ErrNotPublished, err)
+	}
+
+	tag := p.nextTag
+	p.nextTag++
+
+	// 等待 Broker 确认，跳过之前超时消息迟到的确认
+	for {
+		select {
+		case confirm, ok := <-p.confirms:
+			if !ok {
+				return fmt.Errorf("confirm channel closed")
+			}
+			if confirm.DeliveryTag < tag {
+				continue
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("%w: message nacked by broker (delivery tag %d)", ErrNotPublished, confirm.DeliveryTag)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for publish confirm: %w", ctx.Err())
+		}
+		break
 	}
 
 	p.logger.Debugf("Message sent to exchange: %s, routing key: %s", p.exchange, routingKey)
@@ -140,17 +178,27 @@ func (p *RabbitMQProducer) SendMessageWithRoutingKey(ctx context.Context, messag
 func (p *RabbitMQProducer) SendSeckillOrderMessage(ctx context.Context, msg *SeckillOrderMessage) error {
 	data, err := msg.Marshal()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
 	}
 
 	return p.SendMessageWithRoutingKey(ctx, data, "seckill.order.create")
 }
 
+// 发送发券消息
+func (p *RabbitMQProducer) SendCouponGrantedMessage(ctx context.Context, msg *CouponGrantedMessage) error {
+	data, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
+	}
+
+	return p.SendMessageWithRoutingKey(ctx, data, "seckill.coupon.granted")
+}
+
 // 发送库存更新消息
 func (p *RabbitMQProducer) SendStockUpdateMessage(ctx context.Context, msg *StockUpdateMessage) error {
 	data, err := msg.Marshal()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
 	}
 
 	return p.SendMessageWithRoutingKey(ctx, data, "seckill.stock.update")
@@ -160,7 +208,7 @@ func (p *RabbitMQProducer) SendStockUpdateMessage(ctx context.Context, msg *Stoc
 func (p *RabbitMQProducer) SendUserNotifyMessage(ctx context.Context, msg *UserNotifyMessage) error {
 	data, err := msg.Marshal()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
 	}
 
 	return p.SendMessageWithRoutingKey(ctx, data, "seckill.user.notify")
@@ -214,8 +262,16 @@ func (p *RabbitMQProducer) Reconnect(config *RabbitMQConfig) error {
 		return fmt.Errorf("failed to open channel: %w", err)
 	}
 
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
 	p.conn = conn
 	p.channel = channel
+	p.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 64))
+	p.nextTag = 1
 
 	p.logger.Info("RabbitMQ producer reconnected successfully")
 	return nil
@@ -0,0 +1,115 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Redis Stream 条目字段
+const (
+	StreamFieldType    = "type"
+	StreamFieldPayload = "payload"
+)
+
+// Redis Stream 配置
+type RedisStreamConfig struct {
+	Stream string
+	MaxLen int64 // 近似裁剪长度，0 表示不裁剪
+}
+
+// Redis Stream 生产者
+type RedisStreamProducer struct {
+	client redis.Cmdable
+	stream string
+	maxLen int64
+	logger *logrus.Logger
+}
+
+// 创建 Redis Stream 生产者
+func NewRedisStreamProducer(client redis.Cmdable, config *RedisStreamConfig, logger *logrus.Logger) *RedisStreamProducer {
+	logger.Infof("Redis stream producer initialized: stream=%s, max_len=%d", config.Stream, config.MaxLen)
+	return &RedisStreamProducer{
+		client: client,
+		stream: config.Stream,
+		maxLen: config.MaxLen,
+		logger: logger,
+	}
+}
+
+// 发送消息
+func (p *RedisStreamProducer) SendMessage(ctx context.Context, messageType string, message []byte) error {
+	args := &redis.XAddArgs{
+		Stream: p.stream,
+		Values: map[string]interface{}{
+			StreamFieldType:    messageType,
+			StreamFieldPayload: message,
+		},
+	}
+	if p.maxLen > 0 {
+		args.MaxLen = p.maxLen
+		args.Approx = true
+	}
+
+	id, err := p.client.XAdd(ctx, args).Result()
+	if err != nil {
+		p.logger.Errorf("Failed to add message to stream %s: %v", p.stream, err)
+		return fmt.Errorf("failed to add message: %w", err)
+	}
+
+	p.logger.Debugf("Message sent to stream: %s, id: %s", p.stream, id)
+	return nil
+}
+
+// 发送秒杀订单消息
+func (p *RedisStreamProducer) SendSeckillOrderMessage(ctx context.Context, msg *SeckillOrderMessage) error {
+	data, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
+	}
+
+	return p.SendMessage(ctx, MessageTypeSeckillOrder, data)
+}
+
+// 发送发券消息
+func (p *RedisStreamProducer) SendCouponGrantedMessage(ctx context.Context, msg *CouponGrantedMessage) error {
+	data, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
+	}
+
+	return p.SendMessage(ctx, MessageTypeCouponGranted, data)
+}
+
+// 发送库存更新消息
+func (p *RedisStreamProducer) SendStockUpdateMessage(ctx context.Context, msg *StockUpdateMessage) error {
+	data, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
+	}
+
+	return p.SendMessage(ctx, MessageTypeStockUpdate, data)
+}
+
+// 发送用户通知消息
+func (p *RedisStreamProducer) SendUserNotifyMessage(ctx context.Context, msg *UserNotifyMessage) error {
+	data, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal message: %v", ErrNotPublished, err)
+	}
+
+	return p.SendMessage(ctx, MessageTypeUserNotify, data)
+}
+
+// 健康检查
+func (p *RedisStreamProducer) HealthCheck(ctx context.Context) error {
+	return p.client.Ping(ctx).Err()
+}
+
+// 关闭生产者（Redis 客户端由服务统一关闭）
+func (p *RedisStreamProducer) Close() error {
+	p.logger.Info("Redis stream producer closed")
+	return nil
+}
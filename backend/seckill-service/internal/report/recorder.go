@@ -0,0 +1,248 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"seckill-service/internal/seckill"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// 请求结果分类
+type Outcome string
+
+const (
+	OutcomeSuccess        Outcome = "success"
+	OutcomeRateLimited    Outcome = "rate_limited"    // 全局、热点限流
+	OutcomeCircuitBreaker Outcome = "circuit_breaker" // 熔断器打开
+	OutcomeSystemBusy     Outcome = "system_busy"     // 过载降级、队列已满
+	OutcomeDuplicate      Outcome = "duplicate"       // 重复购买
+	OutcomeSoldOut        Outcome = "sold_out"        // 库存不足、本地售罄标记
+	OutcomeOther          Outcome = "other"           // 活动未开始、风控拒绝、系统错误等
+)
+
+// 报告中依次展示的结果分类
+var Outcomes = []Outcome{
+	OutcomeSuccess,
+	OutcomeRateLimited,
+	OutcomeCircuitBreaker,
+	OutcomeSystemBusy,
+	OutcomeDuplicate,
+	OutcomeSoldOut,
+	OutcomeOther,
+}
+
+// 秒杀结果码对应的结果分类
+func ResultOutcome(code int) Outcome {
+	switch code {
+	case seckill.ResultSuccess:
+		return OutcomeSuccess
+	case seckill.ResultUserAlreadyBought:
+		return OutcomeDuplicate
+	case seckill.ResultInsufficientStock:
+		return OutcomeSoldOut
+	case seckill.ResultSystemBusy:
+		return OutcomeSystemBusy
+	}
+	return OutcomeOther
+}
+
+// 延迟直方图的桶上界（毫秒），超过最后一个桶的请求计入 inf
+var LatencyBuckets = []int64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000}
+
+// 报告数据的 hash 字段：
+//   - s:<Unix秒>:<结果分类>  每秒各结果的请求数
+//   - lat:<桶上界毫秒>|lat:inf  延迟直方图
+//   - sold_out_at  售罄时间（毫秒），只记录第一次
+//   - orders_created / orders_paid  订单服务创建、支付的秒杀订单数
+//   - start_time / end_time / stock / product_name  预热时记录的活动信息
+const (
+	fieldSoldOutAt     = "sold_out_at"
+	fieldOrdersCreated = "orders_created"
+	fieldOrdersPaid    = "orders_paid"
+	fieldStartTime     = "start_time"
+	fieldEndTime       = "end_time"
+	fieldStock         = "stock"
+	fieldProductName   = "product_name"
+)
+
+// 活动报告数据key，与活动的其他 key 使用相同的 {商品ID} hash tag；
+// order-service 创建、支付秒杀订单时累加同一个 hash 的订单字段
+func Key(productID int64) string {
+	return fmt.Sprintf("seckill:report:{%d}", productID)
+}
+
+func secondField(second int64, outcome Outcome) string {
+	return "s:" + strconv.FormatInt(second, 10) + ":" + string(outcome)
+}
+
+func latencyField(latency time.Duration) string {
+	ms := latency.Milliseconds()
+	for _, bound := range LatencyBuckets {
+		if ms < bound {
+			return "lat:" + strconv.FormatInt(bound, 10)
+		}
+	}
+	return "lat:inf"
+}
+
+// 活动报告计数器：请求结果按秒在本地汇总，定期批量写入 Redis，
+// 多个节点的计数在同一个 hash 中累加
+type Recorder struct {
+	client        redis.UniversalClient
+	flushInterval time.Duration
+	ttl           time.Duration
+	logger        *logrus.Logger
+
+	mu       sync.Mutex
+	counters map[int64]map[string]int64 // 商品ID -> 字段 -> 增量
+	soldOut  map[int64]time.Time
+}
+
+// 创建活动报告计数器
+func NewRecorder(client redis.UniversalClient, flushInterval, ttl time.Duration, logger *logrus.Logger) *Recorder {
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+	return &Recorder{
+		client:        client,
+		flushInterval: flushInterval,
+		ttl:           ttl,
+		logger:        logger,
+		counters:      make(map[int64]map[string]int64),
+		soldOut:       make(map[int64]time.Time),
+	}
+}
+
+// 记录一个请求的结果和端到端延迟
+func (r *Recorder) Record(productID int64, outcome Outcome, at time.Time, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fields, ok := r.counters[productID]
+	if !ok {
+		fields = make(map[string]int64)
+		r.counters[productID] = fields
+	}
+	fields[secondField(at.Unix(), outcome)]++
+	fields[latencyField(latency)]++
+}
+
+// 记录售罄时间，各节点只保留最早的一次
+func (r *Recorder) MarkSoldOut(productID int64, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if prev, ok := r.soldOut[productID]; !ok || at.Before(prev) {
+		r.soldOut[productID] = at
+	}
+}
+
+// 活动预热时记录活动信息；同一商品开始新的活动时清空上一场的报告数据
+func (r *Recorder) Begin(ctx context.Context, activity *seckill.SeckillActivity) error {
+	key := Key(activity.ProductID)
+	startTime := strconv.FormatInt(activity.StartTime.UnixMilli(), 10)
+
+	prev, err := r.client.HGet(ctx, key, fieldStartTime).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to get report: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	if err == nil && prev != startTime {
+		pipe.Del(ctx, key)
+	}
+	pipe.HSet(ctx, key,
+		fieldStartTime, startTime,
+		fieldEndTime, activity.EndTime.UnixMilli(),
+		fieldStock, activity.Stock,
+		fieldProductName, activity.ProductName,
+	)
+	if r.ttl > 0 {
+		pipe.Expire(ctx, key, r.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to begin report: %w", err)
+	}
+	return nil
+}
+
+// 定期把本地计数写入 Redis，ctx 结束时不再写入，需调用 Flush 写入剩余计数
+func (r *Recorder) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.logger.Warnf("Failed to flush report counters: %v", err)
+			}
+		}
+	}
+}
+
+// 把本地计数写入 Redis，写入失败的计数保留到下次
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	counters, soldOut := r.counters, r.soldOut
+	r.counters = make(map[int64]map[string]int64)
+	r.soldOut = make(map[int64]time.Time)
+	r.mu.Unlock()
+
+	if len(counters) == 0 && len(soldOut) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for productID, fields := range counters {
+		key := Key(productID)
+		for field, delta := range fields {
+			pipe.HIncrBy(ctx, key, field, delta)
+		}
+	}
+	for productID, at := range soldOut {
+		pipe.HSetNX(ctx, Key(productID), fieldSoldOutAt, at.UnixMilli())
+	}
+	if r.ttl > 0 {
+		for productID := range counters {
+			pipe.Expire(ctx, Key(productID), r.ttl)
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.restore(counters, soldOut)
+		return err
+	}
+	return nil
+}
+
+// 写入失败时把计数合并回本地
+func (r *Recorder) restore(counters map[int64]map[string]int64, soldOut map[int64]time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for productID, fields := range counters {
+		current, ok := r.counters[productID]
+		if !ok {
+			r.counters[productID] = fields
+			continue
+		}
+		for field, delta := range fields {
+			current[field] += delta
+		}
+	}
+	for productID, at := range soldOut {
+		if prev, ok := r.soldOut[productID]; !ok || at.Before(prev) {
+			r.soldOut[productID] = at
+		}
+	}
+}
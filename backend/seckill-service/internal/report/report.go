@@ -0,0 +1,288 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotEnabled     = errors.New("activity report is not enabled")
+	ErrReportNotFound = errors.New("report not found")
+)
+
+// 延迟分位数（毫秒，取所在直方图桶的上界；超过最大桶时为 -1）
+type LatencyStats struct {
+	Samples int64 `json:"samples"`
+	P50     int64 `json:"p50_ms"`
+	P90     int64 `json:"p90_ms"`
+	P99     int64 `json:"p99_ms"`
+	P999    int64 `json:"p999_ms"`
+}
+
+// 购买到下单、支付的转化
+type Conversion struct {
+	Purchases     int64   `json:"purchases"`      // 秒杀成功数
+	OrdersCreated int64   `json:"orders_created"` // 订单服务创建的订单数
+	OrdersPaid    int64   `json:"orders_paid"`    // 已支付订单数
+	CreatedRate   float64 `json:"created_rate"`   // 创建订单数 / 秒杀成功数
+	PaidRate      float64 `json:"paid_rate"`      // 已支付订单数 / 秒杀成功数
+}
+
+// 每秒的请求统计
+type Second struct {
+	Time     time.Time         `json:"time"`
+	Requests int64             `json:"requests"`
+	Outcomes map[Outcome]int64 `json:"outcomes"`
+}
+
+// 活动报告
+type Report struct {
+	ProductID       int64             `json:"product_id"`
+	ProductName     string            `json:"product_name,omitempty"`
+	Stock           int64             `json:"stock"`
+	StartTime       *time.Time        `json:"start_time,omitempty"`
+	EndTime         *time.Time        `json:"end_time,omitempty"`
+	Requests        int64             `json:"requests"`
+	Outcomes        map[Outcome]int64 `json:"outcomes"`
+	SoldOutAt       *time.Time        `json:"sold_out_at,omitempty"`
+	TimeToSellOutMs *int64            `json:"time_to_sell_out_ms,omitempty"` // 从活动开始（缺失时为第一个请求）到售罄
+	PeakRPS         int64             `json:"peak_rps"`
+	PeakAt          *time.Time        `json:"peak_at,omitempty"`
+	Latency         LatencyStats      `json:"latency"`
+	Conversion      Conversion        `json:"conversion"`
+	Seconds         []*Second         `json:"seconds"`
+	GeneratedAt     time.Time         `json:"generated_at"`
+}
+
+// 从 Redis 读取报告数据并生成报告，只包含已写入 Redis 的计数
+func (r *Recorder) Build(ctx context.Context, productID int64) (*Report, error) {
+	fields, err := r.client.HGetAll(ctx, Key(productID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrReportNotFound
+	}
+	return build(productID, fields, time.Now()), nil
+}
+
+func build(productID int64, fields map[string]string, now time.Time) *Report {
+	report := &Report{
+		ProductID:   productID,
+		ProductName: fields[fieldProductName],
+		Outcomes:    make(map[Outcome]int64, len(Outcomes)),
+		Seconds:     []*Second{},
+		GeneratedAt: now,
+	}
+	for _, outcome := range Outcomes {
+		report.Outcomes[outcome] = 0
+	}
+
+	report.Stock, _ = strconv.ParseInt(fields[fieldStock], 10, 64)
+	report.StartTime = parseMillis(fields[fieldStartTime])
+	report.EndTime = parseMillis(fields[fieldEndTime])
+	report.SoldOutAt = parseMillis(fields[fieldSoldOutAt])
+
+	seconds := make(map[int64]*Second)
+	latency := make(map[string]int64)
+	for field, value := range fields {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(field, "s:"):
+			second, outcome, ok := parseSecondField(field)
+			if !ok {
+				continue
+			}
+			stats, ok := seconds[second]
+			if !ok {
+				stats = &Second{Time: time.Unix(second, 0), Outcomes: make(map[Outcome]int64)}
+				seconds[second] = stats
+			}
+			stats.Requests += count
+			stats.Outcomes[outcome] += count
+			report.Requests += count
+			report.Outcomes[outcome] += count
+		case strings.HasPrefix(field, "lat:"):
+			latency[strings.TrimPrefix(field, "lat:")] += count
+		case field == fieldOrdersCreated:
+			report.Conversion.OrdersCreated = count
+		case field == fieldOrdersPaid:
+			report.Conversion.OrdersPaid = count
+		}
+	}
+
+	// 按时间排序的每秒统计与峰值
+	for _, stats := range seconds {
+		report.Seconds = append(report.Seconds, stats)
+	}
+	sort.Slice(report.Seconds, func(i, j int) bool {
+		return report.Seconds[i].Time.Before(report.Seconds[j].Time)
+	})
+	for _, stats := range report.Seconds {
+		if stats.Requests > report.PeakRPS {
+			report.PeakRPS = stats.Requests
+			peakAt := stats.Time
+			report.PeakAt = &peakAt
+		}
+	}
+
+	// 售罄耗时
+	if report.SoldOutAt != nil {
+		start := report.StartTime
+		if start == nil && len(report.Seconds) > 0 {
+			start = &report.Seconds[0].Time
+		}
+		if start != nil {
+			elapsed := report.SoldOutAt.Sub(*start).Milliseconds()
+			report.TimeToSellOutMs = &elapsed
+		}
+	}
+
+	report.Latency = latencyStats(latency)
+
+	report.Conversion.Purchases = report.Outcomes[OutcomeSuccess]
+	if report.Conversion.Purchases > 0 {
+		report.Conversion.CreatedRate = ratio(report.Conversion.OrdersCreated, report.Conversion.Purchases)
+		report.Conversion.PaidRate = ratio(report.Conversion.OrdersPaid, report.Conversion.Purchases)
+	}
+	return report
+}
+
+func parseSecondField(field string) (int64, Outcome, bool) {
+	parts := strings.SplitN(field, ":", 3)
+	if len(parts) != 3 {
+		return 0, "", false
+	}
+	second, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return second, Outcome(parts[2]), true
+}
+
+func parseMillis(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
+
+// 按直方图计算延迟分位数
+func latencyStats(histogram map[string]int64) LatencyStats {
+	var stats LatencyStats
+	for _, count := range histogram {
+		stats.Samples += count
+	}
+	if stats.Samples == 0 {
+		return stats
+	}
+
+	percentile := func(p float64) int64 {
+		rank := int64(math.Ceil(p * float64(stats.Samples)))
+		var seen int64
+		for _, bound := range LatencyBuckets {
+			seen += histogram[strconv.FormatInt(bound, 10)]
+			if seen >= rank {
+				return bound
+			}
+		}
+		return -1
+	}
+	stats.P50 = percentile(0.5)
+	stats.P90 = percentile(0.9)
+	stats.P99 = percentile(0.99)
+	stats.P999 = percentile(0.999)
+	return stats
+}
+
+func ratio(n, d int64) float64 {
+	return math.Round(float64(n)/float64(d)*10000) / 10000
+}
+
+// 以 CSV 输出报告：先是 metric,value 形式的汇总，空行后是每秒统计
+func (report *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{
+		{"metric", "value"},
+		{"product_id", strconv.FormatInt(report.ProductID, 10)},
+		{"product_name", report.ProductName},
+		{"stock", strconv.FormatInt(report.Stock, 10)},
+		{"start_time", formatTime(report.StartTime)},
+		{"end_time", formatTime(report.EndTime)},
+		{"requests", strconv.FormatInt(report.Requests, 10)},
+	}
+	for _, outcome := range Outcomes {
+		rows = append(rows, []string{string(outcome), strconv.FormatInt(report.Outcomes[outcome], 10)})
+	}
+	timeToSellOut := ""
+	if report.TimeToSellOutMs != nil {
+		timeToSellOut = strconv.FormatInt(*report.TimeToSellOutMs, 10)
+	}
+	rows = append(rows,
+		[]string{"sold_out_at", formatTime(report.SoldOutAt)},
+		[]string{"time_to_sell_out_ms", timeToSellOut},
+		[]string{"peak_rps", strconv.FormatInt(report.PeakRPS, 10)},
+		[]string{"peak_at", formatTime(report.PeakAt)},
+		[]string{"latency_samples", strconv.FormatInt(report.Latency.Samples, 10)},
+		[]string{"latency_p50_ms", strconv.FormatInt(report.Latency.P50, 10)},
+		[]string{"latency_p90_ms", strconv.FormatInt(report.Latency.P90, 10)},
+		[]string{"latency_p99_ms", strconv.FormatInt(report.Latency.P99, 10)},
+		[]string{"latency_p999_ms", strconv.FormatInt(report.Latency.P999, 10)},
+		[]string{"purchases", strconv.FormatInt(report.Conversion.Purchases, 10)},
+		[]string{"orders_created", strconv.FormatInt(report.Conversion.OrdersCreated, 10)},
+		[]string{"orders_paid", strconv.FormatInt(report.Conversion.OrdersPaid, 10)},
+		[]string{"created_rate", strconv.FormatFloat(report.Conversion.CreatedRate, 'f', -1, 64)},
+		[]string{"paid_rate", strconv.FormatFloat(report.Conversion.PaidRate, 'f', -1, 64)},
+		[]string{"generated_at", formatTime(&report.GeneratedAt)},
+	)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	// 每秒统计
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return err
+	}
+	header := []string{"time", "requests"}
+	for _, outcome := range Outcomes {
+		header = append(header, string(outcome))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, stats := range report.Seconds {
+		row := []string{formatTime(&stats.Time), strconv.FormatInt(stats.Requests, 10)}
+		for _, outcome := range Outcomes {
+			row = append(row, strconv.FormatInt(stats.Outcomes[outcome], 10))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
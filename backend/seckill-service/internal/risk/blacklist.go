@@ -0,0 +1,120 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 黑名单索引，分数为过期时间（秒），永久封禁为 +inf
+const blacklistIndexKey = "seckill:risk:blacklist"
+
+// 黑名单记录
+type BlacklistEntry struct {
+	UserID    int64      `json:"user_id"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 为空表示永久封禁
+}
+
+func blacklistKey(userID int64) string {
+	return fmt.Sprintf("seckill:risk:blacklist:%d", userID)
+}
+
+// 加入黑名单，ttl 为 0 表示永久封禁
+func (e *Engine) AddToBlacklist(ctx context.Context, userID int64, reason string, ttl time.Duration) (*BlacklistEntry, error) {
+	entry := &BlacklistEntry{
+		UserID:    userID,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	score := math.Inf(1)
+	if ttl > 0 {
+		expiresAt := entry.CreatedAt.Add(ttl)
+		entry.ExpiresAt = &expiresAt
+		score = float64(expiresAt.Unix())
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal blacklist entry: %w", err)
+	}
+
+	// 记录与索引不在同一个槽，分别写入
+	if err := e.client.Set(ctx, blacklistKey(userID), data, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to add user to blacklist: %w", err)
+	}
+	if err := e.client.ZAdd(ctx, blacklistIndexKey, &redis.Z{Score: score, Member: userID}).Err(); err != nil {
+		return nil, fmt.Errorf("failed to index blacklist entry: %w", err)
+	}
+
+	e.logger.Infof("User %d added to blacklist: %s", userID, reason)
+	return entry, nil
+}
+
+// 移出黑名单
+func (e *Engine) RemoveFromBlacklist(ctx context.Context, userID int64) (bool, error) {
+	deleted, err := e.client.Del(ctx, blacklistKey(userID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove user from blacklist: %w", err)
+	}
+	if err := e.client.ZRem(ctx, blacklistIndexKey, userID).Err(); err != nil {
+		return false, fmt.Errorf("failed to unindex blacklist entry: %w", err)
+	}
+
+	if deleted > 0 {
+		e.logger.Infof("User %d removed from blacklist", userID)
+	}
+	return deleted > 0, nil
+}
+
+// 获取用户的黑名单记录，不在黑名单时返回 nil
+func (e *Engine) GetBlacklistEntry(ctx context.Context, userID int64) (*BlacklistEntry, error) {
+	data, err := e.client.Get(ctx, blacklistKey(userID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blacklist entry: %w", err)
+	}
+
+	var entry BlacklistEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal blacklist entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// 列出黑名单，顺带清理索引中已过期的用户
+func (e *Engine) ListBlacklist(ctx context.Context) ([]*BlacklistEntry, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := e.client.ZRemRangeByScore(ctx, blacklistIndexKey, "-inf", now).Err(); err != nil {
+		return nil, fmt.Errorf("failed to prune blacklist: %w", err)
+	}
+
+	members, err := e.client.ZRange(ctx, blacklistIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blacklist: %w", err)
+	}
+
+	entries := make([]*BlacklistEntry, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		entry, err := e.GetBlacklistEntry(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
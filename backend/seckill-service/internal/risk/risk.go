@@ -0,0 +1,404 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"seckill-service/internal/antibot"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotEnabled        = errors.New("risk control is not enabled")
+	ErrRejected          = errors.New("request rejected by risk control")
+	ErrChallengeRequired = errors.New("risk challenge required")
+)
+
+// 风控决策
+type Decision string
+
+const (
+	DecisionAllow     Decision = "allow"
+	DecisionDegrade   Decision = "degrade"   // 放入最低优先级队列
+	DecisionChallenge Decision = "challenge" // 需要先完成工作量证明挑战
+	DecisionReject    Decision = "reject"
+)
+
+// 风险信号名
+const (
+	SignalBlacklist      = "blacklist"
+	SignalAccountAge     = "account_age"
+	SignalDevicePurchase = "device_purchases"
+	SignalIPPurchase     = "ip_purchases"
+	SignalFailureBurst   = "failure_burst"
+)
+
+// 在窗口内计数，窗口从第一次计数开始
+// KEYS[1]: 计数key
+// ARGV[1]: 窗口（毫秒）
+// 返回当前计数
+const countLuaScript = `
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+    redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`
+
+var countScript = redis.NewScript(countLuaScript)
+
+// 计数类信号：窗口内计数达到 Limit 时计 Score 分
+type CounterRule struct {
+	Limit  int64
+	Window time.Duration
+	Score  int
+}
+
+// 风控配置，某个决策的分数阈值为 0 表示不使用该决策
+type Config struct {
+	AccountKeyPrefix string        // 用户注册时间（Unix 秒）key 前缀，为空时不使用账号年龄信号
+	NewAccountAge    time.Duration // 注册时间短于该值视为新账号
+	NewAccountScore  int
+	DevicePurchases  CounterRule // 同一设备的成功购买次数
+	IPPurchases      CounterRule // 同一 IP 的成功购买次数
+	FailureBurst     CounterRule // 同一用户的失败尝试次数
+	DegradeScore     int
+	ChallengeScore   int
+	RejectScore      int
+	AssessmentTTL    time.Duration // 非放行评估结果的保存时间，供事后查询
+}
+
+// 请求来源信息，由网关转发的请求头和连接地址得到
+type Client struct {
+	DeviceID  string
+	IP        string
+	Challenge string // 被要求验证时下发的挑战
+	Nonce     string // 挑战的解
+}
+
+// 触发的风险信号
+type Signal struct {
+	Name   string `json:"name"`
+	Value  int64  `json:"value"`
+	Limit  int64  `json:"limit,omitempty"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// 风险评估结果
+type Assessment struct {
+	UserID     int64     `json:"user_id"`
+	DeviceID   string    `json:"device_id,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	Score      int       `json:"score"`
+	Decision   Decision  `json:"decision"`
+	Signals    []Signal  `json:"signals"`
+	AssessedAt time.Time `json:"assessed_at"`
+}
+
+// 用户风险说明
+type Report struct {
+	UserID         int64           `json:"user_id"`
+	Blacklist      *BlacklistEntry `json:"blacklist,omitempty"`
+	LastAssessment *Assessment     `json:"last_assessment,omitempty"` // 最近一次未放行的评估
+	Current        *Assessment     `json:"current"`                   // 按当前信号重新评估
+}
+
+// 风控引擎：从 Redis 中带过期时间的信号计算风险分数并给出决策
+type Engine struct {
+	client redis.Cmdable
+	config Config
+	signer *antibot.ChallengeSigner
+	logger *logrus.Logger
+
+	challengeDifficulty int
+	challengeTTL        time.Duration
+}
+
+// 创建风控引擎，signer 为空时挑战决策按拒绝处理
+func NewEngine(client redis.Cmdable, config Config, signer *antibot.ChallengeSigner, difficulty int, challengeTTL time.Duration, logger *logrus.Logger) *Engine {
+	return &Engine{
+		client:              client,
+		config:              config,
+		signer:              signer,
+		logger:              logger,
+		challengeDifficulty: difficulty,
+		challengeTTL:        challengeTTL,
+	}
+}
+
+func deviceKey(deviceID string) string {
+	return "seckill:risk:device:" + deviceID
+}
+
+func ipKey(ip string) string {
+	return "seckill:risk:ip:" + ip
+}
+
+func failuresKey(userID int64) string {
+	return fmt.Sprintf("seckill:risk:failures:%d", userID)
+}
+
+func assessmentKey(userID int64) string {
+	return fmt.Sprintf("seckill:risk:assessment:%d", userID)
+}
+
+// 评估请求风险，Redis 不可用时放行，风控故障不应阻断正常购买
+func (e *Engine) Assess(ctx context.Context, userID int64, client *Client) *Assessment {
+	assessment := &Assessment{
+		UserID:     userID,
+		Decision:   DecisionAllow,
+		Signals:    []Signal{},
+		AssessedAt: time.Now(),
+	}
+	if client != nil {
+		assessment.DeviceID = client.DeviceID
+		assessment.IP = client.IP
+	}
+
+	signals, err := e.collect(ctx, userID, client)
+	if err != nil {
+		e.logger.Warnf("Failed to assess risk for user %d: %v", userID, err)
+		return assessment
+	}
+
+	assessment.Signals = signals
+	for _, signal := range signals {
+		assessment.Score += signal.Score
+	}
+	assessment.Decision = e.decide(assessment)
+	return assessment
+}
+
+// 一次管道读取所有信号
+func (e *Engine) collect(ctx context.Context, userID int64, client *Client) ([]Signal, error) {
+	pipe := e.client.Pipeline()
+	blacklistCmd := pipe.Get(ctx, blacklistKey(userID))
+	failuresCmd := pipe.Get(ctx, failuresKey(userID))
+	var accountCmd, deviceCmd, ipCmd *redis.StringCmd
+	if e.config.AccountKeyPrefix != "" {
+		accountCmd = pipe.Get(ctx, e.config.AccountKeyPrefix+strconv.FormatInt(userID, 10))
+	}
+	if client != nil && client.DeviceID != "" {
+		deviceCmd = pipe.Get(ctx, deviceKey(client.DeviceID))
+	}
+	if client != nil && client.IP != "" {
+		ipCmd = pipe.Get(ctx, ipKey(client.IP))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	signals := []Signal{}
+
+	// 黑名单直接达到拒绝分数
+	if data, err := blacklistCmd.Bytes(); err == nil {
+		var entry BlacklistEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blacklist entry: %w", err)
+		}
+		signals = append(signals, Signal{
+			Name:   SignalBlacklist,
+			Value:  1,
+			Score:  e.blacklistScore(),
+			Detail: entry.Reason,
+		})
+	}
+
+	if accountCmd != nil {
+		if createdAt, err := accountCmd.Int64(); err == nil {
+			age := time.Since(time.Unix(createdAt, 0))
+			if age < e.config.NewAccountAge {
+				signals = append(signals, Signal{
+					Name:   SignalAccountAge,
+					Value:  int64(age.Seconds()),
+					Limit:  int64(e.config.NewAccountAge.Seconds()),
+					Score:  e.config.NewAccountScore,
+					Detail: "account registered " + age.Truncate(time.Second).String() + " ago",
+				})
+			}
+		}
+	}
+
+	counters := []struct {
+		name string
+		cmd  *redis.StringCmd
+		rule CounterRule
+	}{
+		{SignalDevicePurchase, deviceCmd, e.config.DevicePurchases},
+		{SignalIPPurchase, ipCmd, e.config.IPPurchases},
+		{SignalFailureBurst, failuresCmd, e.config.FailureBurst},
+	}
+	for _, counter := range counters {
+		if counter.cmd == nil || counter.rule.Limit <= 0 {
+			continue
+		}
+		count, err := counter.cmd.Int64()
+		if err != nil || count < counter.rule.Limit {
+			continue
+		}
+		signals = append(signals, Signal{
+			Name:  counter.name,
+			Value: count,
+			Limit: counter.rule.Limit,
+			Score: counter.rule.Score,
+		})
+	}
+	return signals, nil
+}
+
+// 黑名单用户的分数，保证不低于拒绝阈值
+func (e *Engine) blacklistScore() int {
+	return max(e.config.RejectScore, 100)
+}
+
+// 按分数从高到低匹配决策
+func (e *Engine) decide(assessment *Assessment) Decision {
+	for _, signal := range assessment.Signals {
+		if signal.Name == SignalBlacklist {
+			return DecisionReject
+		}
+	}
+
+	score := assessment.Score
+	switch {
+	case e.config.RejectScore > 0 && score >= e.config.RejectScore:
+		return DecisionReject
+	case e.config.ChallengeScore > 0 && score >= e.config.ChallengeScore:
+		if e.signer == nil {
+			return DecisionReject
+		}
+		return DecisionChallenge
+	case e.config.DegradeScore > 0 && score >= e.config.DegradeScore:
+		return DecisionDegrade
+	}
+	return DecisionAllow
+}
+
+// 评估请求并校验挑战的解，返回实际采用的决策；未放行的评估结果保存备查
+func (e *Engine) Check(ctx context.Context, productID, userID int64, client *Client) (*Assessment, error) {
+	assessment := e.Assess(ctx, userID, client)
+
+	var err error
+	switch assessment.Decision {
+	case DecisionReject:
+		err = ErrRejected
+	case DecisionChallenge:
+		if client == nil || client.Challenge == "" {
+			err = ErrChallengeRequired
+		} else if verifyErr := e.signer.Verify(client.Challenge, client.Nonce, productID, userID, time.Now()); verifyErr != nil {
+			err = fmt.Errorf("%w: %v", ErrChallengeRequired, verifyErr)
+		} else {
+			// 完成挑战后按降级处理
+			assessment.Decision = DecisionDegrade
+		}
+	}
+
+	if assessment.Decision != DecisionAllow {
+		e.saveAssessment(ctx, assessment)
+	}
+	return assessment, err
+}
+
+// 签发风控挑战
+func (e *Engine) Challenge(productID, userID int64) (*antibot.Challenge, error) {
+	if e.signer == nil {
+		return nil, ErrRejected
+	}
+
+	expiresAt := time.Now().Add(e.challengeTTL)
+	challenge, err := e.signer.Sign(productID, userID, e.challengeDifficulty, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &antibot.Challenge{
+		ProductID:  productID,
+		UserID:     userID,
+		Challenge:  challenge,
+		Difficulty: e.challengeDifficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+func (e *Engine) saveAssessment(ctx context.Context, assessment *Assessment) {
+	if e.config.AssessmentTTL <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(assessment)
+	if err != nil {
+		e.logger.Warnf("Failed to marshal risk assessment: %v", err)
+		return
+	}
+	if err := e.client.Set(ctx, assessmentKey(assessment.UserID), data, e.config.AssessmentTTL).Err(); err != nil {
+		e.logger.Warnf("Failed to save risk assessment for user %d: %v", assessment.UserID, err)
+	}
+}
+
+// 最近一次未放行的评估结果，不存在时返回 nil
+func (e *Engine) LastAssessment(ctx context.Context, userID int64) (*Assessment, error) {
+	data, err := e.client.Get(ctx, assessmentKey(userID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk assessment: %w", err)
+	}
+
+	var assessment Assessment
+	if err := json.Unmarshal(data, &assessment); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal risk assessment: %w", err)
+	}
+	return &assessment, nil
+}
+
+// 说明用户当前与最近一次的评分依据
+func (e *Engine) Explain(ctx context.Context, userID int64, client *Client) (*Report, error) {
+	entry, err := e.GetBlacklistEntry(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	last, err := e.LastAssessment(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{
+		UserID:         userID,
+		Blacklist:      entry,
+		LastAssessment: last,
+		Current:        e.Assess(ctx, userID, client),
+	}, nil
+}
+
+// 记录成功购买，累加设备与 IP 的购买次数
+func (e *Engine) RecordPurchase(ctx context.Context, client *Client) {
+	if client == nil {
+		return
+	}
+	if client.DeviceID != "" && e.config.DevicePurchases.Limit > 0 {
+		e.count(ctx, deviceKey(client.DeviceID), e.config.DevicePurchases.Window)
+	}
+	if client.IP != "" && e.config.IPPurchases.Limit > 0 {
+		e.count(ctx, ipKey(client.IP), e.config.IPPurchases.Window)
+	}
+}
+
+// 记录失败尝试
+func (e *Engine) RecordFailure(ctx context.Context, userID int64) {
+	if e.config.FailureBurst.Limit > 0 {
+		e.count(ctx, failuresKey(userID), e.config.FailureBurst.Window)
+	}
+}
+
+func (e *Engine) count(ctx context.Context, key string, window time.Duration) {
+	if err := countScript.Run(ctx, e.client, []string{key}, window.Milliseconds()).Err(); err != nil {
+		e.logger.Warnf("Failed to record risk signal %s: %v", key, err)
+	}
+}
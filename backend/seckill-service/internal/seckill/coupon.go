@@ -0,0 +1,184 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 发券活动：库存是预先导入 Redis 列表的券码，购买脚本原子地弹出一个券码并绑定到用户。
+// 库存key 与券码池同步扣减，库存统计、售罄标记、活动报告等沿用普通活动的逻辑。
+
+// 单个券码的最大长度
+const MaxCouponCodeLength = 64
+
+var (
+	ErrInvalidCouponCode   = errors.New("invalid coupon code")
+	ErrInsufficientCoupons = errors.New("insufficient coupon codes")
+)
+
+// 活动类型缓存时间，重新预热修改活动类型后其他节点最迟在该时间后生效
+const couponFlagCacheTTL = 10 * time.Second
+
+// 缓存的活动类型
+type couponFlag struct {
+	coupon   bool
+	loadedAt time.Time
+}
+
+// 商品当前的活动是否为发券活动
+func (sc *SeckillCore) IsCouponActivity(ctx context.Context, productID int64) (bool, error) {
+	sc.couponsMutex.RLock()
+	cached, exists := sc.coupons[productID]
+	sc.couponsMutex.RUnlock()
+	if exists && time.Since(cached.loadedAt) < couponFlagCacheTTL {
+		return cached.coupon, nil
+	}
+
+	coupon := false
+	activity, err := sc.GetActivity(ctx, productID)
+	switch {
+	case err == nil:
+		coupon = activity.IsCoupon()
+	case !errors.Is(err, ErrActivityNotFound):
+		return false, err
+	}
+
+	sc.setCouponFlag(productID, coupon)
+	return coupon, nil
+}
+
+func (sc *SeckillCore) setCouponFlag(productID int64, coupon bool) {
+	sc.couponsMutex.Lock()
+	sc.coupons[productID] = couponFlag{coupon: coupon, loadedAt: time.Now()}
+	sc.couponsMutex.Unlock()
+}
+
+// 校验券码：非空、不超过最大长度、只包含可见 ASCII 字符
+func ValidateCouponCode(code string) error {
+	if code == "" || len(code) > MaxCouponCodeLength {
+		return fmt.Errorf("%w: length must be between 1 and %d", ErrInvalidCouponCode, MaxCouponCodeLength)
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] <= ' ' || code[i] > '~' {
+			return fmt.Errorf("%w: %q contains invalid characters", ErrInvalidCouponCode, code)
+		}
+	}
+	return nil
+}
+
+// 向券码池追加券码，返回追加后的券码数量。券码池不随库存过期，清理活动时删除
+func (sc *SeckillCore) AddCouponCodes(ctx context.Context, productID int64, codes []string) (int64, error) {
+	values := make([]interface{}, len(codes))
+	for i, code := range codes {
+		if err := ValidateCouponCode(code); err != nil {
+			return 0, err
+		}
+		values[i] = code
+	}
+
+	size, err := sc.redisClient.RPush(ctx, CouponsKey(productID), values...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add coupon codes: %w", err)
+	}
+	return size, nil
+}
+
+// 券码池中尚未发出的券码数量
+func (sc *SeckillCore) CouponPoolSize(ctx context.Context, productID int64) (int64, error) {
+	size, err := sc.redisClient.LLen(ctx, CouponsKey(productID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get coupon pool size: %w", err)
+	}
+	return size, nil
+}
+
+// 预热发券活动前检查券码池是否足够发放活动库存
+func (sc *SeckillCore) checkCouponPool(ctx context.Context, activity *SeckillActivity) error {
+	size, err := sc.CouponPoolSize(ctx, activity.ProductID)
+	if err != nil {
+		return err
+	}
+	if size < activity.Stock {
+		return fmt.Errorf("%w: stock %d, coupon codes %d", ErrInsufficientCoupons, activity.Stock, size)
+	}
+	return nil
+}
+
+// 执行发券，每个用户限领一张；event 不为空时由脚本追加券码后写入 outbox
+func (sc *SeckillCore) executeCoupon(ctx context.Context, req *SeckillRequest, event *OrderEvent) (*SeckillResult, error) {
+	if req.Quantity != 1 {
+		return &SeckillResult{
+			Code:    ResultInvalidQuantity,
+			Message: sc.ResultMessage(ResultInvalidQuantity),
+			Success: false,
+		}, nil
+	}
+
+	record, err := json.Marshal(purchaseRecord{
+		Quantity:     req.Quantity,
+		PurchaseTime: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return &SeckillResult{
+			Code:    ResultSystemError,
+			Message: "系统错误",
+			Success: false,
+		}, fmt.Errorf("failed to marshal purchase record: %w", err)
+	}
+
+	keys := []string{
+		StockKey(req.ProductID),
+		UsersKey(req.ProductID),
+		OutboxStreamKey(req.ProductID),
+		PurchasesKey(req.ProductID),
+		CouponsKey(req.ProductID),
+	}
+	args := []interface{}{req.UserID, "", "", string(record)}
+	if event != nil {
+		args[1] = event.Type
+		args[2] = string(event.Payload)
+	}
+
+	result := sc.evalScript(ctx, "coupon", keys, args...)
+	if err := result.Err(); err != nil {
+		sc.logger.Errorf("Failed to execute coupon script: %v", err)
+		return &SeckillResult{
+			Code:    ResultSystemError,
+			Message: "系统错误",
+			Success: false,
+		}, err
+	}
+
+	seckillResult, err := sc.parseSeckillResult(result.Val())
+	if err != nil {
+		return seckillResult, err
+	}
+	if values, ok := result.Val().([]interface{}); ok && len(values) >= 3 {
+		seckillResult.CouponCode, _ = values[2].(string)
+	}
+
+	sc.markSoldOutIfEmpty(ctx, req.ProductID, seckillResult)
+	return seckillResult, nil
+}
+
+// 回滚发券：券码放回券码池头部，优先发给下一个用户
+func (sc *SeckillCore) rollbackCoupon(ctx context.Context, productID, userID int64) error {
+	keys := []string{StockKey(productID), UsersKey(productID), PurchasesKey(productID), CouponsKey(productID)}
+
+	result := sc.evalScript(ctx, "coupon_rollback", keys, userID)
+	if err := result.Err(); err != nil {
+		sc.logger.Errorf("Failed to rollback coupon: %v", err)
+		return err
+	}
+
+	newStock := result.Val()
+	sc.logger.Infof("Rollback coupon for product %d, user %d, new stock: %v", productID, userID, newStock)
+
+	if restored, _ := newStock.(int64); restored > 0 {
+		sc.clearSoldOut(ctx, productID)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 活动在库存服务中的库存预留，预热时记录，活动结束后按剩余库存释放
+type InventoryReservation struct {
+	ID         string     `json:"id"`
+	ProductID  int64      `json:"product_id"`
+	Quantity   int64      `json:"quantity"`
+	EndTime    time.Time  `json:"end_time"` // 活动结束时间，活动信息过期后仍可结算
+	ReservedAt time.Time  `json:"reserved_at"`
+	SettledAt  *time.Time `json:"settled_at,omitempty"`
+	Returned   int64      `json:"returned"`          // 释放时归还库存服务的数量
+	Aborted    bool       `json:"aborted,omitempty"` // 预热失败，预留已全部归还
+}
+
+// 活动的预留ID，同一场活动重复预热时保持不变，库存服务据此调整预留数量；
+// 预热失败归还预留后，重试时在其后追加时间戳作为新的预留ID
+func InventoryReservationID(activity *SeckillActivity) string {
+	return fmt.Sprintf("seckill-%d-%d", activity.ProductID, activity.StartTime.Unix())
+}
+
+// 获取活动的库存预留记录，未预留时返回 nil
+func (sc *SeckillCore) GetInventoryReservation(ctx context.Context, productID int64) (*InventoryReservation, error) {
+	data, err := sc.redisClient.Get(ctx, InventoryReservationKey(productID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory reservation: %w", err)
+	}
+
+	var reservation InventoryReservation
+	if err := json.Unmarshal(data, &reservation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal inventory reservation: %w", err)
+	}
+	return &reservation, nil
+}
+
+// 保存活动的库存预留记录，随活动清理删除
+func (sc *SeckillCore) SaveInventoryReservation(ctx context.Context, reservation *InventoryReservation) error {
+	data, err := json.Marshal(reservation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory reservation: %w", err)
+	}
+	if err := sc.redisClient.Set(ctx, InventoryReservationKey(reservation.ProductID), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save inventory reservation: %w", err)
+	}
+	return nil
+}
+
+// 剩余库存，库存key不存在时为 0
+func (sc *SeckillCore) RemainingStock(ctx context.Context, productID int64) (int64, error) {
+	stock, err := sc.redisClient.Get(ctx, StockKey(productID)).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("failed to get stock: %w", err)
+	}
+	return stock, nil
+}
+
+// 待支付的库存预留数与件数
+func (sc *SeckillCore) PendingReservations(ctx context.Context, productID int64) (int64, int64, error) {
+	members, err := sc.redisClient.ZRangeByScore(ctx, ReservationsKey(productID), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query reservations: %w", err)
+	}
+
+	var quantity int64
+	for _, member := range members {
+		var userID, count int64
+		if _, err := fmt.Sscanf(member, "%d:%d", &userID, &count); err == nil {
+			quantity += count
+		}
+	}
+	return int64(len(members)), quantity, nil
+}
+
+// 预留是否属于该活动（包括预热失败后重试使用的预留ID）
+func (r *InventoryReservation) BelongsTo(activity *SeckillActivity) bool {
+	id := InventoryReservationID(activity)
+	return r.ID == id || strings.HasPrefix(r.ID, id+"-")
+}
@@ -0,0 +1,56 @@
+package seckill
+
+import "fmt"
+
+// 活动相关的 key 都以 {商品ID} 作为 hash tag，Redis Cluster 下同一活动的 key 落在同一个槽，
+// 脚本可以在一次调用中同时访问库存、购买记录、预留与 outbox。
+
+// 已预热活动的商品集合
+const ActivitiesKey = "seckill:activities"
+
+// 所有 outbox 流的集合，供 outbox 中继发现各活动的流
+const OutboxStreamsKey = "seckill:outbox:streams"
+
+func hashTag(productID int64) string {
+	return fmt.Sprintf("{%d}", productID)
+}
+
+// 库存key
+func StockKey(productID int64) string {
+	return "seckill:stock:" + hashTag(productID)
+}
+
+// 用户购买记录key
+func UsersKey(productID int64) string {
+	return "seckill:users:" + hashTag(productID)
+}
+
+// 活动信息key
+func ActivityKey(productID int64) string {
+	return "seckill:activity:" + hashTag(productID)
+}
+
+// 用户购买详情key（hash，字段为用户ID）
+func PurchasesKey(productID int64) string {
+	return "seckill:purchases:" + hashTag(productID)
+}
+
+// 库存预留记录key
+func ReservationsKey(productID int64) string {
+	return "seckill:reservations:" + hashTag(productID)
+}
+
+// 活动在库存服务中的库存预留记录key
+func InventoryReservationKey(productID int64) string {
+	return "seckill:inventory_reservation:" + hashTag(productID)
+}
+
+// 订单事件 outbox 流，每个活动一个，与库存在同一个槽
+func OutboxStreamKey(productID int64) string {
+	return "seckill:outbox:" + hashTag(productID)
+}
+
+// 发券活动的券码池（list），购买时从头部弹出
+func CouponsKey(productID int64) string {
+	return "seckill:coupons:" + hashTag(productID)
+}
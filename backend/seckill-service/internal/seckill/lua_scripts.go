@@ -3,9 +3,9 @@ package seckill
 // 秒杀 Lua 脚本 - 原子性库存扣减 + 用户去重
 const SeckillLuaScript = `
 -- 秒杀 Lua 脚本
--- KEYS[1]: 库存key (seckill:stock:productId)
--- KEYS[2]: 用户购买记录key (seckill:users:productId)
--- KEYS[3]: 活动信息key (seckill:activity:productId)
+-- KEYS[1]: 库存key (seckill:stock:{productId})
+-- KEYS[2]: 用户购买记录key (seckill:users:{productId})
+-- KEYS[3]: 活动信息key (seckill:activity:{productId})
 -- ARGV[1]: 用户ID
 -- ARGV[2]: 购买数量
 -- ARGV[3]: 当前时间戳
@@ -98,15 +98,27 @@ return {RESULT_SUCCESS, new_stock}
 // 简化版秒杀脚本（不依赖 cjson）
 const SeckillSimpleLuaScript = `
 -- 简化版秒杀 Lua 脚本
--- KEYS[1]: 库存key (seckill:stock:productId)
--- KEYS[2]: 用户购买记录key (seckill:users:productId)
+-- KEYS[1]: 库存key (seckill:stock:{productId})
+-- KEYS[2]: 用户购买记录key (seckill:users:{productId})
+-- KEYS[3]: 订单事件 outbox 流 (seckill:outbox:{productId})
+-- KEYS[4]: 库存预留记录key (seckill:reservations:{productId})
+-- KEYS[5]: 用户购买详情key (seckill:purchases:{productId})
 -- ARGV[1]: 用户ID
 -- ARGV[2]: 购买数量
+-- ARGV[3]: 订单事件类型，为空时不写入 outbox
+-- ARGV[4]: 订单事件内容 (JSON)
+-- ARGV[5]: 预留过期时间戳（毫秒），为 0 时不创建预留
+-- ARGV[6]: 购买详情 (JSON)
 
 local stock_key = KEYS[1]
 local users_key = KEYS[2]
+local outbox_key = KEYS[3]
+local reservations_key = KEYS[4]
+local purchases_key = KEYS[5]
 local user_id = ARGV[1]
 local quantity = tonumber(ARGV[2])
+local event_type = ARGV[3] or ''
+local reserve_until = tonumber(ARGV[5] or '0')
 
 -- 返回码定义
 local RESULT_SUCCESS = 1
@@ -134,17 +146,27 @@ end
 
 current_stock = tonumber(current_stock)
 
--- 检查库存是否足够
+-- 检查库存是否足够，同时返回当前库存用于判断是否售罄
 if current_stock < quantity then
-    return RESULT_INSUFFICIENT_STOCK
+    return {RESULT_INSUFFICIENT_STOCK, current_stock}
 end
 
 -- 扣减库存
-local new_stock = current_stock - quantity
-redis.call('SET', stock_key, new_stock)
+local new_stock = redis.call('DECRBY', stock_key, quantity)
 
--- 添加用户购买记录
+-- 添加用户购买记录与购买详情
 redis.call('SADD', users_key, user_id)
+redis.call('HSET', purchases_key, user_id, ARGV[6])
+
+-- 创建库存预留，到期未确认时由回收任务归还库存
+if reserve_until > 0 then
+    redis.call('ZADD', reservations_key, reserve_until, user_id .. ':' .. quantity)
+end
+
+-- 写入订单事件，与库存扣减在同一原子操作中完成
+if event_type ~= '' then
+    redis.call('XADD', outbox_key, '*', 'type', event_type, 'payload', ARGV[4])
+end
 
 -- 返回成功和剩余库存
 return {RESULT_SUCCESS, new_stock}
@@ -153,16 +175,28 @@ return {RESULT_SUCCESS, new_stock}
 // 库存回滚脚本
 const StockRollbackLuaScript = `
 -- 库存回滚 Lua 脚本
--- KEYS[1]: 库存key (seckill:stock:productId)
--- KEYS[2]: 用户购买记录key (seckill:users:productId)
+-- KEYS[1]: 库存key (seckill:stock:{productId})
+-- KEYS[2]: 用户购买记录key (seckill:users:{productId})
+-- KEYS[3]: 用户购买详情key (seckill:purchases:{productId})
+-- KEYS[4]: 库存预留记录key (seckill:reservations:{productId})，可选
 -- ARGV[1]: 用户ID
 -- ARGV[2]: 回滚数量
+-- ARGV[3]: 预留成员，可选；指定时仅在预留仍存在（未确认）时回滚
 
 local stock_key = KEYS[1]
 local users_key = KEYS[2]
+local purchases_key = KEYS[3]
+local reservations_key = KEYS[4]
 local user_id = ARGV[1]
 local quantity = tonumber(ARGV[2])
 
+-- 释放预留：预留已被确认或已释放时不回滚
+if reservations_key and ARGV[3] then
+    if redis.call('ZREM', reservations_key, ARGV[3]) == 0 then
+        return 0
+    end
+end
+
 -- 检查用户是否已购买
 local user_bought = redis.call('SISMEMBER', users_key, user_id)
 if user_bought == 0 then
@@ -172,27 +206,142 @@ end
 -- 回滚库存
 redis.call('INCRBY', stock_key, quantity)
 
--- 移除用户购买记录
+-- 移除用户购买记录与购买详情
 redis.call('SREM', users_key, user_id)
+redis.call('HDEL', purchases_key, user_id)
 
 -- 获取回滚后的库存
 local new_stock = redis.call('GET', stock_key)
 return tonumber(new_stock)
 `
 
+// 发券脚本 - 原子性弹出券码 + 用户去重
+const CouponSeckillLuaScript = `
+-- 发券 Lua 脚本
+-- KEYS[1]: 库存key (seckill:stock:{productId})，与券码池同步扣减
+-- KEYS[2]: 用户购买记录key (seckill:users:{productId})
+-- KEYS[3]: 订单事件 outbox 流 (seckill:outbox:{productId})
+-- KEYS[4]: 用户购买详情key (seckill:purchases:{productId})
+-- KEYS[5]: 券码池 (seckill:coupons:{productId})
+-- ARGV[1]: 用户ID
+-- ARGV[2]: 发券事件类型，为空时不写入 outbox
+-- ARGV[3]: 发券事件内容 (JSON，不含券码)
+-- ARGV[4]: 购买详情 (JSON，不含券码)
+-- 成功时返回 {1, 剩余库存, 券码}
+
+local stock_key = KEYS[1]
+local users_key = KEYS[2]
+local outbox_key = KEYS[3]
+local purchases_key = KEYS[4]
+local coupons_key = KEYS[5]
+local user_id = ARGV[1]
+local event_type = ARGV[2] or ''
+
+local RESULT_SUCCESS = 1
+local RESULT_STOCK_NOT_FOUND = -1
+local RESULT_INSUFFICIENT_STOCK = -2
+local RESULT_USER_ALREADY_BOUGHT = -3
+
+-- 在 JSON 对象末尾追加券码字段，不重新编码原有字段，避免大整数丢失精度
+local function with_code(json, code)
+    return string.sub(json, 1, -2) .. ',"coupon_code":' .. cjson.encode(code) .. '}'
+end
+
+-- 检查用户是否已经领取
+if redis.call('SISMEMBER', users_key, user_id) == 1 then
+    return RESULT_USER_ALREADY_BOUGHT
+end
+
+-- 检查库存
+local stock = redis.call('GET', stock_key)
+if not stock then
+    return RESULT_STOCK_NOT_FOUND
+end
+stock = tonumber(stock)
+if stock < 1 then
+    return {RESULT_INSUFFICIENT_STOCK, stock}
+end
+
+-- 弹出券码，券码池被外部清空时按售罄处理
+local code = redis.call('LPOP', coupons_key)
+if not code then
+    return {RESULT_INSUFFICIENT_STOCK, 0}
+end
+local new_stock = redis.call('DECR', stock_key)
+
+-- 添加用户领取记录，券码记录在购买详情中
+redis.call('SADD', users_key, user_id)
+redis.call('HSET', purchases_key, user_id, with_code(ARGV[4], code))
+
+-- 写入发券事件
+if event_type ~= '' then
+    redis.call('XADD', outbox_key, '*', 'type', event_type, 'payload', with_code(ARGV[3], code))
+end
+
+return {RESULT_SUCCESS, new_stock, code}
+`
+
+// 发券回滚脚本
+const CouponRollbackLuaScript = `
+-- 发券回滚 Lua 脚本
+-- KEYS[1]: 库存key (seckill:stock:{productId})
+-- KEYS[2]: 用户购买记录key (seckill:users:{productId})
+-- KEYS[3]: 用户购买详情key (seckill:purchases:{productId})
+-- KEYS[4]: 券码池 (seckill:coupons:{productId})
+-- ARGV[1]: 用户ID
+
+local stock_key = KEYS[1]
+local users_key = KEYS[2]
+local purchases_key = KEYS[3]
+local coupons_key = KEYS[4]
+local user_id = ARGV[1]
+
+if redis.call('SISMEMBER', users_key, user_id) == 0 then
+    return 0  -- 用户未领取，无需回滚
+end
+
+-- 券码放回券码池头部
+local detail = redis.call('HGET', purchases_key, user_id)
+if detail then
+    local code = cjson.decode(detail).coupon_code
+    if code then
+        redis.call('LPUSH', coupons_key, code)
+    end
+end
+
+redis.call('SREM', users_key, user_id)
+redis.call('HDEL', purchases_key, user_id)
+return redis.call('INCR', stock_key)
+`
+
 // 批量检查用户购买状态脚本
 const BatchCheckUserScript = `
 -- 批量检查用户购买状态
--- KEYS[1]: 用户购买记录key (seckill:users:productId)
+-- KEYS[1]: 用户购买记录key (seckill:users:{productId})
+-- KEYS[2]: 用户购买详情key (seckill:purchases:{productId})
+-- KEYS[3]: 库存预留记录key (seckill:reservations:{productId})
 -- ARGV[1..n]: 用户ID列表
+-- 返回每个用户的 {是否购买, 购买详情, 预留过期时间戳}，没有详情或预留时为空字符串
 
 local users_key = KEYS[1]
+local purchases_key = KEYS[2]
+local reservations_key = KEYS[3]
 local result = {}
 
 for i = 1, #ARGV do
     local user_id = ARGV[i]
     local bought = redis.call('SISMEMBER', users_key, user_id)
-    result[i] = bought
+    local detail = ''
+    local reserved_until = ''
+    if bought == 1 then
+        detail = redis.call('HGET', purchases_key, user_id) or ''
+        if detail ~= '' then
+            -- 预留成员为 用户ID:购买数量
+            local quantity = cjson.decode(detail).quantity
+            reserved_until = redis.call('ZSCORE', reservations_key, user_id .. ':' .. quantity) or ''
+        end
+    end
+    result[i] = {bought, detail, reserved_until}
 end
 
 return result
@@ -201,9 +350,9 @@ return result
 // 获取秒杀统计信息脚本
 const SeckillStatsScript = `
 -- 获取秒杀统计信息
--- KEYS[1]: 库存key (seckill:stock:productId)
--- KEYS[2]: 用户购买记录key (seckill:users:productId)
--- KEYS[3]: 活动信息key (seckill:activity:productId)
+-- KEYS[1]: 库存key (seckill:stock:{productId})
+-- KEYS[2]: 用户购买记录key (seckill:users:{productId})
+-- KEYS[3]: 活动信息key (seckill:activity:{productId})
 
 local stock_key = KEYS[1]
 local users_key = KEYS[2]
@@ -230,3 +379,13 @@ return {
     activity_info or ""
 }
 `
+
+// 库存预留确认脚本
+const ConfirmReservationScript = `
+-- 确认库存预留（订单已支付）
+-- KEYS[1]: 库存预留记录key (seckill:reservations:{productId})
+-- ARGV[1]: 预留成员 (userId:quantity)
+
+-- 返回 1 表示确认成功，0 表示预留不存在（已过期释放或已确认）
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`
@@ -3,9 +3,15 @@ package seckill
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"seckill-service/internal/idgen"
+
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 )
@@ -23,6 +29,21 @@ const (
 	ResultSystemError        = -8
 	ResultSystemBusy         = -9
 	ResultRequestTimeout     = -10
+	ResultNoPurchaseRight    = -11
+	ResultRiskRejected       = -12
+	ResultRiskChallenge      = -13
+)
+
+// 活动类型
+const (
+	ActivityTypeFCFS    = "fcfs"    // 先到先得（默认）
+	ActivityTypeLottery = "lottery" // 抽签
+	ActivityTypeCoupon  = "coupon"  // 限量发券，库存为预先导入的券码
+)
+
+var (
+	ErrActivityNotFound = errors.New("activity not found")
+	ErrSoldOut          = errors.New("product sold out")
 )
 
 // 秒杀请求
@@ -39,17 +60,37 @@ type SeckillResult struct {
 	Success        bool   `json:"success"`
 	RemainingStock int64  `json:"remaining_stock"`
 	OrderID        string `json:"order_id,omitempty"`
+	CouponCode     string `json:"coupon_code,omitempty"` // 发券活动抢到的券码
 }
 
 // 秒杀活动信息
 type SeckillActivity struct {
-	ProductID   int64     `json:"product_id"`
-	ProductName string    `json:"product_name"`
-	Price       float64   `json:"price"`
-	Stock       int64     `json:"stock"`
-	StartTime   time.Time `json:"start_time"`
-	EndTime     time.Time `json:"end_time"`
-	Status      string    `json:"status"`
+	ProductID   int64            `json:"product_id"`
+	ProductName string           `json:"product_name"`
+	Price       float64          `json:"price"`
+	Stock       int64            `json:"stock"`
+	StartTime   time.Time        `json:"start_time"`
+	EndTime     time.Time        `json:"end_time"`
+	Status      string           `json:"status"`
+	Type        string           `json:"type,omitempty"`
+	Lottery     *LotterySettings `json:"lottery,omitempty"`
+}
+
+// 抽签活动配置：登记截止后开奖，中签者在活动开始后凭资格购买
+type LotterySettings struct {
+	EntryStartTime     time.Time `json:"entry_start_time"`
+	EntryEndTime       time.Time `json:"entry_end_time"`
+	ClaimWindowSeconds int64     `json:"claim_window_seconds"` // 中签后的购买期限，为 0 时使用服务配置
+}
+
+// 是否为抽签活动
+func (a *SeckillActivity) IsLottery() bool {
+	return a.Type == ActivityTypeLottery
+}
+
+// 是否为发券活动
+func (a *SeckillActivity) IsCoupon() bool {
+	return a.Type == ActivityTypeCoupon
 }
 
 // 秒杀统计信息
@@ -62,14 +103,33 @@ type SeckillStats struct {
 
 // 用户购买信息
 type UserPurchaseInfo struct {
-	UserID       int64     `json:"user_id"`
-	ProductID    int64     `json:"product_id"`
-	Quantity     int64     `json:"quantity"`
-	PurchaseTime time.Time `json:"purchase_time"`
-	Status       string    `json:"status"`
+	UserID        int64      `json:"user_id"`
+	ProductID     int64      `json:"product_id"`
+	Purchased     bool       `json:"purchased"`
+	Quantity      int64      `json:"quantity"`
+	OrderID       string     `json:"order_id,omitempty"`
+	CouponCode    string     `json:"coupon_code,omitempty"` // 发券活动绑定的券码
+	PurchaseTime  *time.Time `json:"purchase_time,omitempty"`
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"` // 待支付预留的过期时间
+	Status        string     `json:"status"`
 }
 
-// Redis 客户端接口
+// 用户购买状态
+const (
+	PurchaseStatusNone     = "none"     // 未购买
+	PurchaseStatusReserved = "reserved" // 已抢到，库存预留待支付
+	PurchaseStatusSuccess  = "success"  // 已抢到，预留已确认或未启用预留
+)
+
+// 秒杀成功时写入的购买详情
+type purchaseRecord struct {
+	Quantity     int64  `json:"quantity"`
+	OrderID      string `json:"order_id"`
+	PurchaseTime int64  `json:"purchase_time"`         // 毫秒时间戳
+	CouponCode   string `json:"coupon_code,omitempty"` // 发券活动由脚本在弹出券码后追加
+}
+
+// Redis 客户端接口，*redis.Client 与 *redis.ClusterClient 均满足
 type RedisClient interface {
 	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
 	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd
@@ -77,47 +137,129 @@ type RedisClient interface {
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	SScan(ctx context.Context, key string, cursor uint64, match string, count int64) *redis.ScanCmd
+	XLen(ctx context.Context, stream string) *redis.IntCmd
+	RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	LLen(ctx context.Context, key string) *redis.IntCmd
 }
 
 // 秒杀核心服务
 type SeckillCore struct {
-	redisClient RedisClient
-	logger      *logrus.Logger
-	scriptSHA   map[string]string // 预加载的脚本 SHA
+	redisClient    RedisClient
+	idGenerator    *idgen.Generator
+	logger         *logrus.Logger
+	scriptMutex    sync.RWMutex
+	scriptSHA      map[string]string // 预加载的脚本 SHA
+	reloadMutex    sync.Mutex        // 防止 NOSCRIPT 时并发重复加载脚本
+	reservationTTL time.Duration     // 库存预留时长，为 0 时直接扣减
+	soldOut        *SoldOutFlags     // 本地售罄标记，为空时不启用
+
+	couponsMutex sync.RWMutex
+	coupons      map[int64]couponFlag // 各商品是否为发券活动的缓存
+}
+
+// 订单ID前缀
+const OrderIDPrefix = "SK"
+
+// 订单事件（随库存扣减原子写入 outbox）
+type OrderEvent struct {
+	Type    string
+	Payload []byte
 }
 
 // 创建秒杀核心服务
-func NewSeckillCore(redisClient RedisClient, logger *logrus.Logger) *SeckillCore {
+func NewSeckillCore(redisClient RedisClient, idGenerator *idgen.Generator, logger *logrus.Logger) *SeckillCore {
 	return &SeckillCore{
 		redisClient: redisClient,
+		idGenerator: idGenerator,
 		logger:      logger,
 		scriptSHA:   make(map[string]string),
+		coupons:     make(map[int64]couponFlag),
 	}
 }
 
-// 初始化脚本
-func (sc *SeckillCore) InitScripts(ctx context.Context) error {
-	scripts := map[string]string{
-		"seckill":     SeckillSimpleLuaScript,
-		"rollback":    StockRollbackLuaScript,
-		"batch_check": BatchCheckUserScript,
-		"stats":       SeckillStatsScript,
+// 启用两阶段库存预留，预留在 ttl 内未确认则归还库存
+func (sc *SeckillCore) EnableReservation(ttl time.Duration) {
+	sc.reservationTTL = ttl
+}
+
+// 启用本地售罄标记
+func (sc *SeckillCore) EnableSoldOutFlags(flags *SoldOutFlags) {
+	sc.soldOut = flags
+}
+
+// 商品是否已标记售罄
+func (sc *SeckillCore) IsSoldOut(productID int64) bool {
+	return sc.soldOut != nil && sc.soldOut.IsSoldOut(productID)
+}
+
+// 清除售罄标记
+func (sc *SeckillCore) clearSoldOut(ctx context.Context, productID int64) {
+	if sc.soldOut != nil {
+		sc.soldOut.Clear(ctx, productID)
 	}
+}
+
+// 预加载的脚本
+var coreScripts = map[string]string{
+	"seckill":         SeckillSimpleLuaScript,
+	"rollback":        StockRollbackLuaScript,
+	"batch_check":     BatchCheckUserScript,
+	"stats":           SeckillStatsScript,
+	"coupon":          CouponSeckillLuaScript,
+	"coupon_rollback": CouponRollbackLuaScript,
+}
 
-	for name, script := range scripts {
+// 初始化脚本，集群模式下脚本会加载到所有主节点
+func (sc *SeckillCore) InitScripts(ctx context.Context) error {
+	for name, script := range coreScripts {
 		result := sc.redisClient.ScriptLoad(ctx, script)
 		if result.Err() != nil {
 			return fmt.Errorf("failed to load script %s: %w", name, result.Err())
 		}
+
+		sc.scriptMutex.Lock()
 		sc.scriptSHA[name] = result.Val()
-		sc.logger.Infof("Loaded script %s with SHA: %s", name, sc.scriptSHA[name])
+		sc.scriptMutex.Unlock()
+		sc.logger.Infof("Loaded script %s with SHA: %s", name, result.Val())
 	}
 
 	return nil
 }
 
-// 执行秒杀
-func (sc *SeckillCore) ExecuteSeckill(ctx context.Context, req *SeckillRequest) (*SeckillResult, error) {
+// 执行预加载的脚本。主从切换或节点重启后脚本缓存会丢失，
+// 遇到 NOSCRIPT 时重新加载全部脚本再重试；其他协程正在加载时直接发送脚本内容执行。
+func (sc *SeckillCore) evalScript(ctx context.Context, name string, keys []string, args ...interface{}) *redis.Cmd {
+	sc.scriptMutex.RLock()
+	sha, exists := sc.scriptSHA[name]
+	sc.scriptMutex.RUnlock()
+	if !exists {
+		return sc.redisClient.Eval(ctx, coreScripts[name], keys, args...)
+	}
+
+	result := sc.redisClient.EvalSha(ctx, sha, keys, args...)
+	if err := result.Err(); err == nil || !strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		return result
+	}
+
+	if sc.reloadMutex.TryLock() {
+		sc.logger.Warnf("Script %s not found on redis, reloading scripts", name)
+		err := sc.InitScripts(ctx)
+		sc.reloadMutex.Unlock()
+		if err == nil {
+			return sc.redisClient.EvalSha(ctx, sha, keys, args...)
+		}
+		sc.logger.Errorf("Failed to reload scripts: %v", err)
+	}
+	return sc.redisClient.Eval(ctx, coreScripts[name], keys, args...)
+}
+
+// 执行秒杀，event 不为空时在扣减成功的同时写入 outbox
+func (sc *SeckillCore) ExecuteSeckill(ctx context.Context, req *SeckillRequest, orderID string, event *OrderEvent) (*SeckillResult, error) {
 	// 参数验证
 	if req.ProductID <= 0 || req.UserID <= 0 || req.Quantity <= 0 {
 		return &SeckillResult{
@@ -127,24 +269,60 @@ func (sc *SeckillCore) ExecuteSeckill(ctx context.Context, req *SeckillRequest)
 		}, nil
 	}
 
-	// 构建 Redis 键
-	stockKey := fmt.Sprintf("seckill:stock:%d", req.ProductID)
-	usersKey := fmt.Sprintf("seckill:users:%d", req.ProductID)
+	// 已售罄的商品直接拒绝，不访问 Redis
+	if sc.IsSoldOut(req.ProductID) {
+		return &SeckillResult{
+			Code:    ResultInsufficientStock,
+			Message: sc.ResultMessage(ResultInsufficientStock),
+			Success: false,
+		}, nil
+	}
 
-	// 执行 Lua 脚本
-	keys := []string{stockKey, usersKey}
-	args := []interface{}{req.UserID, req.Quantity}
+	// 发券活动从券码池发放
+	coupon, err := sc.IsCouponActivity(ctx, req.ProductID)
+	if err != nil {
+		sc.logger.Errorf("Failed to resolve activity type: %v", err)
+		return &SeckillResult{
+			Code:    ResultSystemError,
+			Message: "系统错误",
+			Success: false,
+		}, err
+	}
+	if coupon {
+		return sc.executeCoupon(ctx, req, event)
+	}
 
-	var result *redis.Cmd
-	var err error
+	// 构建 Redis 键
+	stockKey := StockKey(req.ProductID)
+	usersKey := UsersKey(req.ProductID)
+	reservationsKey := ReservationsKey(req.ProductID)
+
+	record, err := json.Marshal(purchaseRecord{
+		Quantity:     req.Quantity,
+		OrderID:      orderID,
+		PurchaseTime: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return &SeckillResult{
+			Code:    ResultSystemError,
+			Message: "系统错误",
+			Success: false,
+		}, fmt.Errorf("failed to marshal purchase record: %w", err)
+	}
 
-	// 优先使用预加载的脚本
-	if sha, exists := sc.scriptSHA["seckill"]; exists {
-		result = sc.redisClient.EvalSha(ctx, sha, keys, args...)
-	} else {
-		result = sc.redisClient.Eval(ctx, SeckillSimpleLuaScript, keys, args...)
+	// 执行 Lua 脚本
+	keys := []string{stockKey, usersKey, OutboxStreamKey(req.ProductID), reservationsKey, PurchasesKey(req.ProductID)}
+	args := []interface{}{req.UserID, req.Quantity, "", "", 0, string(record)}
+	if event != nil {
+		args[2] = event.Type
+		args[3] = string(event.Payload)
+	}
+	if sc.reservationTTL > 0 {
+		args[4] = time.Now().Add(sc.reservationTTL).UnixMilli()
 	}
 
+	result := sc.evalScript(ctx, "seckill", keys, args...)
+
 	if err = result.Err(); err != nil {
 		sc.logger.Errorf("Failed to execute seckill script: %v", err)
 		return &SeckillResult{
@@ -155,7 +333,22 @@ func (sc *SeckillCore) ExecuteSeckill(ctx context.Context, req *SeckillRequest)
 	}
 
 	// 解析结果
-	return sc.parseSeckillResult(result.Val())
+	seckillResult, err := sc.parseSeckillResult(result.Val())
+	if err != nil {
+		return seckillResult, err
+	}
+
+	sc.markSoldOutIfEmpty(ctx, req.ProductID, seckillResult)
+	return seckillResult, nil
+}
+
+// 库存耗尽时标记售罄并通知其他节点
+func (sc *SeckillCore) markSoldOutIfEmpty(ctx context.Context, productID int64, result *SeckillResult) {
+	if result.RemainingStock == 0 && (result.Success || result.Code == ResultInsufficientStock) && sc.soldOut != nil {
+		if sc.soldOut.MarkSoldOut(ctx, productID) {
+			sc.logger.Infof("Product %d sold out", productID)
+		}
+	}
 }
 
 // 解析秒杀结果
@@ -168,7 +361,7 @@ func (sc *SeckillCore) parseSeckillResult(result interface{}) (*SeckillResult, e
 
 			return &SeckillResult{
 				Code:           int(code),
-				Message:        sc.getResultMessage(int(code)),
+				Message:        sc.ResultMessage(int(code)),
 				Success:        code == ResultSuccess,
 				RemainingStock: remainingStock,
 			}, nil
@@ -177,7 +370,7 @@ func (sc *SeckillCore) parseSeckillResult(result interface{}) (*SeckillResult, e
 		code := int(v)
 		return &SeckillResult{
 			Code:    code,
-			Message: sc.getResultMessage(code),
+			Message: sc.ResultMessage(code),
 			Success: code == ResultSuccess,
 		}, nil
 	}
@@ -190,7 +383,7 @@ func (sc *SeckillCore) parseSeckillResult(result interface{}) (*SeckillResult, e
 }
 
 // 获取结果消息
-func (sc *SeckillCore) getResultMessage(code int) string {
+func (sc *SeckillCore) ResultMessage(code int) string {
 	messages := map[int]string{
 		ResultSuccess:            "秒杀成功",
 		ResultStockNotFound:      "商品不存在",
@@ -203,6 +396,9 @@ func (sc *SeckillCore) getResultMessage(code int) string {
 		ResultSystemError:        "系统错误",
 		ResultSystemBusy:         "系统繁忙，请稍后重试",
 		ResultRequestTimeout:     "请求超时",
+		ResultNoPurchaseRight:    "未获得购买资格",
+		ResultRiskRejected:       "账号存在风险，暂时无法购买",
+		ResultRiskChallenge:      "请完成安全验证后重试",
 	}
 
 	if msg, exists := messages[code]; exists {
@@ -211,23 +407,31 @@ func (sc *SeckillCore) getResultMessage(code int) string {
 	return "未知错误"
 }
 
-// 库存回滚
+// 库存回滚，分桶时归还到用户所属的分桶；启用库存预留时仅回滚预留仍存在（未支付确认）的购买；
+// 发券活动把券码放回券码池
 func (sc *SeckillCore) RollbackStock(ctx context.Context, productID, userID, quantity int64) error {
-	stockKey := fmt.Sprintf("seckill:stock:%d", productID)
-	usersKey := fmt.Sprintf("seckill:users:%d", productID)
+	coupon, err := sc.IsCouponActivity(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if coupon {
+		return sc.rollbackCoupon(ctx, productID, userID)
+	}
 
-	keys := []string{stockKey, usersKey}
-	args := []interface{}{userID, quantity}
+	stockKey := StockKey(productID)
+	usersKey := UsersKey(productID)
 
-	var result *redis.Cmd
-	var err error
+	keys := []string{stockKey, usersKey, PurchasesKey(productID)}
+	args := []interface{}{userID, quantity}
 
-	if sha, exists := sc.scriptSHA["rollback"]; exists {
-		result = sc.redisClient.EvalSha(ctx, sha, keys, args...)
-	} else {
-		result = sc.redisClient.Eval(ctx, StockRollbackLuaScript, keys, args...)
+	// 启用库存预留时同时移除预留，避免过期回收时再次归还
+	if sc.reservationTTL > 0 {
+		keys = append(keys, ReservationsKey(productID))
+		args = append(args, reservationMember(userID, quantity))
 	}
 
+	result := sc.evalScript(ctx, "rollback", keys, args...)
+
 	if err = result.Err(); err != nil {
 		sc.logger.Errorf("Failed to rollback stock: %v", err)
 		return err
@@ -237,67 +441,235 @@ func (sc *SeckillCore) RollbackStock(ctx context.Context, productID, userID, qua
 	sc.logger.Infof("Rollback stock for product %d, user %d, quantity %d, new stock: %v",
 		productID, userID, quantity, newStock)
 
+	if restored, _ := newStock.(int64); restored > 0 {
+		sc.clearSoldOut(ctx, productID)
+	}
+
 	return nil
 }
 
-// 批量检查用户购买状态
-func (sc *SeckillCore) BatchCheckUserStatus(ctx context.Context, productID int64, userIDs []int64) ([]bool, error) {
-	usersKey := fmt.Sprintf("seckill:users:%d", productID)
-	keys := []string{usersKey}
+// 预留成员
+func reservationMember(userID, quantity int64) string {
+	return fmt.Sprintf("%d:%d", userID, quantity)
+}
+
+// 释放一个库存预留：预留仍存在时归还库存并清除用户购买记录
+func (sc *SeckillCore) ReleaseReservation(ctx context.Context, productID, userID, quantity int64) (bool, error) {
+	stockKey := StockKey(productID)
+	usersKey := UsersKey(productID)
+	reservationsKey := ReservationsKey(productID)
+
+	keys := []string{stockKey, usersKey, PurchasesKey(productID), reservationsKey}
+	args := []interface{}{userID, quantity, reservationMember(userID, quantity)}
+
+	result := sc.evalScript(ctx, "rollback", keys, args...)
+
+	if err := result.Err(); err != nil {
+		return false, fmt.Errorf("failed to release reservation: %w", err)
+	}
+
+	released, _ := result.Val().(int64)
+	if released > 0 {
+		sc.clearSoldOut(ctx, productID)
+	}
+	return released > 0, nil
+}
+
+// 确认库存预留（订单支付后调用），返回 false 表示预留已过期释放
+func (sc *SeckillCore) ConfirmReservation(ctx context.Context, productID, userID, quantity int64) (bool, error) {
+	reservationsKey := ReservationsKey(productID)
+
+	result := sc.redisClient.Eval(ctx, ConfirmReservationScript, []string{reservationsKey}, reservationMember(userID, quantity))
+	if err := result.Err(); err != nil {
+		return false, fmt.Errorf("failed to confirm reservation: %w", err)
+	}
+
+	confirmed, _ := result.Val().(int64)
+	return confirmed == 1, nil
+}
+
+// 回收指定商品已过期的库存预留，返回释放数量
+func (sc *SeckillCore) ReleaseExpiredReservations(ctx context.Context, productID int64, now time.Time, limit int64) (int, error) {
+	reservationsKey := ReservationsKey(productID)
+
+	members, err := sc.redisClient.ZRangeByScore(ctx, reservationsKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprintf("%d", now.UnixMilli()),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired reservations: %w", err)
+	}
+
+	released := 0
+	for _, member := range members {
+		var userID, quantity int64
+		if _, err := fmt.Sscanf(member, "%d:%d", &userID, &quantity); err != nil {
+			sc.logger.Warnf("Invalid reservation member %q for product %d", member, productID)
+			continue
+		}
+
+		ok, err := sc.ReleaseReservation(ctx, productID, userID, quantity)
+		if err != nil {
+			return released, err
+		}
+		if ok {
+			released++
+			sc.logger.Infof("Released expired reservation: product=%d, user=%d, quantity=%d", productID, userID, quantity)
+		}
+	}
+
+	return released, nil
+}
+
+// 获取已预热活动的商品ID
+func (sc *SeckillCore) ListActivityProducts(ctx context.Context) ([]int64, error) {
+	members, err := sc.redisClient.SMembers(ctx, ActivitiesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activities: %w", err)
+	}
+
+	productIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		var productID int64
+		if _, err := fmt.Sscanf(member, "%d", &productID); err == nil {
+			productIDs = append(productIDs, productID)
+		}
+	}
+	return productIDs, nil
+}
+
+// 批量检查用户购买状态，按 userIDs 的顺序返回购买详情
+func (sc *SeckillCore) BatchCheckUserStatus(ctx context.Context, productID int64, userIDs []int64) ([]*UserPurchaseInfo, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := []string{UsersKey(productID), PurchasesKey(productID), ReservationsKey(productID)}
 
 	args := make([]interface{}, len(userIDs))
 	for i, userID := range userIDs {
 		args[i] = userID
 	}
 
-	var result *redis.Cmd
-	var err error
+	result := sc.evalScript(ctx, "batch_check", keys, args...)
 
-	if sha, exists := sc.scriptSHA["batch_check"]; exists {
-		result = sc.redisClient.EvalSha(ctx, sha, keys, args...)
-	} else {
-		result = sc.redisClient.Eval(ctx, BatchCheckUserScript, keys, args...)
-	}
-
-	if err = result.Err(); err != nil {
+	if err := result.Err(); err != nil {
 		sc.logger.Errorf("Failed to batch check user status: %v", err)
 		return nil, err
 	}
 
 	// 解析结果
 	resultSlice, ok := result.Val().([]interface{})
-	if !ok {
+	if !ok || len(resultSlice) != len(userIDs) {
 		return nil, fmt.Errorf("unexpected result type")
 	}
 
-	statuses := make([]bool, len(resultSlice))
+	infos := make([]*UserPurchaseInfo, len(resultSlice))
 	for i, v := range resultSlice {
-		if status, ok := v.(int64); ok {
-			statuses[i] = status == 1
+		info := &UserPurchaseInfo{
+			UserID:    userIDs[i],
+			ProductID: productID,
+			Status:    PurchaseStatusNone,
+		}
+		infos[i] = info
+
+		fields, _ := v.([]interface{})
+		if len(fields) < 3 {
+			continue
+		}
+		if bought, _ := fields[0].(int64); bought != 1 {
+			continue
+		}
+		info.Purchased = true
+		info.Status = PurchaseStatusSuccess
+
+		// 早于购买详情记录的购买只有购买状态
+		if detail, _ := fields[1].(string); detail != "" {
+			var record purchaseRecord
+			if err := json.Unmarshal([]byte(detail), &record); err == nil {
+				purchaseTime := time.UnixMilli(record.PurchaseTime)
+				info.Quantity = record.Quantity
+				info.OrderID = record.OrderID
+				info.CouponCode = record.CouponCode
+				info.PurchaseTime = &purchaseTime
+			}
+		}
+		if score, _ := fields[2].(string); score != "" {
+			if until, err := strconv.ParseFloat(score, 64); err == nil {
+				reservedUntil := time.UnixMilli(int64(until))
+				info.ReservedUntil = &reservedUntil
+				info.Status = PurchaseStatusReserved
+			}
 		}
 	}
 
-	return statuses, nil
+	return infos, nil
+}
+
+// 遍历活动的全部购买者，每批最多约 count 个用户（SSCAN 的数量提示），不阻塞 Redis。
+// SSCAN 可能重复返回同一成员，这里按用户去重。
+func (sc *SeckillCore) ScanBuyers(ctx context.Context, productID int64, count int64, fn func([]*UserPurchaseInfo) error) error {
+	usersKey := UsersKey(productID)
+	seen := make(map[int64]struct{})
+
+	var cursor uint64
+	for {
+		members, next, err := sc.redisClient.SScan(ctx, usersKey, cursor, "", count).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan buyers: %w", err)
+		}
+
+		userIDs := make([]int64, 0, len(members))
+		for _, member := range members {
+			userID, err := strconv.ParseInt(member, 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := seen[userID]; ok {
+				continue
+			}
+			seen[userID] = struct{}{}
+			userIDs = append(userIDs, userID)
+		}
+
+		if len(userIDs) > 0 {
+			infos, err := sc.BatchCheckUserStatus(ctx, productID, userIDs)
+			if err != nil {
+				return err
+			}
+			// 扫描期间回滚的用户不再输出
+			buyers := infos[:0]
+			for _, info := range infos {
+				if info.Purchased {
+					buyers = append(buyers, info)
+				}
+			}
+			if len(buyers) > 0 {
+				if err := fn(buyers); err != nil {
+					return err
+				}
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
 }
 
 // 获取秒杀统计信息
 func (sc *SeckillCore) GetSeckillStats(ctx context.Context, productID int64) (*SeckillStats, error) {
-	stockKey := fmt.Sprintf("seckill:stock:%d", productID)
-	usersKey := fmt.Sprintf("seckill:users:%d", productID)
-	activityKey := fmt.Sprintf("seckill:activity:%d", productID)
+	stockKey := StockKey(productID)
+	usersKey := UsersKey(productID)
+	activityKey := ActivityKey(productID)
 
 	keys := []string{stockKey, usersKey, activityKey}
 
-	var result *redis.Cmd
-	var err error
-
-	if sha, exists := sc.scriptSHA["stats"]; exists {
-		result = sc.redisClient.EvalSha(ctx, sha, keys)
-	} else {
-		result = sc.redisClient.Eval(ctx, SeckillStatsScript, keys)
-	}
+	result := sc.evalScript(ctx, "stats", keys)
 
-	if err = result.Err(); err != nil {
+	if err := result.Err(); err != nil {
 		sc.logger.Errorf("Failed to get seckill stats: %v", err)
 		return nil, err
 	}
@@ -338,23 +710,30 @@ func (sc *SeckillCore) IsUserPurchased(ctx context.Context, productID, userID in
 	}
 
 	if len(statuses) > 0 {
-		return statuses[0], nil
+		return statuses[0].Purchased, nil
 	}
 
 	return false, nil
 }
 
+// 获取用户购买详情
+func (sc *SeckillCore) GetUserPurchaseInfo(ctx context.Context, productID, userID int64) (*UserPurchaseInfo, error) {
+	infos, err := sc.BatchCheckUserStatus(ctx, productID, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+	return infos[0], nil
+}
+
 // 预热活动数据
 func (sc *SeckillCore) PrewarmActivity(ctx context.Context, activity *SeckillActivity) error {
 	// 设置库存
-	stockKey := fmt.Sprintf("seckill:stock:%d", activity.ProductID)
-	err := sc.redisClient.Set(ctx, stockKey, activity.Stock, 24*time.Hour).Err()
-	if err != nil {
-		return fmt.Errorf("failed to set stock: %w", err)
+	if err := sc.prewarmStock(ctx, activity); err != nil {
+		return err
 	}
 
 	// 设置活动信息
-	activityKey := fmt.Sprintf("seckill:activity:%d", activity.ProductID)
+	activityKey := ActivityKey(activity.ProductID)
 	activityData, err := json.Marshal(activity)
 	if err != nil {
 		return fmt.Errorf("failed to marshal activity: %w", err)
@@ -365,16 +744,66 @@ func (sc *SeckillCore) PrewarmActivity(ctx context.Context, activity *SeckillAct
 		return fmt.Errorf("failed to set activity: %w", err)
 	}
 
+	// 登记活动，供预留回收等后台任务遍历
+	if err := sc.redisClient.SAdd(ctx, ActivitiesKey, activity.ProductID).Err(); err != nil {
+		return fmt.Errorf("failed to register activity: %w", err)
+	}
+
+	// 登记活动的 outbox 流，供 outbox 中继消费
+	if err := sc.redisClient.SAdd(ctx, OutboxStreamsKey, OutboxStreamKey(activity.ProductID)).Err(); err != nil {
+		return fmt.Errorf("failed to register outbox stream: %w", err)
+	}
+
+	sc.clearSoldOut(ctx, activity.ProductID)
+
 	sc.logger.Infof("Prewarmed activity for product %d with stock %d", activity.ProductID, activity.Stock)
 	return nil
 }
 
+// 写入库存，发券活动先检查券码池
+func (sc *SeckillCore) prewarmStock(ctx context.Context, activity *SeckillActivity) error {
+	if activity.IsCoupon() {
+		if err := sc.checkCouponPool(ctx, activity); err != nil {
+			return err
+		}
+	}
+
+	if err := sc.redisClient.Set(ctx, StockKey(activity.ProductID), activity.Stock, 24*time.Hour).Err(); err != nil {
+		return fmt.Errorf("failed to set stock: %w", err)
+	}
+
+	sc.setCouponFlag(activity.ProductID, activity.IsCoupon())
+	return nil
+}
+
+// 获取活动信息
+func (sc *SeckillCore) GetActivity(ctx context.Context, productID int64) (*SeckillActivity, error) {
+	activityKey := ActivityKey(productID)
+	data, err := sc.redisClient.Get(ctx, activityKey).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrActivityNotFound
+		}
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+
+	var activity SeckillActivity
+	if err := json.Unmarshal(data, &activity); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal activity: %w", err)
+	}
+	return &activity, nil
+}
+
 // 清理活动数据
 func (sc *SeckillCore) CleanupActivity(ctx context.Context, productID int64) error {
 	keys := []string{
-		fmt.Sprintf("seckill:stock:%d", productID),
-		fmt.Sprintf("seckill:users:%d", productID),
-		fmt.Sprintf("seckill:activity:%d", productID),
+		StockKey(productID),
+		UsersKey(productID),
+		PurchasesKey(productID),
+		ActivityKey(productID),
+		ReservationsKey(productID),
+		InventoryReservationKey(productID),
+		CouponsKey(productID),
 	}
 
 	err := sc.redisClient.Del(ctx, keys...).Err()
@@ -382,12 +811,38 @@ func (sc *SeckillCore) CleanupActivity(ctx context.Context, productID int64) err
 		return fmt.Errorf("failed to cleanup activity: %w", err)
 	}
 
+	sc.couponsMutex.Lock()
+	delete(sc.coupons, productID)
+	sc.couponsMutex.Unlock()
+
+	if err := sc.redisClient.SRem(ctx, ActivitiesKey, productID).Err(); err != nil {
+		return fmt.Errorf("failed to unregister activity: %w", err)
+	}
+
+	// outbox 流中还有未投递的订单事件时保留，等中继投递完后再次清理时删除
+	outboxKey := OutboxStreamKey(productID)
+	backlog, err := sc.redisClient.XLen(ctx, outboxKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check outbox backlog: %w", err)
+	}
+	if backlog == 0 {
+		if err := sc.redisClient.Del(ctx, outboxKey).Err(); err != nil {
+			return fmt.Errorf("failed to delete outbox stream: %w", err)
+		}
+		if err := sc.redisClient.SRem(ctx, OutboxStreamsKey, outboxKey).Err(); err != nil {
+			return fmt.Errorf("failed to unregister outbox stream: %w", err)
+		}
+	} else {
+		sc.logger.Warnf("Keeping outbox stream %s with %d undelivered events", outboxKey, backlog)
+	}
+
+	sc.clearSoldOut(ctx, productID)
+
 	sc.logger.Infof("Cleaned up activity for product %d", productID)
 	return nil
 }
 
-// 生成订单ID
-func (sc *SeckillCore) GenerateOrderID(productID, userID int64) string {
-	timestamp := time.Now().UnixNano()
-	return fmt.Sprintf("SK%d%d%d", productID, userID, timestamp)
+// 生成订单ID（雪花算法，不包含用户信息）
+func (sc *SeckillCore) GenerateOrderID() (string, error) {
+	return sc.idGenerator.NextString(OrderIDPrefix)
 }
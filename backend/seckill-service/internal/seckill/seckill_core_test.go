@@ -0,0 +1,62 @@
+package seckill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+func TestExecuteSeckillRemainingStock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	sc := NewSeckillCore(client, nil, logrus.New())
+	activity := &SeckillActivity{
+		ProductID: 1001,
+		Stock:     5,
+		StartTime: time.Now(),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	if err := sc.PrewarmActivity(ctx, activity); err != nil {
+		t.Fatalf("PrewarmActivity: %v", err)
+	}
+
+	steps := []struct {
+		userID    int64
+		quantity  int64
+		wantCode  int
+		wantStock int64
+	}{
+		{userID: 1, quantity: 2, wantCode: ResultSuccess, wantStock: 3},
+		{userID: 1, quantity: 1, wantCode: ResultUserAlreadyBought},
+		{userID: 2, quantity: 4, wantCode: ResultInsufficientStock, wantStock: 3},
+		{userID: 3, quantity: 3, wantCode: ResultSuccess, wantStock: 0},
+		{userID: 4, quantity: 1, wantCode: ResultInsufficientStock, wantStock: 0},
+	}
+
+	for i, step := range steps {
+		req := &SeckillRequest{ProductID: activity.ProductID, UserID: step.userID, Quantity: step.quantity}
+		result, err := sc.ExecuteSeckill(ctx, req, "", nil)
+		if err != nil {
+			t.Fatalf("step %d: ExecuteSeckill: %v", i, err)
+		}
+		if result.Code != step.wantCode || result.RemainingStock != step.wantStock {
+			t.Fatalf("step %d: ExecuteSeckill(user %d, quantity %d) = %d, %d, want %d, %d",
+				i, step.userID, step.quantity, result.Code, result.RemainingStock, step.wantCode, step.wantStock)
+		}
+
+		stock, err := sc.RemainingStock(ctx, activity.ProductID)
+		if err != nil {
+			t.Fatalf("step %d: RemainingStock: %v", i, err)
+		}
+		if result.Success && stock != result.RemainingStock {
+			t.Fatalf("step %d: RemainingStock = %d, want %d", i, stock, result.RemainingStock)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package seckill
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// 售罄广播频道，消息格式：soldout:<productId> / restock:<productId>
+const SoldOutChannel = "seckill:soldout"
+
+const (
+	soldOutMessage = "soldout"
+	restockMessage = "restock"
+)
+
+// 售罄广播 Redis 客户端接口
+type SoldOutClient interface {
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// 本地售罄标记：库存耗尽后直接拒绝请求，不再访问 Redis。
+// 标记通过 Redis pub/sub 在节点间同步；为防止漏收补货消息，标记在 ttl 后过期，
+// 过期后的下一个请求重新执行脚本确认库存。
+type SoldOutFlags struct {
+	client SoldOutClient
+	ttl    time.Duration
+	logger *logrus.Logger
+
+	mu       sync.RWMutex
+	products map[int64]time.Time // 商品ID -> 标记过期时间
+}
+
+// 创建本地售罄标记
+func NewSoldOutFlags(client SoldOutClient, ttl time.Duration, logger *logrus.Logger) *SoldOutFlags {
+	return &SoldOutFlags{
+		client:   client,
+		ttl:      ttl,
+		logger:   logger,
+		products: make(map[int64]time.Time),
+	}
+}
+
+// 订阅其他节点的售罄与补货广播
+func (f *SoldOutFlags) Start(ctx context.Context) {
+	pubsub := f.client.Subscribe(ctx, SoldOutChannel)
+
+	go func() {
+		defer pubsub.Close()
+
+		channel := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-channel:
+				if !ok {
+					return
+				}
+				f.handleMessage(msg.Payload)
+			}
+		}
+	}()
+}
+
+func (f *SoldOutFlags) handleMessage(payload string) {
+	kind, value, ok := strings.Cut(payload, ":")
+	if !ok {
+		return
+	}
+	var productID int64
+	if _, err := fmt.Sscanf(value, "%d", &productID); err != nil {
+		return
+	}
+
+	switch kind {
+	case soldOutMessage:
+		f.set(productID)
+	case restockMessage:
+		f.unset(productID)
+	}
+}
+
+// 商品是否已售罄
+func (f *SoldOutFlags) IsSoldOut(productID int64) bool {
+	f.mu.RLock()
+	expiresAt, exists := f.products[productID]
+	f.mu.RUnlock()
+
+	return exists && (f.ttl <= 0 || time.Now().Before(expiresAt))
+}
+
+// 标记商品售罄并广播，首次标记时返回 true
+func (f *SoldOutFlags) MarkSoldOut(ctx context.Context, productID int64) bool {
+	if f.IsSoldOut(productID) {
+		return false
+	}
+	f.set(productID)
+	f.publish(ctx, soldOutMessage, productID)
+	return true
+}
+
+// 清除售罄标记并广播（库存归还或重新预热后调用）
+func (f *SoldOutFlags) Clear(ctx context.Context, productID int64) {
+	f.unset(productID)
+	f.publish(ctx, restockMessage, productID)
+}
+
+// 当前标记为售罄的商品
+func (f *SoldOutFlags) Products() []int64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	now := time.Now()
+	productIDs := make([]int64, 0, len(f.products))
+	for productID, expiresAt := range f.products {
+		if f.ttl <= 0 || now.Before(expiresAt) {
+			productIDs = append(productIDs, productID)
+		}
+	}
+	return productIDs
+}
+
+func (f *SoldOutFlags) set(productID int64) {
+	f.mu.Lock()
+	f.products[productID] = time.Now().Add(f.ttl)
+	f.mu.Unlock()
+}
+
+func (f *SoldOutFlags) unset(productID int64) {
+	f.mu.Lock()
+	delete(f.products, productID)
+	f.mu.Unlock()
+}
+
+// 广播失败只影响其他节点，它们会在下一次执行脚本时自行发现
+func (f *SoldOutFlags) publish(ctx context.Context, kind string, productID int64) {
+	message := fmt.Sprintf("%s:%d", kind, productID)
+	if err := f.client.Publish(ctx, SoldOutChannel, message).Err(); err != nil {
+		f.logger.Warnf("Failed to broadcast %s: %v", message, err)
+	}
+}
@@ -0,0 +1,80 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 票据ID前缀
+const TicketIDPrefix = "TK"
+
+// 票据状态
+type TicketStatus string
+
+const (
+	TicketQueued     TicketStatus = "queued"
+	TicketProcessing TicketStatus = "processing"
+	TicketSuccess    TicketStatus = "success"
+	TicketFailed     TicketStatus = "failed"
+)
+
+var ErrTicketNotFound = errors.New("ticket not found")
+
+// 异步秒杀票据
+type Ticket struct {
+	ID        string       `json:"ticket"`
+	ProductID int64        `json:"product_id"`
+	UserID    int64        `json:"user_id"`
+	Quantity  int64        `json:"quantity"`
+	Status    TicketStatus `json:"status"`
+	Code      int          `json:"code,omitempty"`
+	Reason    string       `json:"reason,omitempty"`
+	OrderID   string       `json:"order_id,omitempty"`
+	Position  int          `json:"position"` // 入队时前方排队的请求数
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// 生成票据ID
+func (sc *SeckillCore) GenerateTicketID() (string, error) {
+	return sc.idGenerator.NextString(TicketIDPrefix)
+}
+
+// 保存票据
+func (sc *SeckillCore) SaveTicket(ctx context.Context, ticket *Ticket, ttl time.Duration) error {
+	ticket.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	ticketKey := fmt.Sprintf("seckill:ticket:%s", ticket.ID)
+	if err := sc.redisClient.Set(ctx, ticketKey, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save ticket: %w", err)
+	}
+	return nil
+}
+
+// 获取票据
+func (sc *SeckillCore) GetTicket(ctx context.Context, ticketID string) (*Ticket, error) {
+	ticketKey := fmt.Sprintf("seckill:ticket:%s", ticketID)
+	data, err := sc.redisClient.Get(ctx, ticketKey).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	var ticket Ticket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ticket: %w", err)
+	}
+	return &ticket, nil
+}
@@ -2,14 +2,29 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"runtime"
+	"os"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"seckill-service/internal/activity"
+	"seckill-service/internal/antibot"
 	"seckill-service/internal/config"
+	"seckill-service/internal/dashboard"
+	"seckill-service/internal/database"
 	"seckill-service/internal/flowcontrol"
+	"seckill-service/internal/idgen"
+	"seckill-service/internal/lottery"
+	"seckill-service/internal/model"
 	"seckill-service/internal/mq"
+	"seckill-service/internal/report"
+	"seckill-service/internal/risk"
 	"seckill-service/internal/seckill"
+	"seckill-service/internal/waitingroom"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
@@ -18,43 +33,74 @@ import (
 // 秒杀服务
 type SeckillService struct {
 	config         *config.Config
-	redisClient    *redis.Client
+	redisClient    redis.UniversalClient
 	seckillCore    *seckill.SeckillCore
+	idGenerator    *idgen.Generator
+	workerLease    *idgen.WorkerLease
 	messageQueue   mq.MessageQueue
+	outboxRelay    *mq.OutboxRelay
 	limiter        flowcontrol.Limiter
+	adaptive       *flowcontrol.AdaptiveLimiter
+	hotspots       []*flowcontrol.HotspotLimiter
 	circuitBreaker *flowcontrol.CircuitBreaker
-	requestQueue   *flowcontrol.RequestQueue
+	breakers       *flowcontrol.CircuitBreakerManager
+	breakerEvents  chan flowcontrol.CircuitBreakerEvent // 待广播的熔断器状态变化事件
+	instance       string                               // 节点标识 hostname:pid
+	requestQueue   *flowcontrol.PriorityRequestQueue
+	overload       *flowcontrol.OverloadDetector
+	soldOut        *seckill.SoldOutFlags
+	waitingRoom    *waitingroom.Room
+	lottery        *lottery.Lottery
+	antiBot        *antibot.Gate
+	pathLimiter    flowcontrol.Limiter         // 购买路径领取接口的全局限流
+	pathUsers      *flowcontrol.HotspotLimiter // 购买路径领取接口的单用户限流
+	riskEngine     *risk.Engine
+	database       *database.Database
+	activities     *activity.Store           // 活动管理，未配置数据库时为空
+	inventory      *activity.InventoryClient // 库存服务客户端，未配置时预热不预留库存
+	report         *report.Recorder          // 活动报告计数，未启用时为空
+	dashboard      *dashboard.Dashboard      // 实时看板，未启用时为空
 	logger         *logrus.Logger
 
+	// 下线中：拒绝新的秒杀请求，健康检查返回不健康
+	draining atomic.Bool
+
 	// 统计信息
 	stats ServiceStats
 }
 
 // 服务统计信息
 type ServiceStats struct {
-	TotalRequests       int64
-	SuccessRequests     int64
-	FailedRequests      int64
-	RateLimitedRequests int64
-	CircuitBreakerTrips int64
-	QueueFullRequests   int64
-	SystemBusyRequests  int64
+	TotalRequests          int64
+	SuccessRequests        int64
+	FailedRequests         int64
+	RateLimitedRequests    int64
+	CircuitBreakerTrips    int64
+	QueueFullRequests      int64
+	SystemBusyRequests     int64
+	HotspotLimitedRequests int64
+	ReleasedReservations   int64
+	AdmittedUsers          int64 // 等候室放行人数
+	AdmissionRejected      int64 // 缺少或持有无效准入令牌的请求数
+	PromotedLotteryRights  int64 // 转给候补用户的抽签购买资格数
+	SoldOutRequests        int64 // 因本地售罄标记直接拒绝的请求数
+	PurchasePathsIssued    int64 // 签发的购买路径数
+	PathIssueRejected      int64 // 被限流或工作量证明校验失败的领取请求数
+	PathRejected           int64 // 缺少或持有无效购买路径的下单请求数
+	RiskRejected           int64 // 被风控拒绝的请求数
+	RiskChallenged         int64 // 被要求完成风控挑战的请求数
+	RiskDegraded           int64 // 被风控降级到最低优先级的请求数
+	SettledReservations    int64 // 已释放的活动库存预留数
+	DrainRejected          int64 // 下线期间拒绝的请求数（含排空超时未处理的排队请求）
+	RolledBackPurchases    int64 // 订单消息未发出而回滚库存的购买数
+	ConcurrencyLimit       int   // 自适应并发上限
+	InFlightRequests       int
 }
 
 // 创建秒杀服务
 func NewSeckillService(cfg *config.Config, logger *logrus.Logger) (*SeckillService, error) {
 	// 创建 Redis 客户端
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
-		Password:     cfg.Redis.Password,
-		DB:           cfg.Redis.DB,
-		PoolSize:     cfg.Redis.PoolSize,
-		MinIdleConns: cfg.Redis.MinIdleConns,
-		MaxRetries:   cfg.Redis.MaxRetries,
-		DialTimeout:  cfg.Redis.DialTimeout,
-		ReadTimeout:  cfg.Redis.ReadTimeout,
-		WriteTimeout: cfg.Redis.WriteTimeout,
-	})
+	redisClient := newRedisClient(cfg)
 
 	// 测试 Redis 连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -63,334 +109,2258 @@ func NewSeckillService(cfg *config.Config, logger *logrus.Logger) (*SeckillServi
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
+	// 创建订单ID生成器
+	idGenerator, workerLease, err := newIDGenerator(ctx, cfg, redisClient, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create id generator: %w", err)
+	}
+
 	// 创建秒杀核心服务
-	seckillCore := seckill.NewSeckillCore(redisClient, logger)
+	seckillCore := seckill.NewSeckillCore(redisClient, idGenerator, logger)
 	if err := seckillCore.InitScripts(ctx); err != nil {
 		return nil, fmt.Errorf("failed to init seckill scripts: %w", err)
 	}
-
-	// 创建消息队列
-	var messageQueue mq.MessageQueue
-	var err error
-
-	// 根据配置选择消息队列
-	if cfg.RabbitMQ.URL != "" {
-		mqConfig := &mq.RabbitMQConfig{
-			URL:        cfg.RabbitMQ.URL,
-			Exchange:   cfg.RabbitMQ.Exchange,
-			Queue:      cfg.RabbitMQ.Queue,
-			RoutingKey: cfg.RabbitMQ.RoutingKey,
-			Durable:    cfg.RabbitMQ.Durable,
-			AutoDelete: cfg.RabbitMQ.AutoDelete,
-		}
-		messageQueue, err = mq.NewRabbitMQProducer(mqConfig, logger)
-	} else if len(cfg.Kafka.Brokers) > 0 {
-		kafkaConfig := &mq.KafkaConfig{
-			Brokers:   cfg.Kafka.Brokers,
-			Topic:     cfg.Kafka.Topic,
-			Partition: cfg.Kafka.Partition,
-			Timeout:   cfg.Kafka.Timeout,
-		}
-		messageQueue = mq.NewKafkaProducer(kafkaConfig, logger)
+	if cfg.Seckill.Reservation.Enable {
+		seckillCore.EnableReservation(cfg.Seckill.Reservation.TTL)
+	}
+	var soldOut *seckill.SoldOutFlags
+	if cfg.Seckill.SoldOut.Enable {
+		soldOut = seckill.NewSoldOutFlags(redisClient, cfg.Seckill.SoldOut.FlagTTL, logger)
+		seckillCore.EnableSoldOutFlags(soldOut)
 	}
 
+	// 创建消息队列
+	messageQueue, err := newMessageQueue(cfg, redisClient, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create message queue: %w", err)
 	}
 
+	// 创建 outbox 中继，订单事件由中继投递到消息队列
+	var outboxRelay *mq.OutboxRelay
+	if cfg.Seckill.Outbox.Enable && messageQueue != nil {
+		hostname, _ := os.Hostname()
+		outboxRelay = mq.NewOutboxRelay(redisClient, messageQueue, mq.OutboxRelayConfig{
+			StreamSet:     seckill.OutboxStreamsKey,
+			Group:         cfg.Seckill.Outbox.Group,
+			Consumer:      fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+			BatchSize:     cfg.Seckill.Outbox.BatchSize,
+			BlockTimeout:  cfg.Seckill.Outbox.BlockTimeout,
+			ClaimIdle:     cfg.Seckill.Outbox.ClaimIdle,
+			MaxRetries:    cfg.Seckill.Outbox.MaxRetries,
+			RetryInterval: cfg.Seckill.Outbox.RetryInterval,
+			RefreshPeriod: cfg.Seckill.Outbox.RefreshPeriod,
+		}, logger)
+	}
+
 	// 创建限流器
-	limiter := flowcontrol.NewTokenBucketLimiter(
+	var limiter flowcontrol.Limiter = flowcontrol.NewTokenBucketLimiter(
 		float64(cfg.Seckill.RateLimit.RequestsPerSecond),
 		cfg.Seckill.RateLimit.BurstSize,
 		logger,
 	)
 
-	// 创建熔断器
-	circuitBreakerConfig := flowcontrol.CircuitBreakerConfig{
-		MaxRequests: uint32(cfg.Seckill.CircuitBreaker.HalfOpenRequests),
-		Interval:    60 * time.Second,
-		Timeout:     cfg.Seckill.CircuitBreaker.RecoveryTimeout,
-		ReadyToTrip: func(counts flowcontrol.Counts) bool {
-			return counts.Requests >= 3 && counts.TotalFailures >= uint32(cfg.Seckill.CircuitBreaker.FailureThreshold)
+	// 集群限流，所有副本共享同一配额
+	if clusterCfg := cfg.Seckill.RateLimit.Cluster; clusterCfg.Enable {
+		clusterLimiter := flowcontrol.NewDistributedLimiter(
+			clusterCfg.Key,
+			clusterCfg.RequestsPerSecond,
+			time.Second,
+			clusterCfg.BurstSize,
+			redisClient,
+			logger,
+		)
+		if clusterCfg.FallbackRequestsPerSecond > 0 {
+			clusterLimiter.SetFallback(flowcontrol.NewTokenBucketLimiter(
+				float64(clusterCfg.FallbackRequestsPerSecond),
+				clusterCfg.FallbackRequestsPerSecond,
+				logger,
+			))
+		}
+		limiter = flowcontrol.NewMultiLevelLimiter([]flowcontrol.Limiter{limiter, clusterLimiter}, logger)
+	}
+
+	// 自适应并发限流与令牌桶组成多级限流
+	var adaptive *flowcontrol.AdaptiveLimiter
+	if adaptiveCfg := cfg.Seckill.AdaptiveLimit; adaptiveCfg.Enable {
+		maxLimit := adaptiveCfg.MaxLimit
+		if maxLimit <= 0 {
+			maxLimit = cfg.Seckill.MaxConcurrentRequests
+		}
+		adaptive = flowcontrol.NewAdaptiveLimiter(flowcontrol.AdaptiveLimiterConfig{
+			InitialLimit: adaptiveCfg.InitialLimit,
+			MinLimit:     adaptiveCfg.MinLimit,
+			MaxLimit:     maxLimit,
+			Window:       adaptiveCfg.Window,
+			Smoothing:    adaptiveCfg.Smoothing,
+			Tolerance:    adaptiveCfg.Tolerance,
+			BackoffRatio: adaptiveCfg.BackoffRatio,
+		}, logger)
+		limiter = flowcontrol.NewMultiLevelLimiter(append(limiterLevels(limiter), adaptive), logger)
+	}
+
+	// 热点参数限流
+	var hotspots []*flowcontrol.HotspotLimiter
+	if hotspotCfg := cfg.Seckill.Hotspot; hotspotCfg.Enable {
+		params := []struct {
+			name string
+			cfg  config.HotspotParamConfig
+		}{
+			{hotspotProduct, hotspotCfg.Product},
+			{hotspotUser, hotspotCfg.User},
+		}
+		for _, param := range params {
+			name, paramCfg := param.name, param.cfg
+			if paramCfg.RequestsPerSecond <= 0 {
+				continue
+			}
+			overrides := make(map[string]flowcontrol.HotspotOverride, len(paramCfg.Overrides))
+			for value, override := range paramCfg.Overrides {
+				overrides[value] = flowcontrol.HotspotOverride{
+					RequestsPerSecond: override.RequestsPerSecond,
+					Burst:             override.BurstSize,
+				}
+			}
+			hotspots = append(hotspots, flowcontrol.NewHotspotLimiter(flowcontrol.HotspotLimiterConfig{
+				Name:              name,
+				RequestsPerSecond: paramCfg.RequestsPerSecond,
+				Burst:             paramCfg.BurstSize,
+				Overrides:         overrides,
+				MaxKeys:           hotspotCfg.MaxKeys,
+			}, logger))
+		}
+	}
+
+	// 创建熔断器：按滑动窗口内的失败率与慢调用比例熔断，系统错误计为失败
+	breakerCfg := cfg.Seckill.CircuitBreaker
+	breakers := flowcontrol.NewCircuitBreakerManager(logger)
+	breakerEvents := make(chan flowcontrol.CircuitBreakerEvent, 64)
+	circuitBreaker := breakers.GetCircuitBreaker("seckill", flowcontrol.CircuitBreakerConfig{
+		MaxRequests:           uint32(breakerCfg.HalfOpenRequests),
+		Interval:              60 * time.Second,
+		Timeout:               breakerCfg.RecoveryTimeout,
+		SlidingWindowType:     breakerCfg.SlidingWindowType,
+		SlidingWindowSize:     breakerCfg.SlidingWindowSize,
+		MinimumCalls:          uint32(breakerCfg.MinimumCalls),
+		FailureRateThreshold:  breakerCfg.FailureRateThreshold,
+		SlowCallRateThreshold: breakerCfg.SlowCallRateThreshold,
+		SlowCallDuration:      breakerCfg.SlowCallDuration,
+		IsSuccessful: func(result interface{}, err error) bool {
+			if err != nil {
+				return false
+			}
+			r, ok := result.(*seckill.SeckillResult)
+			return !ok || r.Code != seckill.ResultSystemError
 		},
-		OnStateChange: func(name string, from, to flowcontrol.CircuitBreakerState) {
-			logger.Infof("Circuit breaker %s state changed: %s -> %s", name, from, to)
+		OnStateChange: func(event flowcontrol.CircuitBreakerEvent) {
+			// 回调在熔断器锁内执行，事件交给后台协程广播，队列满时丢弃
+			select {
+			case breakerEvents <- event:
+			default:
+				logger.Warnf("Circuit breaker event queue full, dropping %s -> %s event of %s", event.From, event.To, event.Name)
+			}
 		},
-	}
-	circuitBreaker := flowcontrol.NewCircuitBreaker("seckill", circuitBreakerConfig, logger)
+	})
+
+	hostname, _ := os.Hostname()
+	instance := fmt.Sprintf("%s:%d", hostname, os.Getpid())
 
 	service := &SeckillService{
 		config:         cfg,
 		redisClient:    redisClient,
 		seckillCore:    seckillCore,
+		idGenerator:    idGenerator,
+		workerLease:    workerLease,
 		messageQueue:   messageQueue,
+		outboxRelay:    outboxRelay,
 		limiter:        limiter,
+		adaptive:       adaptive,
+		hotspots:       hotspots,
 		circuitBreaker: circuitBreaker,
+		breakers:       breakers,
+		breakerEvents:  breakerEvents,
+		instance:       instance,
+		soldOut:        soldOut,
+		lottery:        lottery.NewLottery(redisClient, logger),
 		logger:         logger,
 	}
 
-	// 创建请求队列
-	requestQueue := flowcontrol.NewRequestQueue(
+	// 创建请求队列，按用户等级优先级调度
+	var levels []flowcontrol.PriorityLevel
+	if cfg.Seckill.Priority.Enable {
+		for _, tier := range cfg.Seckill.Priority.Tiers {
+			levels = append(levels, flowcontrol.PriorityLevel{
+				Priority: tier.Priority,
+				Share:    tier.Share,
+			})
+		}
+	}
+	requestQueue := flowcontrol.NewPriorityRequestQueue(
 		cfg.Seckill.QueueSize,
 		10, // 10 个工作协程
+		levels,
 		service.processQueueItem,
 		logger,
 	)
 	service.requestQueue = requestQueue
 
-	logger.Info("Seckill service created successfully")
-	return service, nil
-}
+	// 创建过载检测器
+	if cfg.Seckill.Degradation.Enable {
+		degradation := cfg.Seckill.Degradation
+		service.overload = flowcontrol.NewOverloadDetector(flowcontrol.OverloadConfig{
+			Threshold:        degradation.Threshold,
+			RecoverThreshold: degradation.RecoverThreshold,
+			MinDuration:      degradation.MinDuration,
+			SampleInterval:   degradation.SampleInterval,
+			MaxGoroutines:    degradation.MaxGoroutines,
+			MaxRedisLatency:  degradation.MaxRedisLatency,
+		}, flowcontrol.LoadSources{
+			RedisPing: func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			},
+			QueueUsage: func() float64 {
+				if cfg.Seckill.QueueSize <= 0 {
+					return 0
+				}
+				return float64(requestQueue.QueueLength()) / float64(cfg.Seckill.QueueSize)
+			},
+		}, logger)
+	}
 
-// 启动服务
-func (s *SeckillService) Start(ctx context.Context) error {
-	// 启动请求队列
-	s.requestQueue.Start(ctx)
+	// 创建虚拟等候室
+	if cfg.Seckill.WaitingRoom.Enable {
+		secret := []byte(cfg.Seckill.WaitingRoom.TokenSecret)
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, fmt.Errorf("failed to generate admission token secret: %w", err)
+			}
+			logger.Warn("Waiting room token secret is not configured, tokens are only valid on this instance")
+		}
+		service.waitingRoom = waitingroom.NewRoom(redisClient, waitingroom.NewTokenSigner(secret), logger)
+	}
 
-	s.logger.Info("Seckill service started")
-	return nil
-}
+	// 创建防刷购买闸门
+	if botCfg := cfg.Seckill.AntiBot; botCfg.Enable {
+		secret := []byte(botCfg.ChallengeSecret)
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, fmt.Errorf("failed to generate challenge secret: %w", err)
+			}
+			logger.Warn("Anti-bot challenge secret is not configured, challenges are only valid on this instance")
+		}
+		challengeTTL := botCfg.ChallengeTTL
+		if challengeTTL <= 0 {
+			challengeTTL = time.Minute
+		}
+		service.antiBot = antibot.NewGate(redisClient, antibot.NewChallengeSigner(secret), botCfg.Difficulty, challengeTTL, logger)
 
-// 停止服务
-func (s *SeckillService) Stop() error {
-	if s.redisClient != nil {
-		s.redisClient.Close()
-	}
-	if s.messageQueue != nil {
-		s.messageQueue.Close()
+		limitCfg := botCfg.IssueRateLimit
+		if limitCfg.RequestsPerSecond > 0 {
+			service.pathLimiter = flowcontrol.NewTokenBucketLimiter(float64(limitCfg.RequestsPerSecond), limitCfg.BurstSize, logger)
+		}
+		if limitCfg.UserRequestsPerSecond > 0 {
+			service.pathUsers = flowcontrol.NewHotspotLimiter(flowcontrol.HotspotLimiterConfig{
+				Name:              hotspotUser,
+				RequestsPerSecond: limitCfg.UserRequestsPerSecond,
+				Burst:             limitCfg.UserBurst,
+			}, logger)
+		}
 	}
 
-	s.logger.Info("Seckill service stopped")
-	return nil
-}
+	// 创建风控引擎
+	if riskCfg := cfg.Seckill.Risk; riskCfg.Enable {
+		var signer *antibot.ChallengeSigner
+		if riskCfg.ChallengeScore > 0 {
+			secret := []byte(riskCfg.Challenge.Secret)
+			if len(secret) == 0 {
+				secret = make([]byte, 32)
+				if _, err := rand.Read(secret); err != nil {
+					return nil, fmt.Errorf("failed to generate risk challenge secret: %w", err)
+				}
+				logger.Warn("Risk challenge secret is not configured, challenges are only valid on this instance")
+			}
+			signer = antibot.NewChallengeSigner(secret)
+		}
+		challengeTTL := riskCfg.Challenge.TTL
+		if challengeTTL <= 0 {
+			challengeTTL = time.Minute
+		}
+		service.riskEngine = risk.NewEngine(redisClient, risk.Config{
+			AccountKeyPrefix: riskCfg.AccountKeyPrefix,
+			NewAccountAge:    riskCfg.NewAccountAge,
+			NewAccountScore:  riskCfg.NewAccountScore,
+			DevicePurchases:  riskCounterRule(riskCfg.DevicePurchases, 24*time.Hour),
+			IPPurchases:      riskCounterRule(riskCfg.IPPurchases, 24*time.Hour),
+			FailureBurst:     riskCounterRule(riskCfg.FailureBurst, time.Minute),
+			DegradeScore:     riskCfg.DegradeScore,
+			ChallengeScore:   riskCfg.ChallengeScore,
+			RejectScore:      riskCfg.RejectScore,
+			AssessmentTTL:    riskCfg.AssessmentTTL,
+		}, signer, riskCfg.Challenge.Difficulty, challengeTTL, logger)
+	}
 
-// 秒杀请求处理
-func (s *SeckillService) ProcessSeckill(ctx context.Context, req *seckill.SeckillRequest) (*seckill.SeckillResult, error) {
-	// 检查系统负载
-	if s.isSystemBusy() {
-		s.stats.SystemBusyRequests++
-		return &seckill.SeckillResult{
-			Code:    seckill.ResultSystemBusy,
-			Message: s.config.Seckill.Degradation.ResponseMessage,
-			Success: false,
-		}, nil
+	if reportCfg := cfg.Seckill.Report; reportCfg.Enable {
+		service.report = report.NewRecorder(redisClient, reportCfg.FlushInterval, reportCfg.TTL, logger)
 	}
 
-	// 限流检查
-	if !s.limiter.Allow() {
-		s.stats.RateLimitedRequests++
-		return &seckill.SeckillResult{
-			Code:    seckill.ResultSystemBusy,
-			Message: "请求过于频繁，请稍后重试",
-			Success: false,
-		}, nil
+	// 创建实时看板，节点状态为请求队列积压数与熔断器状态
+	if dashboardCfg := cfg.Seckill.Dashboard; dashboardCfg.Enable {
+		channel := dashboardCfg.Channel
+		if channel == "" {
+			channel = "seckill:dashboard"
+		}
+		service.dashboard = dashboard.New(redisClient, dashboard.Config{
+			Channel:  channel,
+			Instance: instance,
+			Interval: dashboardCfg.Interval,
+			NodeTTL:  dashboardCfg.NodeTTL,
+		}, func() (int64, string) {
+			return service.requestQueue.GetStats().QueuedRequests, service.circuitBreaker.State().String()
+		}, logger)
 	}
 
-	// 熔断器检查
-	result, err := s.circuitBreaker.ExecuteWithContext(ctx, func(ctx context.Context) (interface{}, error) {
-		return s.executeSeckill(ctx, req)
-	})
+	if inventoryCfg := cfg.Inventory; inventoryCfg.Host != "" {
+		service.inventory = activity.NewInventoryClient(inventoryCfg.Host, inventoryCfg.Port, inventoryCfg.Timeout)
+	}
 
-	if err != nil {
-		if err == flowcontrol.ErrCircuitBreakerOpen {
-			s.stats.CircuitBreakerTrips++
-			return &seckill.SeckillResult{
-				Code:    seckill.ResultSystemBusy,
-				Message: "系统繁忙，请稍后重试",
-				Success: false,
-			}, nil
+	// 连接活动管理数据库
+	if cfg.Database.Driver != "" {
+		db, err := database.NewDatabase(&cfg.Database)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
 		}
-		return nil, err
+		if err := db.AutoMigrate(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+
+		service.database = db
+		service.activities = activity.NewStore(db.GetDB(), service.inventory, logger)
 	}
 
-	return result.(*seckill.SeckillResult), nil
+	logger.Info("Seckill service created successfully")
+	return service, nil
 }
 
-// 执行秒杀逻辑
-func (s *SeckillService) executeSeckill(ctx context.Context, req *seckill.SeckillRequest) (*seckill.SeckillResult, error) {
-	s.stats.TotalRequests++
+// 风控计数规则，未配置窗口时使用默认窗口
+func riskCounterRule(counterCfg config.RiskCounterConfig, defaultWindow time.Duration) risk.CounterRule {
+	window := counterCfg.Window
+	if window <= 0 {
+		window = defaultWindow
+	}
+	return risk.CounterRule{
+		Limit:  counterCfg.Limit,
+		Window: window,
+		Score:  counterCfg.Score,
+	}
+}
 
-	// 执行秒杀核心逻辑
-	result, err := s.seckillCore.ExecuteSeckill(ctx, req)
-	if err != nil {
-		s.stats.FailedRequests++
-		s.logger.Errorf("Seckill execution failed: %v", err)
-		return &seckill.SeckillResult{
-			Code:    seckill.ResultSystemError,
-			Message: "系统错误",
-			Success: false,
-		}, nil
+// 展开多级限流器的各级
+func limiterLevels(limiter flowcontrol.Limiter) []flowcontrol.Limiter {
+	if multiLevel, ok := limiter.(*flowcontrol.MultiLevelLimiter); ok {
+		return multiLevel.Limiters()
 	}
+	return []flowcontrol.Limiter{limiter}
+}
 
-	// 如果秒杀成功，发送消息到队列
-	if result.Success {
-		s.stats.SuccessRequests++
+// 根据配置创建单机或集群 Redis 客户端
+func newRedisClient(cfg *config.Config) redis.UniversalClient {
+	addrs := cfg.Redis.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)}
+	}
 
-		// 生成订单ID
-		orderID := s.seckillCore.GenerateOrderID(req.ProductID, req.UserID)
-		result.OrderID = orderID
+	options := &redis.UniversalOptions{
+		Addrs:        addrs,
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		MinIdleConns: cfg.Redis.MinIdleConns,
+		MaxRetries:   cfg.Redis.MaxRetries,
+		DialTimeout:  cfg.Redis.DialTimeout,
+		ReadTimeout:  cfg.Redis.ReadTimeout,
+		WriteTimeout: cfg.Redis.WriteTimeout,
+	}
+	if cfg.Redis.Cluster {
+		return redis.NewClusterClient(options.Cluster())
+	}
+	return redis.NewClient(options.Simple())
+}
 
-		// 发送订单消息
-		if err := s.sendOrderMessage(ctx, req, orderID); err != nil {
-			s.logger.Errorf("Failed to send order message: %v", err)
-			// 不影响秒杀结果，只记录错误
+// 根据配置选择消息队列
+func newMessageQueue(cfg *config.Config, redisClient redis.UniversalClient, logger *logrus.Logger) (mq.MessageQueue, error) {
+	mqType := cfg.MQ.Type
+	if mqType == "" {
+		// 未指定类型时按已配置的连接信息选择
+		if cfg.RabbitMQ.URL != "" {
+			mqType = config.MQTypeRabbitMQ
+		} else if len(cfg.Kafka.Brokers) > 0 {
+			mqType = config.MQTypeKafka
+		} else {
+			return nil, nil
 		}
+	}
 
-		// 发送库存更新消息
-		if err := s.sendStockUpdateMessage(ctx, req.ProductID, result.RemainingStock); err != nil {
-			s.logger.Errorf("Failed to send stock update message: %v", err)
+	switch mqType {
+	case config.MQTypeRabbitMQ:
+		mqConfig := &mq.RabbitMQConfig{
+			URL:        cfg.RabbitMQ.URL,
+			Exchange:   cfg.RabbitMQ.Exchange,
+			Queue:      cfg.RabbitMQ.Queue,
+			RoutingKey: cfg.RabbitMQ.RoutingKey,
+			Durable:    cfg.RabbitMQ.Durable,
+			AutoDelete: cfg.RabbitMQ.AutoDelete,
 		}
-
-		s.logger.Infof("Seckill success: user=%d, product=%d, order=%s, remaining_stock=%d",
-			req.UserID, req.ProductID, orderID, result.RemainingStock)
-	} else {
-		s.stats.FailedRequests++
-		s.logger.Debugf("Seckill failed: user=%d, product=%d, reason=%s",
-			req.UserID, req.ProductID, result.Message)
+		return mq.NewRabbitMQProducer(mqConfig, logger)
+	case config.MQTypeKafka:
+		kafkaConfig := &mq.KafkaConfig{
+			Brokers:   cfg.Kafka.Brokers,
+			Topic:     cfg.Kafka.Topic,
+			Partition: cfg.Kafka.Partition,
+			Timeout:   cfg.Kafka.Timeout,
+		}
+		return mq.NewKafkaProducer(kafkaConfig, logger), nil
+	case config.MQTypeRedisStream:
+		streamConfig := &mq.RedisStreamConfig{
+			Stream: cfg.RedisStream.Stream,
+			MaxLen: cfg.RedisStream.MaxLen,
+		}
+		return mq.NewRedisStreamProducer(redisClient, streamConfig, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported message queue type: %s", mqType)
 	}
-
-	return result, nil
 }
 
-// 发送订单消息
-func (s *SeckillService) sendOrderMessage(ctx context.Context, req *seckill.SeckillRequest, orderID string) error {
-	if s.messageQueue == nil {
-		return nil
+// 创建订单ID生成器，工作节点ID取自配置或从 Redis 租用，限定在本服务的工作节点ID范围内
+func newIDGenerator(ctx context.Context, cfg *config.Config, redisClient redis.UniversalClient, logger *logrus.Logger) (*idgen.Generator, *idgen.WorkerLease, error) {
+	workerID := cfg.IDGenerator.WorkerID
+	if !cfg.IDGenerator.Lease && !idgen.SeckillWorkerIDs.Contains(workerID) {
+		return nil, nil, fmt.Errorf("%w: %d (seckill-service uses %d-%d)", idgen.ErrInvalidWorkerID, workerID, idgen.SeckillWorkerIDs.Min, idgen.SeckillWorkerIDs.Max)
 	}
 
-	// 获取商品价格（这里简化处理，实际应该从数据库获取）
-	price := 99.99 // 默认价格
+	var lease *idgen.WorkerLease
+	if cfg.IDGenerator.Lease {
+		hostname, _ := os.Hostname()
+		owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
 
-	message := mq.NewSeckillOrderMessage(
-		orderID,
-		req.ProductID,
-		req.UserID,
-		req.Quantity,
-		price,
-		s.generateTraceID(),
-	)
+		var err error
+		lease, err = idgen.AcquireWorkerLease(ctx, redisClient, "seckill:idgen:worker", idgen.SeckillWorkerIDs, owner, cfg.IDGenerator.LeaseTTL, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		workerID = lease.WorkerID()
+	}
+
+	generator, err := idgen.NewGenerator(workerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.IDGenerator.MaxClockBackward > 0 {
+		generator.SetMaxBackward(cfg.IDGenerator.MaxClockBackward)
+	}
 
-	return s.messageQueue.SendSeckillOrderMessage(ctx, message)
+	return generator, lease, nil
 }
 
-// 发送库存更新消息
-func (s *SeckillService) sendStockUpdateMessage(ctx context.Context, productID, remainingStock int64) error {
-	if s.messageQueue == nil {
-		return nil
+// 启动服务
+func (s *SeckillService) Start(ctx context.Context) error {
+	// 启动请求队列
+	s.requestQueue.Start(ctx)
+
+	// 订阅售罄广播
+	if s.soldOut != nil {
+		s.soldOut.Start(ctx)
 	}
 
-	message := mq.NewStockUpdateMessage(
-		productID,
-		remainingStock,
-		s.generateTraceID(),
-	)
+	// 启动过载检测
+	if s.overload != nil {
+		s.overload.Start(ctx)
+	}
 
-	return s.messageQueue.SendStockUpdateMessage(ctx, message)
-}
+	// 广播熔断器状态变化事件
+	go s.publishBreakerEvents(ctx)
 
-// 生成追踪ID
-func (s *SeckillService) generateTraceID() string {
-	return fmt.Sprintf("trace_%d", time.Now().UnixNano())
-}
+	// 启动 outbox 中继
+	if s.outboxRelay != nil {
+		if err := s.outboxRelay.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start outbox relay: %w", err)
+		}
+	}
 
-// 检查系统是否繁忙
-func (s *SeckillService) isSystemBusy() bool {
-	if !s.config.Seckill.Degradation.Enable {
-		return false
+	// 启动过期库存预留回收
+	if s.config.Seckill.Reservation.Enable {
+		go s.reapReservations(ctx)
 	}
 
-	// 检查 CPU 使用率
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
+	// 启动等候室放行
+	if s.waitingRoom != nil {
+		go s.admitWaitingRooms(ctx)
+	}
 
-	// 简化的系统负载检查
-	// 实际应该使用更精确的系统监控指标
-	return false
-}
+	// 启动抽签活动自动开奖与资格回收
+	go s.runLotteries(ctx)
 
-// 处理队列项
-func (s *SeckillService) processQueueItem(ctx context.Context, item *flowcontrol.QueueItem) (interface{}, error) {
-	req, ok := item.Request.(*seckill.SeckillRequest)
-	if !ok {
-		return nil, fmt.Errorf("invalid request type")
+	// 启动库存预留结算，活动结束后归还未售出的库存
+	if s.inventory != nil {
+		go s.settleInventoryReservations(ctx)
 	}
 
-	return s.executeSeckill(ctx, req)
-}
+	// 定期写入活动报告计数
+	if s.report != nil {
+		go s.report.Start(ctx)
+	}
 
-// 异步处理秒杀请求
-func (s *SeckillService) ProcessSeckillAsync(ctx context.Context, req *seckill.SeckillRequest) error {
-	// 检查系统负载
-	if s.isSystemBusy() {
-		s.stats.SystemBusyRequests++
-		return fmt.Errorf("system is busy")
+	// 启动实时看板广播
+	if s.dashboard != nil {
+		s.dashboard.Start(ctx)
 	}
 
-	// 限流检查
-	if !s.limiter.Allow() {
-		s.stats.RateLimitedRequests++
-		return fmt.Errorf("rate limited")
+	// 维持工作节点ID租约，丢失后停止生成订单ID直到重新租用
+	if s.workerLease != nil {
+		go s.workerLease.KeepAlive(ctx, s.idGenerator)
 	}
 
-	// 提交到请求队列
-	requestID := fmt.Sprintf("seckill_%d_%d_%d", req.ProductID, req.UserID, time.Now().UnixNano())
+	s.logger.Info("Seckill service started")
+	return nil
+}
 
-	err := s.requestQueue.SubmitAsync(ctx, requestID, req, 30*time.Second, func(result interface{}, err error) {
-		if err != nil {
-			s.logger.Errorf("Async seckill failed: %v", err)
-			return
-		}
+// 定期归还超时未支付的库存预留
+func (s *SeckillService) reapReservations(ctx context.Context) {
+	interval := s.config.Seckill.Reservation.ReapInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	batch := s.config.Seckill.Reservation.ReapBatch
+	if batch <= 0 {
+		batch = 100
+	}
 
-		seckillResult := result.(*seckill.SeckillResult)
-		s.logger.Infof("Async seckill completed: %+v", seckillResult)
-	})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	if err != nil {
-		if err == flowcontrol.ErrQueueFull {
-			s.stats.QueueFullRequests++
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			productIDs, err := s.seckillCore.ListActivityProducts(ctx)
+			if err != nil {
+				s.logger.Errorf("Failed to list activities for reservation reaping: %v", err)
+				continue
+			}
+
+			for _, productID := range productIDs {
+				released, err := s.seckillCore.ReleaseExpiredReservations(ctx, productID, time.Now(), batch)
+				if err != nil {
+					s.logger.Errorf("Failed to release expired reservations for product %d: %v", productID, err)
+					continue
+				}
+				if released > 0 {
+					s.stats.ReleasedReservations += int64(released)
+					s.logger.Infof("Released %d expired reservations for product %d", released, productID)
+				}
+			}
 		}
-		return err
 	}
-
-	return nil
 }
 
-// 预热活动
-func (s *SeckillService) PrewarmActivity(ctx context.Context, activity *seckill.SeckillActivity) error {
-	return s.seckillCore.PrewarmActivity(ctx, activity)
-}
+// 定期释放已结束活动的库存预留
+func (s *SeckillService) settleInventoryReservations(ctx context.Context) {
+	interval := s.config.Inventory.SettleInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
 
-// 获取秒杀统计信息
-func (s *SeckillService) GetSeckillStats(ctx context.Context, productID int64) (*seckill.SeckillStats, error) {
-	return s.seckillCore.GetSeckillStats(ctx, productID)
-}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-// 检查用户购买状态
-func (s *SeckillService) IsUserPurchased(ctx context.Context, productID, userID int64) (bool, error) {
-	return s.seckillCore.IsUserPurchased(ctx, productID, userID)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			productIDs, err := s.seckillCore.ListActivityProducts(ctx)
+			if err != nil {
+				s.logger.Errorf("Failed to list activities for inventory settlement: %v", err)
+				continue
+			}
+
+			for _, productID := range productIDs {
+				if err := s.settleInventory(ctx, productID, false); err != nil {
+					s.logger.Errorf("Failed to settle inventory reservation for product %d: %v", productID, err)
+				}
+			}
+		}
+	}
 }
 
-// 获取用户购买信息
+// 释放活动的库存预留，剩余库存归还库存服务，其余视为已售出。
+// 未强制时等活动结束且没有待支付的订单预留后再释放；强制释放（清理活动、重新预热其他场次）时
+// 待支付的订单预留随活动数据一起丢弃，同样归还。
+func (s *SeckillService) settleInventory(ctx context.Context, productID int64, force bool) error {
+	reservation, err := s.seckillCore.GetInventoryReservation(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if reservation == nil || reservation.SettledAt != nil {
+		return nil
+	}
+
+	pending, pendingQuantity, err := s.seckillCore.PendingReservations(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if !force && (time.Now().Before(reservation.EndTime) || pending > 0) {
+		return nil
+	}
+
+	remaining, err := s.seckillCore.RemainingStock(ctx, productID)
+	if err != nil {
+		return err
+	}
+	returned := remaining
+	if force {
+		returned += pendingQuantity
+	}
+	returned = min(max(returned, 0), reservation.Quantity)
+
+	reason := "秒杀活动结束"
+	if force {
+		reason = "秒杀活动清理"
+	}
+	returned, err = s.inventory.Release(ctx, reservation.ID, returned, reason)
+	if errors.Is(err, activity.ErrReservationNotFound) {
+		// 库存服务中没有该预留（例如被人工清理），只在本地标记为已释放
+		s.logger.Warnf("Inventory reservation %s not found in inventory service", reservation.ID)
+		returned, err = 0, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reservation.SettledAt = &now
+	reservation.Returned = returned
+	if err := s.seckillCore.SaveInventoryReservation(ctx, reservation); err != nil {
+		return err
+	}
+	if s.activities != nil {
+		if err := s.activities.MarkSettled(ctx, reservation); err != nil {
+			s.logger.Warnf("Inventory reservation %s settled but failed to record it: %v", reservation.ID, err)
+		}
+	}
+
+	s.stats.SettledReservations++
+	s.logger.Infof("Settled inventory reservation %s for product %d: reserved %d, returned %d",
+		reservation.ID, productID, reservation.Quantity, returned)
+	return nil
+}
+
+// 按各活动的放行间隔分批放行等候室用户
+func (s *SeckillService) admitWaitingRooms(ctx context.Context) {
+	// 以最短的放行间隔轮询，各活动的实际放行频率由等候室的放行锁控制
+	interval := s.roomSettings(0).AdmitInterval
+	for _, activityCfg := range s.config.Seckill.WaitingRoom.Activities {
+		if activityCfg.AdmitInterval > 0 && activityCfg.AdmitInterval < interval {
+			interval = activityCfg.AdmitInterval
+		}
+	}
+	interval = max(interval, 100*time.Millisecond)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			productIDs, err := s.seckillCore.ListActivityProducts(ctx)
+			if err != nil {
+				s.logger.Errorf("Failed to list activities for waiting room admission: %v", err)
+				continue
+			}
+
+			now := time.Now()
+			for _, productID := range productIDs {
+				settings, ok := s.waitingRoomSettings(productID)
+				if !ok {
+					continue
+				}
+
+				// 活动开始后才放行
+				activity, err := s.seckillCore.GetActivity(ctx, productID)
+				if err != nil {
+					if err != seckill.ErrActivityNotFound {
+						s.logger.Errorf("Failed to get activity %d for waiting room admission: %v", productID, err)
+					}
+					continue
+				}
+				if now.Before(activity.StartTime) || (!activity.EndTime.IsZero() && now.After(activity.EndTime)) {
+					continue
+				}
+
+				admitted, err := s.waitingRoom.Admit(ctx, productID, settings)
+				if err != nil {
+					s.logger.Errorf("Failed to admit waiting room users for product %d: %v", productID, err)
+					continue
+				}
+				if admitted > 0 {
+					s.stats.AdmittedUsers += admitted
+					s.logger.Debugf("Admitted %d users from waiting room for product %d", admitted, productID)
+				}
+			}
+		}
+	}
+}
+
+// 定期检查抽签活动：登记截止后自动开奖，回收逾期的购买资格转给候补
+func (s *SeckillService) runLotteries(ctx context.Context) {
+	interval := s.config.Seckill.Lottery.CheckInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	batch := s.config.Seckill.Lottery.PromoteBatch
+	if batch <= 0 {
+		batch = 100
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			productIDs, err := s.seckillCore.ListActivityProducts(ctx)
+			if err != nil {
+				s.logger.Errorf("Failed to list activities for lottery check: %v", err)
+				continue
+			}
+
+			for _, productID := range productIDs {
+				activity, err := s.seckillCore.GetActivity(ctx, productID)
+				if err != nil {
+					if err != seckill.ErrActivityNotFound {
+						s.logger.Errorf("Failed to get activity %d for lottery check: %v", productID, err)
+					}
+					continue
+				}
+				if !activity.IsLottery() {
+					continue
+				}
+				s.checkLottery(ctx, activity, batch)
+			}
+		}
+	}
+}
+
+// 检查单个抽签活动
+func (s *SeckillService) checkLottery(ctx context.Context, activity *seckill.SeckillActivity, batch int64) {
+	drawn, err := s.lottery.Drawn(ctx, activity.ProductID)
+	if err != nil {
+		s.logger.Errorf("Failed to check lottery draw for product %d: %v", activity.ProductID, err)
+		return
+	}
+
+	if !drawn {
+		if activity.Lottery == nil || time.Now().Before(activity.Lottery.EntryEndTime) {
+			return
+		}
+		// 自动开奖使用随机种子，多个节点同时开奖时只有一个结果生效
+		if _, err := s.lottery.Draw(ctx, activity.ProductID, activity.Stock, s.claimWindow(activity)); err != nil &&
+			err != lottery.ErrAlreadyDrawn && err != lottery.ErrDrawing {
+			s.logger.Errorf("Failed to draw lottery for product %d: %v", activity.ProductID, err)
+		}
+		return
+	}
+
+	promoted, err := s.lottery.PromoteWaitlist(ctx, activity.ProductID, s.claimWindow(activity), batch)
+	if err != nil {
+		s.logger.Errorf("Failed to promote lottery waitlist for product %d: %v", activity.ProductID, err)
+		return
+	}
+	if promoted > 0 {
+		s.stats.PromotedLotteryRights += int64(promoted)
+		s.logger.Infof("Promoted %d waitlisted users for product %d", promoted, activity.ProductID)
+	}
+}
+
+// 中签后的购买期限
+func (s *SeckillService) claimWindow(activity *seckill.SeckillActivity) time.Duration {
+	if activity.Lottery != nil && activity.Lottery.ClaimWindowSeconds > 0 {
+		return time.Duration(activity.Lottery.ClaimWindowSeconds) * time.Second
+	}
+	if s.config.Seckill.Lottery.ClaimWindow > 0 {
+		return s.config.Seckill.Lottery.ClaimWindow
+	}
+	return 30 * time.Minute
+}
+
+// 停止服务
+func (s *SeckillService) Stop() error {
+	// 写入剩余的活动报告计数
+	if s.report != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		if err := s.report.Flush(ctx); err != nil {
+			s.logger.Errorf("Failed to flush report counters: %v", err)
+		}
+		cancel()
+	}
+	if s.workerLease != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		if err := s.workerLease.Release(ctx); err != nil {
+			s.logger.Errorf("Failed to release worker lease: %v", err)
+		}
+		cancel()
+	}
+	if s.redisClient != nil {
+		s.redisClient.Close()
+	}
+	if s.messageQueue != nil {
+		s.messageQueue.Close()
+	}
+	if s.database != nil {
+		s.database.Close()
+	}
+
+	s.logger.Info("Seckill service stopped")
+	return nil
+}
+
+// 开始下线：拒绝新的秒杀请求、健康检查返回不健康，在 ctx 结束前处理完已排队的请求，
+// 超时后直接拒绝尚未处理的排队请求
+func (s *SeckillService) Drain(ctx context.Context) {
+	s.draining.Store(true)
+	s.logger.Info("Draining seckill service")
+
+	rejected := s.requestQueue.Drain(ctx)
+	s.stats.DrainRejected += int64(rejected)
+}
+
+// 是否正在下线
+func (s *SeckillService) Draining() bool {
+	return s.draining.Load()
+}
+
+// 秒杀请求处理，client 为风控使用的请求来源信息，可为空
+func (s *SeckillService) ProcessSeckill(ctx context.Context, req *seckill.SeckillRequest, client *risk.Client) (*seckill.SeckillResult, error) {
+	received := time.Now()
+
+	// 下线中不再接收新请求，由网关重试到其他节点
+	if s.draining.Load() {
+		s.stats.DrainRejected++
+		s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultSystemBusy,
+			Message: "服务正在下线，请稍后重试",
+			Success: false,
+		}, nil
+	}
+
+	// 已售罄的商品直接拒绝，不经过限流等需要访问 Redis 的环节
+	if s.seckillCore.IsSoldOut(req.ProductID) {
+		s.stats.SoldOutRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeSoldOut, received)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultInsufficientStock,
+			Message: s.seckillCore.ResultMessage(seckill.ResultInsufficientStock),
+			Success: false,
+		}, nil
+	}
+
+	// 检查系统负载
+	if s.isSystemBusy() {
+		s.stats.SystemBusyRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultSystemBusy,
+			Message: s.config.Seckill.Degradation.ResponseMessage,
+			Success: false,
+		}, nil
+	}
+
+	// 热点参数限流，先于全局限流，避免热点商品耗尽全局配额
+	if message, ok := s.allowHotspot(req); !ok {
+		s.stats.HotspotLimitedRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeRateLimited, received)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultSystemBusy,
+			Message: message,
+			Success: false,
+		}, nil
+	}
+
+	// 抽签活动只允许中签用户购买
+	if code, err := s.checkPurchaseRight(ctx, req); err != nil {
+		s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		return nil, err
+	} else if code != seckill.ResultSuccess {
+		s.recordOutcome(req.ProductID, report.ResultOutcome(code), received)
+		return &seckill.SeckillResult{
+			Code:    code,
+			Message: s.seckillCore.ResultMessage(code),
+			Success: false,
+		}, nil
+	}
+
+	// 风控评估
+	degraded, err := s.checkRisk(ctx, req, client)
+	if err != nil {
+		s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		code := seckill.ResultRiskRejected
+		if errors.Is(err, risk.ErrChallengeRequired) {
+			code = seckill.ResultRiskChallenge
+		}
+		return &seckill.SeckillResult{
+			Code:    code,
+			Message: s.seckillCore.ResultMessage(code),
+			Success: false,
+		}, nil
+	}
+
+	// 限流检查
+	if !s.limiter.Allow() {
+		s.stats.RateLimitedRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeRateLimited, received)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultSystemBusy,
+			Message: "请求过于频繁，请稍后重试",
+			Success: false,
+		}, nil
+	}
+
+	start := time.Now()
+	var result interface{}
+	if degraded {
+		// 高风险请求排在最低优先级队列，只使用其他请求剩余的处理能力，处理时同样经过熔断器
+		result, err = s.requestQueue.Submit(ctx, "", req, s.requestTimeout())
+	} else {
+		// 熔断器检查
+		result, err = s.circuitBreaker.ExecuteWithContext(ctx, func(ctx context.Context) (interface{}, error) {
+			return s.executeSeckill(ctx, req)
+		})
+	}
+	s.completeRequest(start, result, err)
+
+	if err != nil {
+		if err == flowcontrol.ErrCircuitBreakerOpen || err == flowcontrol.ErrQueueFull || err == flowcontrol.ErrQueueClosed {
+			switch err {
+			case flowcontrol.ErrCircuitBreakerOpen:
+				s.stats.CircuitBreakerTrips++
+				s.recordOutcome(req.ProductID, report.OutcomeCircuitBreaker, received)
+			case flowcontrol.ErrQueueFull:
+				s.stats.QueueFullRequests++
+				s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+			default:
+				// 队列已关闭（服务下线）
+				s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+			}
+			return &seckill.SeckillResult{
+				Code:    seckill.ResultSystemBusy,
+				Message: "系统繁忙，请稍后重试",
+				Success: false,
+			}, nil
+		}
+		s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		return nil, err
+	}
+
+	seckillResult := result.(*seckill.SeckillResult)
+	s.recordOutcome(req.ProductID, report.ResultOutcome(seckillResult.Code), received)
+	s.recordRiskOutcome(ctx, req, client, seckillResult)
+	return seckillResult, nil
+}
+
+// 风控评估，返回请求是否降级到最低优先级；拒绝或需要挑战时返回错误
+func (s *SeckillService) checkRisk(ctx context.Context, req *seckill.SeckillRequest, client *risk.Client) (bool, error) {
+	if s.riskEngine == nil {
+		return false, nil
+	}
+
+	assessment, err := s.riskEngine.Check(ctx, req.ProductID, req.UserID, client)
+	if err != nil {
+		if errors.Is(err, risk.ErrChallengeRequired) {
+			s.stats.RiskChallenged++
+			// 提交了错误的解也算一次失败尝试
+			if client != nil && client.Challenge != "" {
+				s.riskEngine.RecordFailure(ctx, req.UserID)
+			}
+		} else {
+			s.stats.RiskRejected++
+		}
+		s.logger.Infof("Risk control %s: user=%d, product=%d, score=%d",
+			assessment.Decision, req.UserID, req.ProductID, assessment.Score)
+		return false, err
+	}
+
+	if assessment.Decision == risk.DecisionDegrade {
+		s.stats.RiskDegraded++
+		return true, nil
+	}
+	return false, nil
+}
+
+// 按秒杀结果更新风控信号，系统原因导致的失败不计入
+func (s *SeckillService) recordRiskOutcome(ctx context.Context, req *seckill.SeckillRequest, client *risk.Client, result *seckill.SeckillResult) {
+	if s.riskEngine == nil || result == nil {
+		return
+	}
+
+	switch result.Code {
+	case seckill.ResultSuccess:
+		s.riskEngine.RecordPurchase(ctx, client)
+	case seckill.ResultSystemError, seckill.ResultSystemBusy, seckill.ResultRequestTimeout:
+	default:
+		s.riskEngine.RecordFailure(ctx, req.UserID)
+	}
+}
+
+// 校验抽签活动的购买资格，非抽签活动直接通过。
+// 每次请求都读取活动信息判断活动类型，不使用节点缓存，新预热的抽签活动在所有节点立即生效；
+// 读取失败时拒绝请求，活动信息尚未写入（预热中）时按活动不存在处理
+func (s *SeckillService) checkPurchaseRight(ctx context.Context, req *seckill.SeckillRequest) (int, error) {
+	activity, err := s.seckillCore.GetActivity(ctx, req.ProductID)
+	if err != nil {
+		if errors.Is(err, seckill.ErrActivityNotFound) {
+			return seckill.ResultActivityNotFound, nil
+		}
+		return 0, err
+	}
+	if !activity.IsLottery() {
+		return seckill.ResultSuccess, nil
+	}
+
+	// 每个中签资格对应一件库存
+	if req.Quantity != 1 {
+		return seckill.ResultInvalidQuantity, nil
+	}
+
+	hasRight, err := s.lottery.HasRight(ctx, req.ProductID, req.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if !hasRight {
+		return seckill.ResultNoPurchaseRight, nil
+	}
+	return seckill.ResultSuccess, nil
+}
+
+// 热点参数名
+const (
+	hotspotProduct = "product"
+	hotspotUser    = "user"
+)
+
+// 热点参数限流检查，拒绝时返回提示信息
+func (s *SeckillService) allowHotspot(req *seckill.SeckillRequest) (string, bool) {
+	for _, hotspot := range s.hotspots {
+		switch hotspot.Name() {
+		case hotspotProduct:
+			if !hotspot.Allow(strconv.FormatInt(req.ProductID, 10)) {
+				return "当前商品抢购人数过多，请稍后重试", false
+			}
+		case hotspotUser:
+			if !hotspot.Allow(strconv.FormatInt(req.UserID, 10)) {
+				return "请求过于频繁，请稍后重试", false
+			}
+		}
+	}
+	return "", true
+}
+
+// 获取各参数的热点值
+func (s *SeckillService) GetHotKeys() map[string][]flowcontrol.HotKey {
+	hotKeys := make(map[string][]flowcontrol.HotKey, len(s.hotspots))
+	for _, hotspot := range s.hotspots {
+		hotKeys[hotspot.Name()] = hotspot.HotKeys(s.config.Seckill.Hotspot.TopN)
+	}
+	return hotKeys
+}
+
+// 归还未执行请求占用的并发配额
+func (s *SeckillService) releaseRequest() {
+	if s.adaptive != nil {
+		s.adaptive.Release(1)
+	}
+}
+
+// 向自适应限流器反馈请求结果
+func (s *SeckillService) completeRequest(start time.Time, result interface{}, err error) {
+	if s.adaptive == nil {
+		return
+	}
+
+	// 被熔断或队列拒绝的请求未实际执行，只归还配额
+	if err == flowcontrol.ErrCircuitBreakerOpen || err == flowcontrol.ErrTooManyRequests ||
+		err == flowcontrol.ErrQueueFull || err == flowcontrol.ErrQueueClosed {
+		s.releaseRequest()
+		return
+	}
+
+	success := err == nil
+	if seckillResult, ok := result.(*seckill.SeckillResult); ok && seckillResult.Code == seckill.ResultSystemError {
+		success = false
+	}
+	s.adaptive.Complete(1, time.Since(start), success)
+}
+
+// 记录活动报告与实时看板中的请求结果，received 为收到请求的时间
+func (s *SeckillService) recordOutcome(productID int64, outcome report.Outcome, received time.Time) {
+	if s.report != nil {
+		s.report.Record(productID, outcome, received, time.Since(received))
+	}
+	if s.dashboard != nil {
+		s.dashboard.Record(productID, outcome)
+	}
+}
+
+// 执行秒杀逻辑
+func (s *SeckillService) executeSeckill(ctx context.Context, req *seckill.SeckillRequest) (*seckill.SeckillResult, error) {
+	s.stats.TotalRequests++
+
+	// 先生成订单ID，避免扣减库存后才发现无法生成
+	orderID, err := s.seckillCore.GenerateOrderID()
+	if err != nil {
+		s.stats.FailedRequests++
+		s.logger.Errorf("Failed to generate order id: %v", err)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultSystemError,
+			Message: "系统错误",
+			Success: false,
+		}, nil
+	}
+
+	// 发券活动不创建订单，改为发送发券消息，订单ID作为发放流水号
+	coupon, err := s.seckillCore.IsCouponActivity(ctx, req.ProductID)
+	if err != nil {
+		s.stats.FailedRequests++
+		s.logger.Errorf("Failed to resolve activity type: %v", err)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultSystemError,
+			Message: "系统错误",
+			Success: false,
+		}, nil
+	}
+
+	// 构建订单事件，随库存扣减一起写入 outbox
+	var event *seckill.OrderEvent
+	if s.outboxRelay != nil {
+		event, err = s.buildOrderEvent(req, orderID, coupon)
+		if err != nil {
+			s.stats.FailedRequests++
+			s.logger.Errorf("Failed to build order event: %v", err)
+			return &seckill.SeckillResult{
+				Code:    seckill.ResultSystemError,
+				Message: "系统错误",
+				Success: false,
+			}, nil
+		}
+	}
+
+	// 执行秒杀核心逻辑
+	result, err := s.seckillCore.ExecuteSeckill(ctx, req, orderID, event)
+	if err != nil {
+		s.stats.FailedRequests++
+		s.logger.Errorf("Seckill execution failed: %v", err)
+		return &seckill.SeckillResult{
+			Code:    seckill.ResultSystemError,
+			Message: "系统错误",
+			Success: false,
+		}, nil
+	}
+
+	// 未启用 outbox 时直接发送订单消息；只有确定未被消息队列接受时才回滚已扣减的库存，
+	// 等待确认超时（包括下线时请求被取消）时消息可能已投递，回滚会导致超卖，保留扣减并记录待对账
+	if result.Success && event == nil {
+		if coupon {
+			err = s.sendCouponMessage(ctx, req, orderID, result.CouponCode)
+		} else {
+			err = s.sendOrderMessage(ctx, req, orderID)
+		}
+		if err != nil && !errors.Is(err, mq.ErrNotPublished) {
+			s.logger.Errorf("Order message delivery unknown, keeping purchase for reconciliation: user=%d, product=%d, order=%s, error=%v",
+				req.UserID, req.ProductID, orderID, err)
+			err = nil
+		}
+		if err != nil {
+			s.logger.Errorf("Failed to send order message, rolling back purchase: user=%d, product=%d, order=%s, error=%v",
+				req.UserID, req.ProductID, orderID, err)
+			s.rollbackPurchase(req)
+			s.stats.FailedRequests++
+			return &seckill.SeckillResult{
+				Code:    seckill.ResultSystemError,
+				Message: "系统错误",
+				Success: false,
+			}, nil
+		}
+	}
+
+	// 如果秒杀成功，发送库存更新消息
+	if result.Success {
+		s.stats.SuccessRequests++
+		if !coupon {
+			result.OrderID = orderID
+		}
+
+		// 发送库存更新消息
+		if err := s.sendStockUpdateMessage(ctx, req.ProductID, result.RemainingStock); err != nil {
+			s.logger.Errorf("Failed to send stock update message: %v", err)
+		}
+
+		// 最后一件售出时记录售罄时间
+		if result.RemainingStock == 0 && s.report != nil {
+			s.report.MarkSoldOut(req.ProductID, time.Now())
+		}
+
+		if coupon {
+			s.logger.Infof("Coupon granted: user=%d, product=%d, grant=%s, remaining_stock=%d",
+				req.UserID, req.ProductID, orderID, result.RemainingStock)
+		} else {
+			s.logger.Infof("Seckill success: user=%d, product=%d, order=%s, remaining_stock=%d",
+				req.UserID, req.ProductID, orderID, result.RemainingStock)
+		}
+	} else {
+		s.stats.FailedRequests++
+		s.logger.Debugf("Seckill failed: user=%d, product=%d, reason=%s",
+			req.UserID, req.ProductID, result.Message)
+	}
+
+	return result, nil
+}
+
+// 回滚未完成的购买，请求的上下文可能已取消，使用独立的超时
+func (s *SeckillService) rollbackPurchase(req *seckill.SeckillRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.seckillCore.RollbackStock(ctx, req.ProductID, req.UserID, req.Quantity); err != nil {
+		s.logger.Errorf("Failed to rollback purchase: user=%d, product=%d, error=%v", req.UserID, req.ProductID, err)
+		return
+	}
+	s.stats.RolledBackPurchases++
+}
+
+// 创建订单消息
+func (s *SeckillService) newOrderMessage(req *seckill.SeckillRequest, orderID string) *mq.SeckillOrderMessage {
+	// 获取商品价格（这里简化处理，实际应该从数据库获取）
+	price := 99.99 // 默认价格
+
+	return mq.NewSeckillOrderMessage(
+		orderID,
+		req.ProductID,
+		req.UserID,
+		req.Quantity,
+		price,
+		s.generateTraceID(),
+	)
+}
+
+// 构建写入 outbox 的订单事件，发券活动为不含券码的发券消息，券码由发券脚本追加
+func (s *SeckillService) buildOrderEvent(req *seckill.SeckillRequest, orderID string, coupon bool) (*seckill.OrderEvent, error) {
+	if coupon {
+		payload, err := mq.NewCouponGrantedMessage(orderID, req.ProductID, req.UserID, "", s.generateTraceID()).Marshal()
+		if err != nil {
+			return nil, err
+		}
+		return &seckill.OrderEvent{
+			Type:    mq.MessageTypeCouponGranted,
+			Payload: payload,
+		}, nil
+	}
+
+	payload, err := s.newOrderMessage(req, orderID).Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return &seckill.OrderEvent{
+		Type:    mq.MessageTypeSeckillOrder,
+		Payload: payload,
+	}, nil
+}
+
+// 发送订单消息
+func (s *SeckillService) sendOrderMessage(ctx context.Context, req *seckill.SeckillRequest, orderID string) error {
+	if s.messageQueue == nil {
+		return nil
+	}
+
+	return s.messageQueue.SendSeckillOrderMessage(ctx, s.newOrderMessage(req, orderID))
+}
+
+// 发送发券消息
+func (s *SeckillService) sendCouponMessage(ctx context.Context, req *seckill.SeckillRequest, grantID, couponCode string) error {
+	if s.messageQueue == nil {
+		return nil
+	}
+
+	return s.messageQueue.SendCouponGrantedMessage(ctx, mq.NewCouponGrantedMessage(grantID, req.ProductID, req.UserID, couponCode, s.generateTraceID()))
+}
+
+// 发送库存更新消息
+func (s *SeckillService) sendStockUpdateMessage(ctx context.Context, productID, remainingStock int64) error {
+	if s.messageQueue == nil {
+		return nil
+	}
+
+	message := mq.NewStockUpdateMessage(
+		productID,
+		remainingStock,
+		s.generateTraceID(),
+	)
+
+	return s.messageQueue.SendStockUpdateMessage(ctx, message)
+}
+
+// 生成追踪ID
+func (s *SeckillService) generateTraceID() string {
+	return fmt.Sprintf("trace_%d", time.Now().UnixNano())
+}
+
+// 检查系统是否繁忙
+func (s *SeckillService) isSystemBusy() bool {
+	if s.overload == nil {
+		return false
+	}
+
+	return s.overload.Overloaded()
+}
+
+// 处理队列项
+func (s *SeckillService) processQueueItem(ctx context.Context, item *flowcontrol.QueueItem) (interface{}, error) {
+	req, ok := item.Request.(*seckill.SeckillRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type")
+	}
+
+	// 更新票据状态为处理中，降级的同步请求没有票据
+	if item.ID != "" {
+		processing := newTicket(item.ID, req, item.Timestamp)
+		processing.Status = seckill.TicketProcessing
+		s.saveTicket(processing)
+	}
+
+	// 降级的同步请求与其他同步请求一样经过熔断器，熔断期间不再访问 Redis
+	if item.ID == "" {
+		return s.circuitBreaker.ExecuteWithContext(ctx, func(ctx context.Context) (interface{}, error) {
+			return s.executeSeckill(ctx, req)
+		})
+	}
+
+	return s.executeSeckill(ctx, req)
+}
+
+// 异步处理秒杀请求，返回用于查询结果的票据；tier 为网关转发的用户等级，可为空
+func (s *SeckillService) ProcessSeckillAsync(ctx context.Context, req *seckill.SeckillRequest, tier string, client *risk.Client) (*seckill.Ticket, error) {
+	received := time.Now()
+
+	// 下线中不再接收新请求
+	if s.draining.Load() {
+		s.stats.DrainRejected++
+		s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+		return nil, fmt.Errorf("service is draining")
+	}
+
+	// 已售罄的商品直接拒绝，不再排队
+	if s.seckillCore.IsSoldOut(req.ProductID) {
+		s.stats.SoldOutRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeSoldOut, received)
+		return nil, seckill.ErrSoldOut
+	}
+
+	// 检查系统负载
+	if s.isSystemBusy() {
+		s.stats.SystemBusyRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+		return nil, fmt.Errorf("system is busy: %s", s.config.Seckill.Degradation.ResponseMessage)
+	}
+
+	// 热点参数限流
+	if message, ok := s.allowHotspot(req); !ok {
+		s.stats.HotspotLimitedRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeRateLimited, received)
+		return nil, fmt.Errorf("hotspot limited: %s", message)
+	}
+
+	// 抽签活动只允许中签用户购买
+	if code, err := s.checkPurchaseRight(ctx, req); err != nil {
+		s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		return nil, err
+	} else if code != seckill.ResultSuccess {
+		s.recordOutcome(req.ProductID, report.ResultOutcome(code), received)
+		return nil, fmt.Errorf("purchase rejected: %s", s.seckillCore.ResultMessage(code))
+	}
+
+	// 风控评估
+	degraded, err := s.checkRisk(ctx, req, client)
+	if err != nil {
+		s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		return nil, err
+	}
+
+	// 限流检查
+	if !s.limiter.Allow() {
+		s.stats.RateLimitedRequests++
+		s.recordOutcome(req.ProductID, report.OutcomeRateLimited, received)
+		return nil, fmt.Errorf("rate limited")
+	}
+
+	// 创建票据，需在入队前写入，避免覆盖工作协程更新的状态
+	ticketID, err := s.seckillCore.GenerateTicketID()
+	if err != nil {
+		s.releaseRequest()
+		s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		return nil, fmt.Errorf("failed to generate ticket id: %w", err)
+	}
+	priority := s.userPriority(ctx, req.UserID, tier)
+	if degraded {
+		priority = s.requestQueue.LowestPriority()
+	}
+	ticket := newTicket(ticketID, req, time.Now())
+	ticket.Position = s.requestQueue.QueueLengthAhead(priority)
+	if err := s.seckillCore.SaveTicket(ctx, ticket, s.ticketTTL()); err != nil {
+		s.releaseRequest()
+		s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		return nil, err
+	}
+
+	// 提交到请求队列，结果回调与 HTTP 请求的生命周期无关
+	timeout := s.requestTimeout()
+	callbackCtx, cancel := context.WithTimeout(context.Background(), s.ticketTTL())
+	start := time.Now()
+
+	err = s.requestQueue.SubmitAsyncWithPriority(callbackCtx, ticketID, req, timeout, priority, func(result interface{}, err error) {
+		defer cancel()
+		s.completeRequest(start, result, err)
+
+		final := newTicket(ticketID, req, ticket.CreatedAt)
+		if err != nil {
+			s.logger.Errorf("Async seckill failed: ticket=%s, error=%v", ticketID, err)
+			s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+			final.Status = seckill.TicketFailed
+			final.Code = seckill.ResultSystemError
+			if err == flowcontrol.ErrTimeout || err == context.DeadlineExceeded {
+				final.Code = seckill.ResultRequestTimeout
+			}
+			final.Reason = err.Error()
+		} else {
+			seckillResult := result.(*seckill.SeckillResult)
+			s.logger.Infof("Async seckill completed: ticket=%s, result=%+v", ticketID, seckillResult)
+			s.recordRiskOutcome(callbackCtx, req, client, seckillResult)
+			s.recordOutcome(req.ProductID, report.ResultOutcome(seckillResult.Code), received)
+			final.Code = seckillResult.Code
+			if seckillResult.Success {
+				final.Status = seckill.TicketSuccess
+				final.OrderID = seckillResult.OrderID
+			} else {
+				final.Status = seckill.TicketFailed
+				final.Reason = seckillResult.Message
+			}
+		}
+		s.saveTicket(final)
+	})
+
+	if err != nil {
+		cancel()
+		s.completeRequest(start, nil, err)
+		if err == flowcontrol.ErrQueueFull {
+			s.stats.QueueFullRequests++
+			s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+		} else if err == flowcontrol.ErrQueueClosed {
+			s.stats.DrainRejected++
+			s.recordOutcome(req.ProductID, report.OutcomeSystemBusy, received)
+		} else {
+			s.recordOutcome(req.ProductID, report.OutcomeOther, received)
+		}
+		return nil, err
+	}
+
+	return ticket, nil
+}
+
+// 队列中请求的处理超时时间
+func (s *SeckillService) requestTimeout() time.Duration {
+	if timeout := s.config.Seckill.RequestTimeout; timeout > 0 {
+		return timeout
+	}
+	return 30 * time.Second
+}
+
+// 用户等级请求头
+func (s *SeckillService) UserTierHeader() string {
+	return s.config.Seckill.Priority.Header
+}
+
+// 确定用户的排队优先级
+func (s *SeckillService) userPriority(ctx context.Context, userID int64, tier string) int {
+	if !s.config.Seckill.Priority.Enable {
+		return 0
+	}
+
+	priority, _ := s.tierPriority(s.userTier(ctx, userID, tier))
+	return priority
+}
+
+// 确定用户等级：优先使用网关转发的等级，其次查询 Redis，最后使用默认等级
+func (s *SeckillService) userTier(ctx context.Context, userID int64, tier string) string {
+	priorityCfg := s.config.Seckill.Priority
+
+	if _, ok := s.tierPriority(tier); !ok && priorityCfg.RedisKeyPrefix != "" {
+		key := fmt.Sprintf("%s%d", priorityCfg.RedisKeyPrefix, userID)
+		stored, err := s.redisClient.Get(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			s.logger.Warnf("Failed to lookup user tier for %d: %v", userID, err)
+		}
+		tier = stored
+	}
+
+	if _, ok := s.tierPriority(tier); ok {
+		return tier
+	}
+	return priorityCfg.DefaultTier
+}
+
+// 等级对应的优先级
+func (s *SeckillService) tierPriority(tier string) (int, bool) {
+	if tier == "" {
+		return 0, false
+	}
+	for _, tierCfg := range s.config.Seckill.Priority.Tiers {
+		if tierCfg.Name == tier {
+			return tierCfg.Priority, true
+		}
+	}
+	return 0, false
+}
+
+// 准入令牌请求头
+func (s *SeckillService) AdmissionTokenHeader() string {
+	if header := s.config.Seckill.WaitingRoom.TokenHeader; header != "" {
+		return header
+	}
+	return "X-Admission-Token"
+}
+
+// 活动的等候室配置，未启用等候室时返回 false
+func (s *SeckillService) waitingRoomSettings(productID int64) (waitingroom.Settings, bool) {
+	roomCfg := s.config.Seckill.WaitingRoom
+	if s.waitingRoom == nil {
+		return waitingroom.Settings{}, false
+	}
+
+	// 配置了活动列表时只有列出的活动启用等候室
+	if len(roomCfg.Activities) > 0 {
+		if _, ok := roomCfg.Activities[strconv.FormatInt(productID, 10)]; !ok {
+			return waitingroom.Settings{}, false
+		}
+	}
+	return s.roomSettings(productID), true
+}
+
+// 合并活动配置与默认配置
+func (s *SeckillService) roomSettings(productID int64) waitingroom.Settings {
+	roomCfg := s.config.Seckill.WaitingRoom
+	settings := waitingroom.Settings{
+		AdmitBatch:    roomCfg.AdmitBatch,
+		AdmitInterval: roomCfg.AdmitInterval,
+		TokenTTL:      roomCfg.TokenTTL,
+	}
+
+	if activityCfg, ok := roomCfg.Activities[strconv.FormatInt(productID, 10)]; ok {
+		if activityCfg.AdmitBatch > 0 {
+			settings.AdmitBatch = activityCfg.AdmitBatch
+		}
+		if activityCfg.AdmitInterval > 0 {
+			settings.AdmitInterval = activityCfg.AdmitInterval
+		}
+		if activityCfg.TokenTTL > 0 {
+			settings.TokenTTL = activityCfg.TokenTTL
+		}
+	}
+
+	if settings.AdmitBatch <= 0 {
+		settings.AdmitBatch = 100
+	}
+	if settings.AdmitInterval <= 0 {
+		settings.AdmitInterval = time.Second
+	}
+	if settings.TokenTTL <= 0 {
+		settings.TokenTTL = 2 * time.Minute
+	}
+	return settings
+}
+
+// 加入活动等候室；tier 为网关转发的用户等级，VIP 用户按配置提前排队
+func (s *SeckillService) JoinWaitingRoom(ctx context.Context, productID, userID int64, tier string) (*waitingroom.Status, error) {
+	settings, ok := s.waitingRoomSettings(productID)
+	if !ok {
+		return nil, waitingroom.ErrRoomNotEnabled
+	}
+
+	skipAhead := s.config.Seckill.WaitingRoom.SkipAhead[s.userTier(ctx, userID, tier)]
+	return s.waitingRoom.Join(ctx, productID, userID, skipAhead, settings)
+}
+
+// 查询等候室排队状态
+func (s *SeckillService) GetWaitingRoomStatus(ctx context.Context, productID, userID int64) (*waitingroom.Status, error) {
+	settings, ok := s.waitingRoomSettings(productID)
+	if !ok {
+		return nil, waitingroom.ErrRoomNotEnabled
+	}
+
+	return s.waitingRoom.Status(ctx, productID, userID, settings)
+}
+
+// 校验下单请求的准入令牌，活动未启用等候室时直接通过
+func (s *SeckillService) CheckAdmission(productID, userID int64, token string) error {
+	if _, ok := s.waitingRoomSettings(productID); !ok {
+		return nil
+	}
+
+	if err := s.waitingRoom.Verify(token, productID, userID); err != nil {
+		s.stats.AdmissionRejected++
+		return err
+	}
+	return nil
+}
+
+// 网关转发的已认证用户ID请求头
+func (s *SeckillService) UserIDHeader() string {
+	if header := s.config.Seckill.AntiBot.UserHeader; header != "" {
+		return header
+	}
+	return "X-User-ID"
+}
+
+// 网关转发的用户角色请求头与访问管理接口所需的角色
+func (s *SeckillService) AdminRole() (header, role string) {
+	header, role = s.config.Server.AdminRolesHeader, s.config.Server.AdminRole
+	if header == "" {
+		header = "X-User-Roles"
+	}
+	if role == "" {
+		role = "admin"
+	}
+	return header, role
+}
+
+// 是否启用防刷购买路径
+func (s *SeckillService) PurchasePathEnabled() bool {
+	return s.antiBot != nil
+}
+
+// 校验领取购买路径的请求：限流并检查活动是否已进入发放时间
+func (s *SeckillService) checkPathIssue(ctx context.Context, productID, userID int64) error {
+	if s.antiBot == nil {
+		return antibot.ErrNotEnabled
+	}
+
+	if (s.pathLimiter != nil && !s.pathLimiter.Allow()) ||
+		(s.pathUsers != nil && !s.pathUsers.Allow(strconv.FormatInt(userID, 10))) {
+		s.stats.PathIssueRejected++
+		return antibot.ErrRateLimited
+	}
+
+	activity, err := s.seckillCore.GetActivity(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	issueAhead := s.config.Seckill.AntiBot.IssueAhead
+	if issueAhead <= 0 {
+		issueAhead = 30 * time.Second
+	}
+	now := time.Now()
+	if now.Before(activity.StartTime.Add(-issueAhead)) {
+		return antibot.ErrTooEarly
+	}
+	if !activity.EndTime.IsZero() && !now.Before(activity.EndTime) {
+		return antibot.ErrActivityEnded
+	}
+	return nil
+}
+
+// 获取工作量证明挑战
+func (s *SeckillService) GetPathChallenge(ctx context.Context, productID, userID int64) (*antibot.Challenge, error) {
+	if err := s.checkPathIssue(ctx, productID, userID); err != nil {
+		return nil, err
+	}
+	return s.antiBot.Challenge(ctx, productID, userID)
+}
+
+// 领取一次性购买路径，当前难度大于 0 时需要提交挑战的解
+func (s *SeckillService) IssuePurchasePath(ctx context.Context, req *antibot.PathRequest) (*antibot.PurchasePath, error) {
+	if err := s.checkPathIssue(ctx, req.ProductID, req.UserID); err != nil {
+		return nil, err
+	}
+
+	pathTTL := s.config.Seckill.AntiBot.PathTTL
+	if pathTTL <= 0 {
+		pathTTL = 30 * time.Second
+	}
+	path, err := s.antiBot.IssuePath(ctx, req, pathTTL)
+	if err != nil {
+		if errors.Is(err, antibot.ErrChallengeRequired) || errors.Is(err, antibot.ErrInvalidChallenge) ||
+			errors.Is(err, antibot.ErrChallengeExpired) || errors.Is(err, antibot.ErrInsufficientWork) {
+			s.stats.PathIssueRejected++
+		}
+		return nil, err
+	}
+
+	s.stats.PurchasePathsIssued++
+	return path, nil
+}
+
+// 核销下单请求携带的购买路径，未启用时直接通过
+func (s *SeckillService) CheckPurchasePath(ctx context.Context, productID, userID int64, path string) error {
+	if s.antiBot == nil {
+		return nil
+	}
+
+	if err := s.antiBot.ConsumePath(ctx, productID, userID, path); err != nil {
+		if errors.Is(err, antibot.ErrPathRequired) || errors.Is(err, antibot.ErrInvalidPath) {
+			s.stats.PathRejected++
+		}
+		return err
+	}
+	return nil
+}
+
+// 获取当前工作量证明难度
+func (s *SeckillService) GetPathDifficulty(ctx context.Context) (int, error) {
+	if s.antiBot == nil {
+		return 0, antibot.ErrNotEnabled
+	}
+	return s.antiBot.Difficulty(ctx), nil
+}
+
+// 调整工作量证明难度，攻击期间调高以增加刷单成本
+func (s *SeckillService) SetPathDifficulty(ctx context.Context, difficulty int) error {
+	if s.antiBot == nil {
+		return antibot.ErrNotEnabled
+	}
+
+	maxDifficulty := s.config.Seckill.AntiBot.MaxDifficulty
+	if maxDifficulty <= 0 {
+		maxDifficulty = 24
+	}
+	if difficulty < 0 || difficulty > maxDifficulty {
+		return fmt.Errorf("%w: must be between 0 and %d", antibot.ErrInvalidDifficulty, maxDifficulty)
+	}
+
+	if err := s.antiBot.SetDifficulty(ctx, difficulty); err != nil {
+		return err
+	}
+	s.logger.Warnf("Purchase path proof-of-work difficulty set to %d", difficulty)
+	return nil
+}
+
+// 风控使用的请求头：设备ID、挑战、挑战的解
+func (s *SeckillService) RiskHeaders() (device, challenge, nonce string) {
+	riskCfg := s.config.Seckill.Risk
+	device, challenge, nonce = riskCfg.DeviceHeader, riskCfg.Challenge.Header, riskCfg.Challenge.NonceHeader
+	if device == "" {
+		device = "X-Device-ID"
+	}
+	if challenge == "" {
+		challenge = "X-Risk-Challenge"
+	}
+	if nonce == "" {
+		nonce = "X-Risk-Nonce"
+	}
+	return device, challenge, nonce
+}
+
+// 签发风控挑战，客户端完成后携带挑战与解重试
+func (s *SeckillService) GetRiskChallenge(productID, userID int64) (*antibot.Challenge, error) {
+	if s.riskEngine == nil {
+		return nil, risk.ErrNotEnabled
+	}
+	return s.riskEngine.Challenge(productID, userID)
+}
+
+// 将用户加入黑名单，ttl 为 0 表示永久封禁
+func (s *SeckillService) AddToBlacklist(ctx context.Context, userID int64, reason string, ttl time.Duration) (*risk.BlacklistEntry, error) {
+	if s.riskEngine == nil {
+		return nil, risk.ErrNotEnabled
+	}
+	return s.riskEngine.AddToBlacklist(ctx, userID, reason, ttl)
+}
+
+// 将用户移出黑名单
+func (s *SeckillService) RemoveFromBlacklist(ctx context.Context, userID int64) (bool, error) {
+	if s.riskEngine == nil {
+		return false, risk.ErrNotEnabled
+	}
+	return s.riskEngine.RemoveFromBlacklist(ctx, userID)
+}
+
+// 列出黑名单
+func (s *SeckillService) ListBlacklist(ctx context.Context) ([]*risk.BlacklistEntry, error) {
+	if s.riskEngine == nil {
+		return nil, risk.ErrNotEnabled
+	}
+	return s.riskEngine.ListBlacklist(ctx)
+}
+
+// 查询用户的风险评分依据，client 可指定设备与 IP 一并评估
+func (s *SeckillService) ExplainRisk(ctx context.Context, userID int64, client *risk.Client) (*risk.Report, error) {
+	if s.riskEngine == nil {
+		return nil, risk.ErrNotEnabled
+	}
+	return s.riskEngine.Explain(ctx, userID, client)
+}
+
+// 创建票据
+func newTicket(ticketID string, req *seckill.SeckillRequest, createdAt time.Time) *seckill.Ticket {
+	return &seckill.Ticket{
+		ID:        ticketID,
+		ProductID: req.ProductID,
+		UserID:    req.UserID,
+		Quantity:  req.Quantity,
+		Status:    seckill.TicketQueued,
+		CreatedAt: createdAt,
+	}
+}
+
+// 票据保留时间
+func (s *SeckillService) ticketTTL() time.Duration {
+	if s.config.Seckill.TicketTTL > 0 {
+		return s.config.Seckill.TicketTTL
+	}
+	return 10 * time.Minute
+}
+
+// 更新票据状态，失败只记录日志
+func (s *SeckillService) saveTicket(ticket *seckill.Ticket) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.seckillCore.SaveTicket(ctx, ticket, s.ticketTTL()); err != nil {
+		s.logger.Errorf("Failed to save ticket %s: %v", ticket.ID, err)
+	}
+}
+
+// 查询异步秒杀结果
+func (s *SeckillService) GetSeckillResult(ctx context.Context, ticketID string) (*seckill.Ticket, error) {
+	ticket, err := s.seckillCore.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 排队中的票据按当前队列长度估算位置（队列只会向前推进）
+	if ticket.Status == seckill.TicketQueued {
+		if queueLength := s.requestQueue.QueueLength(); queueLength < ticket.Position {
+			ticket.Position = queueLength
+		}
+	} else {
+		ticket.Position = 0
+	}
+
+	return ticket, nil
+}
+
+// 预热活动，配置了库存服务时先按活动库存预留，预留失败则不预热
+func (s *SeckillService) PrewarmActivity(ctx context.Context, activity *seckill.SeckillActivity) (*seckill.InventoryReservation, error) {
+	reservation, previous, err := s.reserveInventory(ctx, activity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.seckillCore.PrewarmActivity(ctx, activity); err != nil {
+		// 活动未注册，结算任务找不到该预留，立即撤销
+		if reservation != nil {
+			s.undoInventoryReservation(reservation, previous)
+		}
+		return nil, err
+	}
+
+	// 记录活动信息，报告按活动开始时间计算售罄耗时
+	if s.report != nil {
+		if err := s.report.Begin(ctx, activity); err != nil {
+			s.logger.Warnf("Failed to begin report for product %d: %v", activity.ProductID, err)
+		}
+	}
+	return reservation, nil
+}
+
+// 向库存服务预留活动库存并记录到活动上，未配置库存服务或发券活动时返回 nil；
+// 同一场活动重复预热时同时返回调整前的预留，用于预热失败时恢复
+func (s *SeckillService) reserveInventory(ctx context.Context, activity *seckill.SeckillActivity) (*seckill.InventoryReservation, *seckill.InventoryReservation, error) {
+	if s.inventory == nil || activity.IsCoupon() {
+		return nil, nil, nil
+	}
+
+	id := seckill.InventoryReservationID(activity)
+
+	// 该商品上一场活动的预留尚未释放时先释放，新活动会覆盖它的库存
+	previous, err := s.seckillCore.GetInventoryReservation(ctx, activity.ProductID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if previous != nil && previous.BelongsTo(activity) {
+		if previous.Aborted {
+			// 上次预热失败时该预留ID已在库存服务中释放，不能再使用
+			id = fmt.Sprintf("%s-%d", id, time.Now().UnixMilli())
+		} else {
+			id = previous.ID
+		}
+	}
+	if previous != nil && previous.SettledAt == nil && previous.ID != id {
+		if err := s.settleInventory(ctx, activity.ProductID, true); err != nil {
+			return nil, nil, fmt.Errorf("failed to release previous inventory reservation: %w", err)
+		}
+	}
+	if previous != nil && previous.ID != id {
+		previous = nil
+	}
+
+	if err := s.inventory.Reserve(ctx, id, activity.ProductID, activity.Stock, "秒杀活动预热"); err != nil {
+		return nil, nil, err
+	}
+
+	reservation := &seckill.InventoryReservation{
+		ID:         id,
+		ProductID:  activity.ProductID,
+		Quantity:   activity.Stock,
+		EndTime:    activity.EndTime,
+		ReservedAt: time.Now(),
+	}
+	if err := s.seckillCore.SaveInventoryReservation(ctx, reservation); err != nil {
+		s.undoInventoryReservation(reservation, previous)
+		return nil, nil, err
+	}
+
+	s.logger.Infof("Reserved %d units from inventory for product %d (%s)", activity.Stock, activity.ProductID, id)
+	return reservation, previous, nil
+}
+
+// 撤销预热失败的库存预留：重复预热时恢复到调整前的数量，否则全部归还库存服务。
+// 请求的上下文可能已取消，使用独立的超时
+func (s *SeckillService) undoInventoryReservation(reservation, previous *seckill.InventoryReservation) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if previous != nil {
+		if err := s.inventory.Reserve(ctx, previous.ID, previous.ProductID, previous.Quantity, "秒杀活动预热失败"); err != nil {
+			s.logger.Errorf("Failed to restore inventory reservation %s to %d units: %v", previous.ID, previous.Quantity, err)
+			return
+		}
+		if err := s.seckillCore.SaveInventoryReservation(ctx, previous); err != nil {
+			s.logger.Errorf("Failed to restore inventory reservation record %s: %v", previous.ID, err)
+		}
+		return
+	}
+
+	returned, err := s.inventory.Release(ctx, reservation.ID, reservation.Quantity, "秒杀活动预热失败")
+	if err != nil {
+		s.logger.Errorf("Failed to release inventory reservation %s after prewarm failure, %d units remain reserved: %v",
+			reservation.ID, reservation.Quantity, err)
+		return
+	}
+
+	now := time.Now()
+	reservation.SettledAt = &now
+	reservation.Returned = returned
+	reservation.Aborted = true
+	if err := s.seckillCore.SaveInventoryReservation(ctx, reservation); err != nil {
+		s.logger.Errorf("Failed to save released inventory reservation %s: %v", reservation.ID, err)
+	}
+	s.logger.Warnf("Released inventory reservation %s after prewarm failure: returned %d", reservation.ID, returned)
+}
+
+// 是否启用活动管理
+func (s *SeckillService) ActivityManagementEnabled() bool {
+	return s.activities != nil
+}
+
+// 创建活动
+func (s *SeckillService) CreateActivity(ctx context.Context, req *model.ActivityRequest, operator string) (*model.Activity, error) {
+	if s.activities == nil {
+		return nil, activity.ErrNotEnabled
+	}
+	return s.activities.Create(ctx, req, operator)
+}
+
+// 修改活动
+func (s *SeckillService) UpdateActivity(ctx context.Context, id uint, req *model.ActivityRequest, operator string) (*model.Activity, error) {
+	if s.activities == nil {
+		return nil, activity.ErrNotEnabled
+	}
+	return s.activities.Update(ctx, id, req, operator)
+}
+
+// 删除活动，已预热的数据需通过清理接口删除
+func (s *SeckillService) DeleteActivity(ctx context.Context, id uint, operator, reason string) error {
+	if s.activities == nil {
+		return activity.ErrNotEnabled
+	}
+	return s.activities.Delete(ctx, id, operator, reason)
+}
+
+// 获取活动定义
+func (s *SeckillService) GetActivity(ctx context.Context, id uint) (*model.Activity, error) {
+	if s.activities == nil {
+		return nil, activity.ErrNotEnabled
+	}
+	return s.activities.Get(ctx, id)
+}
+
+// 分页查询活动
+func (s *SeckillService) ListActivities(ctx context.Context, query *model.ActivityQuery) ([]model.Activity, int64, error) {
+	if s.activities == nil {
+		return nil, 0, activity.ErrNotEnabled
+	}
+	return s.activities.List(ctx, query)
+}
+
+// 获取活动版本历史
+func (s *SeckillService) GetActivityVersions(ctx context.Context, id uint) ([]model.ActivityVersion, error) {
+	if s.activities == nil {
+		return nil, activity.ErrNotEnabled
+	}
+	return s.activities.Versions(ctx, id)
+}
+
+// 按活动表中的定义预热活动
+func (s *SeckillService) PrewarmStoredActivity(ctx context.Context, id uint) (*model.Activity, error) {
+	if s.activities == nil {
+		return nil, activity.ErrNotEnabled
+	}
+
+	stored, err := s.activities.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return stored, s.prewarmStored(ctx, stored)
+}
+
+// 预热商品当前或下一场未结束的活动
+func (s *SeckillService) PrewarmProduct(ctx context.Context, productID int64) (*model.Activity, error) {
+	if s.activities == nil {
+		return nil, activity.ErrNotEnabled
+	}
+
+	stored, err := s.activities.Current(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	return stored, s.prewarmStored(ctx, stored)
+}
+
+func (s *SeckillService) prewarmStored(ctx context.Context, stored *model.Activity) error {
+	if !time.Now().Before(stored.EndTime) {
+		return activity.ErrAlreadyEnded
+	}
+
+	reservation, err := s.PrewarmActivity(ctx, stored.ToSeckillActivity())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stored.PrewarmedAt = &now
+	if reservation != nil {
+		stored.ReservationID = reservation.ID
+		stored.ReservedStock = reservation.Quantity
+		stored.ReturnedStock = 0
+		stored.SettledAt = nil
+	}
+	if err := s.activities.MarkPrewarmed(ctx, stored.ID, now, reservation); err != nil {
+		s.logger.Warnf("Activity %d prewarmed but failed to record it: %v", stored.ID, err)
+	}
+	return nil
+}
+
+// 获取抽签活动
+func (s *SeckillService) getLotteryActivity(ctx context.Context, productID int64) (*seckill.SeckillActivity, error) {
+	activity, err := s.seckillCore.GetActivity(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !activity.IsLottery() || activity.Lottery == nil {
+		return nil, lottery.ErrNotLottery
+	}
+	return activity, nil
+}
+
+// 登记参与抽签
+func (s *SeckillService) RegisterLottery(ctx context.Context, productID, userID int64) (*lottery.Entry, error) {
+	activity, err := s.getLotteryActivity(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Before(activity.Lottery.EntryStartTime) || !now.Before(activity.Lottery.EntryEndTime) {
+		return nil, lottery.ErrEntryClosed
+	}
+
+	if err := s.lottery.Register(ctx, productID, userID); err != nil {
+		return nil, err
+	}
+	return s.lottery.GetEntry(ctx, productID, userID)
+}
+
+// 手动开奖，种子随机生成
+func (s *SeckillService) DrawLottery(ctx context.Context, productID int64) (*lottery.DrawRecord, error) {
+	activity, err := s.getLotteryActivity(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(activity.Lottery.EntryEndTime) {
+		return nil, lottery.ErrEntryOpen
+	}
+
+	return s.lottery.Draw(ctx, productID, activity.Stock, s.claimWindow(activity))
+}
+
+// 获取开奖记录
+func (s *SeckillService) GetLotteryDraw(ctx context.Context, productID int64) (*lottery.DrawRecord, error) {
+	return s.lottery.GetDraw(ctx, productID)
+}
+
+// 获取开奖记录与开奖时的参与者、中签名单
+func (s *SeckillService) GetLotteryDrawDetail(ctx context.Context, productID int64) (*lottery.DrawDetail, error) {
+	return s.lottery.GetDrawDetail(ctx, productID)
+}
+
+// 查询用户的抽签结果
+func (s *SeckillService) GetLotteryEntry(ctx context.Context, productID, userID int64) (*lottery.Entry, error) {
+	if _, err := s.getLotteryActivity(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.lottery.GetEntry(ctx, productID, userID)
+}
+
+// 获取秒杀统计信息
+func (s *SeckillService) GetSeckillStats(ctx context.Context, productID int64) (*seckill.SeckillStats, error) {
+	return s.seckillCore.GetSeckillStats(ctx, productID)
+}
+
+// 检查用户购买状态
+func (s *SeckillService) IsUserPurchased(ctx context.Context, productID, userID int64) (bool, error) {
+	return s.seckillCore.IsUserPurchased(ctx, productID, userID)
+}
+
+// 获取用户购买信息
 func (s *SeckillService) GetUserPurchaseInfo(ctx context.Context, productID, userID int64) (*seckill.UserPurchaseInfo, error) {
-	// 这里需要实现获取用户购买信息的逻辑
-	// 暂时返回模拟数据
-	return &seckill.UserPurchaseInfo{
-		UserID:       userID,
-		ProductID:    productID,
-		Quantity:     1,
-		PurchaseTime: time.Now(),
-		Status:       "success",
-	}, nil
+	return s.seckillCore.GetUserPurchaseInfo(ctx, productID, userID)
+}
+
+// 批量查询用户购买状态与购买信息
+func (s *SeckillService) BatchCheckUserStatus(ctx context.Context, productID int64, userIDs []int64) ([]*seckill.UserPurchaseInfo, error) {
+	return s.seckillCore.BatchCheckUserStatus(ctx, productID, userIDs)
+}
+
+// 批量查询单次最多的用户数
+func (s *SeckillService) BatchCheckMaxUsers() int {
+	if limit := s.config.Seckill.Buyers.BatchMaxUsers; limit > 0 {
+		return limit
+	}
+	return 200
+}
+
+// 单次导入券码的最大数量
+func (s *SeckillService) CouponMaxCodesPerRequest() int {
+	if limit := s.config.Seckill.Coupon.MaxCodesPerRequest; limit > 0 {
+		return limit
+	}
+	return 10000
+}
+
+// 向发券活动的券码池导入券码，返回导入后券码池中的券码数量
+func (s *SeckillService) AddCouponCodes(ctx context.Context, productID int64, codes []string) (int64, error) {
+	return s.seckillCore.AddCouponCodes(ctx, productID, codes)
+}
+
+// 券码池中尚未发出的券码数量
+func (s *SeckillService) GetCouponPoolSize(ctx context.Context, productID int64) (int64, error) {
+	return s.seckillCore.CouponPoolSize(ctx, productID)
+}
+
+// 按 SSCAN 分批遍历活动的全部购买者
+func (s *SeckillService) ExportBuyers(ctx context.Context, productID int64, fn func([]*seckill.UserPurchaseInfo) error) error {
+	count := s.config.Seckill.Buyers.ExportScanCount
+	if count <= 0 {
+		count = 500
+	}
+	return s.seckillCore.ScanBuyers(ctx, productID, count, fn)
+}
+
+// 生成活动报告，先写入本节点尚未写入的计数
+func (s *SeckillService) GetActivityReport(ctx context.Context, productID int64) (*report.Report, error) {
+	if s.report == nil {
+		return nil, report.ErrNotEnabled
+	}
+	if err := s.report.Flush(ctx); err != nil {
+		s.logger.Warnf("Failed to flush report counters: %v", err)
+	}
+	return s.report.Build(ctx, productID)
+}
+
+// 实时看板推送间隔
+func (s *SeckillService) DashboardInterval() time.Duration {
+	if s.dashboard == nil {
+		return 0
+	}
+	return s.dashboard.Interval()
+}
+
+// 获取活动的集群实时统计
+func (s *SeckillService) GetDashboardFrame(ctx context.Context, productID int64) (*dashboard.Frame, error) {
+	if s.dashboard == nil {
+		return nil, dashboard.ErrNotEnabled
+	}
+	stats, err := s.seckillCore.GetSeckillStats(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	return s.dashboard.Frame(productID, stats.CurrentStock), nil
 }
 
-// 清理活动数据
+// 清理活动数据，库存预留尚未释放时先按剩余库存释放
 func (s *SeckillService) CleanupActivity(ctx context.Context, productID int64) error {
-	return s.seckillCore.CleanupActivity(ctx, productID)
+	if s.inventory != nil {
+		if err := s.settleInventory(ctx, productID, true); err != nil {
+			return fmt.Errorf("failed to release inventory reservation: %w", err)
+		}
+	}
+
+	if err := s.seckillCore.CleanupActivity(ctx, productID); err != nil {
+		return err
+	}
+
+	if err := s.lottery.Cleanup(ctx, productID); err != nil {
+		return err
+	}
+
+	if s.waitingRoom != nil {
+		return s.waitingRoom.Cleanup(ctx, productID)
+	}
+	return nil
 }
 
 // 获取服务统计信息
 func (s *SeckillService) GetServiceStats() ServiceStats {
-	return s.stats
+	stats := s.stats
+	if s.adaptive != nil {
+		adaptiveStats := s.adaptive.GetStats()
+		stats.ConcurrencyLimit = adaptiveStats.Limit
+		stats.InFlightRequests = adaptiveStats.InFlight
+	}
+	return stats
+}
+
+// 获取本节点标记为售罄的商品
+func (s *SeckillService) GetSoldOutProducts() []int64 {
+	if s.soldOut == nil {
+		return nil
+	}
+	return s.soldOut.Products()
 }
 
 // 获取队列统计信息
@@ -398,21 +2368,111 @@ func (s *SeckillService) GetQueueStats() flowcontrol.QueueStats {
 	return s.requestQueue.GetStats()
 }
 
+// 获取各用户等级的队列统计信息
+func (s *SeckillService) GetPriorityQueueStats() map[string]flowcontrol.PriorityStats {
+	names := make(map[int]string)
+	if s.config.Seckill.Priority.Enable {
+		for _, tier := range s.config.Seckill.Priority.Tiers {
+			names[tier.Priority] = tier.Name
+		}
+	}
+
+	stats := make(map[string]flowcontrol.PriorityStats)
+	for _, priorityStats := range s.requestQueue.GetPriorityStats() {
+		name, ok := names[priorityStats.Priority]
+		if !ok {
+			name = strconv.Itoa(priorityStats.Priority)
+		}
+		stats[name] = priorityStats
+	}
+	return stats
+}
+
+// 获取 outbox 统计信息
+func (s *SeckillService) GetOutboxStats(ctx context.Context) *mq.OutboxStats {
+	if s.outboxRelay == nil {
+		return nil
+	}
+	stats := s.outboxRelay.GetStats(ctx)
+	return &stats
+}
+
+// 获取系统负载快照
+func (s *SeckillService) GetLoadSnapshot() *flowcontrol.LoadSnapshot {
+	if s.overload == nil {
+		return nil
+	}
+	snapshot := s.overload.Snapshot()
+	return &snapshot
+}
+
 // 获取熔断器状态
 func (s *SeckillService) GetCircuitBreakerState() flowcontrol.CircuitBreakerState {
 	return s.circuitBreaker.State()
 }
 
+// 获取本节点所有熔断器的状态快照
+func (s *SeckillService) GetCircuitBreakers() []flowcontrol.CircuitBreakerSnapshot {
+	return s.breakers.Snapshots()
+}
+
+// 获取本节点指定熔断器的状态快照
+func (s *SeckillService) GetCircuitBreaker(name string) (flowcontrol.CircuitBreakerSnapshot, bool) {
+	return s.breakers.Snapshot(name)
+}
+
+// 重置本节点的熔断器为关闭状态，返回熔断器是否存在
+func (s *SeckillService) ResetCircuitBreaker(name string) bool {
+	return s.breakers.ResetCircuitBreaker(name)
+}
+
+// 熔断器状态变化事件，附带节点标识
+type circuitBreakerEventMessage struct {
+	flowcontrol.CircuitBreakerEvent
+	Instance string `json:"instance"`
+}
+
+// 将熔断器状态变化事件广播到 Redis 频道，未配置频道时丢弃
+func (s *SeckillService) publishBreakerEvents(ctx context.Context) {
+	channel := s.config.Seckill.CircuitBreaker.EventChannel
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.breakerEvents:
+			if channel == "" {
+				continue
+			}
+
+			payload, err := json.Marshal(circuitBreakerEventMessage{CircuitBreakerEvent: event, Instance: s.instance})
+			if err != nil {
+				s.logger.Errorf("Failed to marshal circuit breaker event: %v", err)
+				continue
+			}
+			if err := s.redisClient.Publish(ctx, channel, payload).Err(); err != nil {
+				s.logger.Warnf("Failed to publish circuit breaker event: %v", err)
+			}
+		}
+	}
+}
+
 // 获取限流器状态
 func (s *SeckillService) GetLimiterTokens() int {
-	if tokenBucket, ok := s.limiter.(*flowcontrol.TokenBucketLimiter); ok {
-		return tokenBucket.GetTokens()
+	for _, limiter := range limiterLevels(s.limiter) {
+		if tokenBucket, ok := limiter.(*flowcontrol.TokenBucketLimiter); ok {
+			return tokenBucket.GetTokens()
+		}
 	}
 	return 0
 }
 
 // 健康检查
 func (s *SeckillService) HealthCheck(ctx context.Context) error {
+	// 下线中返回不健康，网关不再转发请求
+	if s.draining.Load() {
+		return fmt.Errorf("service is draining")
+	}
+
 	// 检查 Redis 连接
 	if err := s.redisClient.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("redis health check failed: %w", err)
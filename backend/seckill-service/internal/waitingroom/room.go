@@ -0,0 +1,232 @@
+package waitingroom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// 等候室数据保留时间，与活动预热数据一致
+const keyTTL = 24 * time.Hour
+
+// 排队状态
+type State string
+
+const (
+	StateNotJoined State = "not_joined"
+	StateWaiting   State = "waiting"
+	StateAdmitted  State = "admitted"
+)
+
+// 单个活动的等候室配置
+type Settings struct {
+	AdmitBatch    int64         // 每批放行人数
+	AdmitInterval time.Duration // 放行间隔
+	TokenTTL      time.Duration // 准入令牌有效期
+}
+
+// 加入等候室请求
+type JoinRequest struct {
+	ProductID int64 `json:"product_id"`
+	UserID    int64 `json:"user_id"`
+}
+
+// 用户排队状态
+type Status struct {
+	ProductID            int64      `json:"product_id"`
+	UserID               int64      `json:"user_id"`
+	State                State      `json:"state"`
+	Position             int64      `json:"position"`     // 前方排队人数
+	QueueLength          int64      `json:"queue_length"` // 等候室总人数
+	EstimatedWaitSeconds int64      `json:"estimated_wait_seconds"`
+	Token                string     `json:"token,omitempty"`
+	TokenExpiresAt       *time.Time `json:"token_expires_at,omitempty"`
+}
+
+// 加入或查询排队
+// KEYS[1]: 排队队列 ZSET（成员为用户ID，分数为加入时间）
+// KEYS[2]: 已放行用户 ZSET（分数为准入过期时间）
+// ARGV[1]: 用户ID
+// ARGV[2]: 排队分数，为空时只查询不加入
+// ARGV[3]: 当前时间（毫秒）
+// ARGV[4]: 队列过期时间（秒）
+// 返回 {1, 准入过期时间, 0} / {0, 前方人数, 总人数} / {-1, 0, 总人数}
+const joinLuaScript = `
+local expires_at = redis.call('ZSCORE', KEYS[2], ARGV[1])
+if expires_at and tonumber(expires_at) > tonumber(ARGV[3]) then
+    return {1, tonumber(expires_at), 0}
+end
+
+if ARGV[2] ~= '' then
+    redis.call('ZADD', KEYS[1], 'NX', ARGV[2], ARGV[1])
+    redis.call('EXPIRE', KEYS[1], ARGV[4])
+end
+
+local rank = redis.call('ZRANK', KEYS[1], ARGV[1])
+local total = redis.call('ZCARD', KEYS[1])
+if not rank then
+    return {-1, 0, total}
+end
+return {0, rank, total}
+`
+
+// 按加入顺序放行一批用户，同一间隔内集群只放行一次
+// KEYS[1]: 排队队列 ZSET
+// KEYS[2]: 已放行用户 ZSET
+// KEYS[3]: 放行锁
+// ARGV[1]: 放行人数
+// ARGV[2]: 当前时间（毫秒）
+// ARGV[3]: 准入过期时间（毫秒）
+// ARGV[4]: 放行锁时长（毫秒）
+// ARGV[5]: 已放行集合过期时间（秒）
+// 返回放行人数，本间隔已放行过时返回 -1
+const admitLuaScript = `
+if not redis.call('SET', KEYS[3], '1', 'NX', 'PX', ARGV[4]) then
+    return -1
+end
+
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', ARGV[2])
+
+local users = redis.call('ZRANGE', KEYS[1], 0, tonumber(ARGV[1]) - 1)
+if #users == 0 then
+    return 0
+end
+
+for _, user_id in ipairs(users) do
+    redis.call('ZADD', KEYS[2], ARGV[3], user_id)
+end
+redis.call('ZREMRANGEBYRANK', KEYS[1], 0, #users - 1)
+redis.call('EXPIRE', KEYS[2], ARGV[5])
+
+return #users
+`
+
+var (
+	joinScript  = redis.NewScript(joinLuaScript)
+	admitScript = redis.NewScript(admitLuaScript)
+)
+
+// 虚拟等候室：用户按加入时间排队，按固定速率分批放行，放行后签发短期准入令牌
+type Room struct {
+	client redis.Cmdable
+	signer *TokenSigner
+	logger *logrus.Logger
+}
+
+// 创建虚拟等候室
+func NewRoom(client redis.Cmdable, signer *TokenSigner, logger *logrus.Logger) *Room {
+	return &Room{
+		client: client,
+		signer: signer,
+		logger: logger,
+	}
+}
+
+// 同一活动的等候室 key 使用 {商品ID} hash tag，保证放行脚本在 Redis Cluster 下可用
+func queueKey(productID int64) string {
+	return fmt.Sprintf("seckill:waitingroom:{%d}", productID)
+}
+
+func admittedKey(productID int64) string {
+	return fmt.Sprintf("seckill:waitingroom:admitted:{%d}", productID)
+}
+
+func admitLockKey(productID int64) string {
+	return fmt.Sprintf("seckill:waitingroom:lock:{%d}", productID)
+}
+
+// 加入等候室，skipAhead 为 VIP 用户提前的排队时长；已加入时保持原位置
+func (r *Room) Join(ctx context.Context, productID, userID int64, skipAhead time.Duration, settings Settings) (*Status, error) {
+	score := time.Now().Add(-skipAhead).UnixMilli()
+	return r.query(ctx, productID, userID, strconv.FormatInt(score, 10), settings)
+}
+
+// 查询排队状态，已放行时返回准入令牌
+func (r *Room) Status(ctx context.Context, productID, userID int64, settings Settings) (*Status, error) {
+	return r.query(ctx, productID, userID, "", settings)
+}
+
+func (r *Room) query(ctx context.Context, productID, userID int64, score string, settings Settings) (*Status, error) {
+	now := time.Now()
+	result, err := joinScript.Run(ctx, r.client,
+		[]string{queueKey(productID), admittedKey(productID)},
+		userID, score, now.UnixMilli(), int64(keyTTL.Seconds()),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query waiting room: %w", err)
+	}
+	if len(result) != 3 {
+		return nil, fmt.Errorf("unexpected waiting room script result: %v", result)
+	}
+
+	status := &Status{
+		ProductID: productID,
+		UserID:    userID,
+	}
+
+	switch result[0] {
+	case 1:
+		expiresAt := time.UnixMilli(result[1])
+		status.State = StateAdmitted
+		status.Token = r.signer.Sign(productID, userID, expiresAt)
+		status.TokenExpiresAt = &expiresAt
+	case 0:
+		status.State = StateWaiting
+		status.Position = result[1]
+		status.QueueLength = result[2]
+		status.EstimatedWaitSeconds = int64(math.Ceil(estimateWait(status.Position, settings).Seconds()))
+	default:
+		status.State = StateNotJoined
+		status.QueueLength = result[2]
+	}
+
+	return status, nil
+}
+
+// 估算等待时间：前方人数按批次放行所需的间隔数
+func estimateWait(position int64, settings Settings) time.Duration {
+	if settings.AdmitBatch <= 0 {
+		return 0
+	}
+	batches := position/settings.AdmitBatch + 1
+	return time.Duration(batches) * settings.AdmitInterval
+}
+
+// 放行一批用户，返回放行人数；本间隔已由其他节点放行时返回 0
+func (r *Room) Admit(ctx context.Context, productID int64, settings Settings) (int64, error) {
+	// 放行锁略短于放行间隔，避免定时器抖动导致跳过一次放行
+	lockTTL := max(settings.AdmitInterval-settings.AdmitInterval/10, time.Millisecond)
+
+	now := time.Now()
+	admitted, err := admitScript.Run(ctx, r.client,
+		[]string{queueKey(productID), admittedKey(productID), admitLockKey(productID)},
+		settings.AdmitBatch, now.UnixMilli(), now.Add(settings.TokenTTL).UnixMilli(),
+		lockTTL.Milliseconds(), int64(keyTTL.Seconds()),
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to admit users: %w", err)
+	}
+	if admitted < 0 {
+		return 0, nil
+	}
+	return admitted, nil
+}
+
+// 校验准入令牌
+func (r *Room) Verify(token string, productID, userID int64) error {
+	return r.signer.Verify(token, productID, userID, time.Now())
+}
+
+// 清理等候室数据
+func (r *Room) Cleanup(ctx context.Context, productID int64) error {
+	err := r.client.Del(ctx, queueKey(productID), admittedKey(productID), admitLockKey(productID)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to cleanup waiting room: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package waitingroom
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRoomNotEnabled    = errors.New("waiting room is not enabled for this activity")
+	ErrAdmissionRequired = errors.New("admission token required")
+	ErrInvalidToken      = errors.New("invalid admission token")
+	ErrTokenExpired      = errors.New("admission token expired")
+)
+
+// 准入令牌签名器（HMAC-SHA256），令牌格式：base64(商品ID:用户ID:过期时间).base64(签名)
+type TokenSigner struct {
+	secret []byte
+}
+
+// 创建准入令牌签名器
+func NewTokenSigner(secret []byte) *TokenSigner {
+	return &TokenSigner{secret: secret}
+}
+
+// 签发准入令牌
+func (s *TokenSigner) Sign(productID, userID int64, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%d:%d:%d", productID, userID, expiresAt.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// 校验准入令牌是否属于该商品和用户且未过期
+func (s *TokenSigner) Verify(token string, productID, userID int64, now time.Time) error {
+	if token == "" {
+		return ErrAdmissionRequired
+	}
+
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if !hmac.Equal(signature, s.sign(string(payload))) {
+		return ErrInvalidToken
+	}
+
+	fields := strings.Split(string(payload), ":")
+	if len(fields) != 3 {
+		return ErrInvalidToken
+	}
+	if fields[0] != strconv.FormatInt(productID, 10) || fields[1] != strconv.FormatInt(userID, 10) {
+		return ErrInvalidToken
+	}
+	expiresAt, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if now.Unix() >= expiresAt {
+		return ErrTokenExpired
+	}
+
+	return nil
+}
+
+func (s *TokenSigner) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -78,8 +78,9 @@ test_prewarm() {
 EOF
 )
     
-    response=$(curl -s -X POST "$BASE_URL$API_PREFIX/seckill/activity/prewarm" \
+    response=$(curl -s -X POST "$BASE_URL$API_PREFIX/admin/activity/prewarm" \
         -H "Content-Type: application/json" \
+        -H "X-User-Roles: admin" \
         -d "$payload")
     
     if echo "$response" | grep -q "successfully"; then